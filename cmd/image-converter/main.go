@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"image-converter/internal/cli"
+	"image-converter/internal/converter"
+	"image-converter/internal/filesystem"
+	"image-converter/internal/server"
+	"image-converter/internal/types"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run は設定を解析して変換を実行し、終了コードを返します
+func run() int {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			return runServe(os.Args[2:])
+		case "sign":
+			return runSign(os.Args[2:])
+		case "info":
+			return runInfo(os.Args[2:])
+		case "compare":
+			return runCompare(os.Args[2:])
+		}
+	}
+
+	config, err := cli.ParseArgs()
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	fsManager := filesystem.NewFileSystemManager()
+	var cache *converter.ResultCache
+	if !config.DryRun {
+		// 変換計画の表示ではキャッシュディレクトリも作成しない
+		cache, err = openCache(config.CacheDir, config.CacheMaxBytes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+	}
+	conv := converter.NewConverterWithCache(*config, cache)
+
+	stdinInput := len(config.Files) == 1 && config.Files[0] == cli.StdioPath
+
+	switch {
+	case config.OutputPath == cli.StdioPath || (config.OutputPath == "" && stdinInput):
+		// ストリームモード（標準出力へ書き込み）
+		return runStream(conv, config.Files[0], os.Stdout, "")
+
+	case stdinInput:
+		// 標準入力からファイルへ書き込み
+		if dir := filepath.Dir(config.OutputPath); dir != "." {
+			if err := fsManager.EnsureOutputDirectory(dir); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				return 1
+			}
+		}
+		// 出力パスの拡張子からフォーマットを決定（判定できなければ-formatまたは入力と同じ）
+		format, _ := converter.NewFormatDetector().DetectFormat(config.OutputPath)
+
+		// 変換と書き込みに成功した場合のみ出力パスに置き換える（失敗時に空や途中までのファイルを残さない）
+		err := converter.WriteFileAtomic(config.OutputPath, func(w io.Writer) error {
+			return convertStream(conv, config.Files[0], w, format)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case config.OutputPath != "":
+		// 単一ファイルモード
+		if dir := filepath.Dir(config.OutputPath); dir != "." {
+			if err := fsManager.EnsureOutputDirectory(dir); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				return 1
+			}
+		}
+		result := conv.ConvertImageTo(config.Files[0], config.OutputPath)
+		if !result.Success {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", result.SourcePath, result.Error)
+			return 1
+		}
+		return 0
+
+	case len(config.Files) > 0 || config.FilesFrom != "":
+		if !config.DryRun {
+			if err := fsManager.EnsureOutputDirectory(config.OutputDir); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				return 1
+			}
+		}
+		if err := conv.ProcessFiles(config.Files, config.OutputDir); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+
+	default:
+		if err := fsManager.ValidateInputDirectory(config.InputDir); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		if !config.DryRun {
+			if err := fsManager.EnsureOutputDirectory(config.OutputDir); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				return 1
+			}
+		}
+
+		// 初回の変換中の変更も検出できるよう、変換の前に監視を開始する
+		var watcher *filesystem.Watcher
+		if config.Watch {
+			watcher, err = filesystem.NewWatcher(config.InputDir, config.WatchInterval)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				return 1
+			}
+			defer watcher.Close()
+		}
+
+		if err := conv.ProcessDirectory(config.InputDir, config.OutputDir, fsManager); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+
+		if watcher != nil {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if err := conv.WatchDirectory(ctx, watcher, config.OutputDir, config.WatchDelete); err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+				return 1
+			}
+		}
+	}
+
+	return exitCode(conv.GetStats())
+}
+
+// runServe はHTTP変換サーバーを起動します
+func runServe(args []string) int {
+	config, err := cli.ParseServeArguments(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	if config.Root != "" {
+		if err := filesystem.NewFileSystemManager().ValidateInputDirectory(config.Root); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+	}
+
+	cache, err := openCache(config.CacheDir, config.CacheMaxBytes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	httpServer := &http.Server{
+		Addr:              config.Addr,
+		Handler:           server.NewServerWithCache(*config, cache).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", config.Addr)
+	if err := httpServer.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runSign は変換サーバー用の署名付きURLを標準出力に1行ずつ書き込みます
+func runSign(args []string) int {
+	config, err := cli.ParseSignArguments(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	var expires time.Time
+	if config.TTL > 0 {
+		expires = time.Now().Add(config.TTL)
+	}
+
+	srv := server.NewServer(config.Serve)
+	for _, rawURL := range config.URLs {
+		signed, err := srv.SignURL(rawURL, expires)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", rawURL, err)
+			return 1
+		}
+		fmt.Println(signed)
+	}
+	return 0
+}
+
+// runInfo は画像ファイルの性質を標準出力に書き込みます
+func runInfo(args []string) int {
+	config, err := cli.ParseInfoArguments(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	loader := converter.NewImageLoader()
+	infos := make([]converter.ImageInfo, 0, len(config.Files))
+	code := 0
+	for _, path := range config.Files {
+		info, err := loader.Inspect(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", path, err)
+			info.Error = err.Error()
+			code = 1
+		}
+		infos = append(infos, info)
+	}
+
+	if config.JSON {
+		err = converter.WriteImageInfoJSON(os.Stdout, infos)
+	} else {
+		err = converter.WriteImageInfoText(os.Stdout, infos)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// runCompare は2つの画像の差の指標を標準出力に書き込み、指定された場合はヒートマップを保存します
+func runCompare(args []string) int {
+	config, err := cli.ParseCompareArguments(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	a, b, err := converter.LoadComparisonImages(config.A, config.B, config.Resize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	comparison, err := converter.CompareImages(a, b)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v（-resizeでサイズを揃えて比較できます）\n", err)
+		return 1
+	}
+
+	psnr := fmt.Sprintf("%.2f dB", comparison.PSNR)
+	if comparison.Identical() {
+		psnr = "inf (identical)"
+	}
+	fmt.Printf("Dimensions: %dx%d\n", comparison.Width, comparison.Height)
+	fmt.Printf("PSNR:       %s\n", psnr)
+	fmt.Printf("SSIM:       %.5f\n", comparison.SSIM)
+	fmt.Printf("Max error:  %d\n", comparison.MaxError)
+
+	if config.DiffPath != "" {
+		heatmap, err := converter.DiffHeatmap(a, b)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		format, err := converter.NewFormatDetector().DetectFormat(config.DiffPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		if err := converter.NewImageSaver().Save(heatmap, config.DiffPath, format, 100); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// openCache はdirが指定されている場合に変換結果のキャッシュを開きます（未指定の場合はnil）
+func openCache(dir string, maxBytes int64) (*converter.ResultCache, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	return converter.OpenResultCache(dir, maxBytes)
+}
+
+// runStream は入力（"-"の場合は標準入力）を変換してwに書き込みます
+func runStream(conv *converter.Converter, input string, w io.Writer, format types.ImageFormat) int {
+	if err := convertStream(conv, input, w, format); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// convertStream は入力（"-"の場合は標準入力）を変換してwに書き込みます
+func convertStream(conv *converter.Converter, input string, w io.Writer, format types.ImageFormat) error {
+	var r io.Reader = os.Stdin
+	if input != cli.StdioPath {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	result := conv.ConvertStream(r, w, format)
+	if !result.Success {
+		return fmt.Errorf("%s: %w", input, result.Error)
+	}
+	return nil
+}
+
+// exitCode は変換結果の統計から終了コードを決定します
+func exitCode(stats types.ConversionStats) int {
+	if stats.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -6,4 +6,8 @@ require github.com/leanovate/gopter v0.2.11
 
 require golang.org/x/image v0.33.0
 
-require github.com/chai2010/webp v1.4.0 // indirect
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/chai2010/webp v1.4.0
+	gopkg.in/yaml.v3 v3.0.1
+)
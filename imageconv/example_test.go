@@ -0,0 +1,54 @@
+package imageconv_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+
+	"image-converter/imageconv"
+)
+
+func ExampleConvert() {
+	// 640x480のPNGを用意
+	var input bytes.Buffer
+	if err := png.Encode(&input, image.NewRGBA(image.Rect(0, 0, 640, 480))); err != nil {
+		log.Fatal(err)
+	}
+
+	// 幅320ピクセルのWebPに変換
+	var output bytes.Buffer
+	info, err := imageconv.Convert(context.Background(), &input, &output, imageconv.Options{
+		Format:  imageconv.FormatWebP,
+		Width:   320,
+		Quality: 80,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%s -> %s %dx%d\n", info.InputFormat, info.Format, info.Width, info.Height)
+	// Output: png -> webp 320x240
+}
+
+func ExampleConvert_maxBytes() {
+	var input bytes.Buffer
+	if err := png.Encode(&input, image.NewRGBA(image.Rect(0, 0, 200, 200))); err != nil {
+		log.Fatal(err)
+	}
+
+	// 出力を10KB以下に収める（品質は自動調整）
+	var output bytes.Buffer
+	info, err := imageconv.Convert(context.Background(), &input, &output, imageconv.Options{
+		Format:   imageconv.FormatJPEG,
+		MaxBytes: 10 * 1024,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(info.Bytes <= 10*1024)
+	// Output: true
+}
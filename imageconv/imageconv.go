@@ -0,0 +1,230 @@
+// Package imageconv は画像変換をライブラリとして利用するための公開APIを提供します
+//
+// CLIと同じ読み込み・リサイズ・保存処理をio.Reader/io.Writerに対して実行します。
+// 入力フォーマットは内容から判定されるため、ファイル名は必要ありません。
+package imageconv
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"image-converter/internal/converter"
+	"image-converter/internal/types"
+)
+
+// Format は画像フォーマットを表します
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatWebP Format = "webp"
+	FormatGIF  Format = "gif"
+	FormatBMP  Format = "bmp"
+)
+
+// Fit は幅と高さの両方を指定した場合の合わせ方を表します
+type Fit string
+
+const (
+	FitContain Fit = "contain" // 縦横比を維持して範囲内に収める
+	FitCover   Fit = "cover"   // 縦横比を維持して範囲を覆い、はみ出した部分の中央を切り取る
+)
+
+// Dither はパレット化時のディザリング方式を表します
+type Dither string
+
+const (
+	DitherNone           Dither = "none"
+	DitherFloydSteinberg Dither = "floyd-steinberg"
+	DitherOrdered        Dither = "ordered"
+)
+
+// Subsampling はJPEGの色差サブサンプリング方式を表します
+type Subsampling string
+
+const (
+	Subsampling444 Subsampling = "444"
+	Subsampling422 Subsampling = "422"
+	Subsampling420 Subsampling = "420"
+)
+
+// DefaultQuality はOptions.Qualityが0の場合に使用するJPEG/WebP品質です
+const DefaultQuality = 85
+
+// Options は変換の設定を表します
+// ゼロ値は「入力と同じフォーマット・同じサイズで出力」を意味します
+type Options struct {
+	// Format は出力フォーマットです（空の場合は入力と同じフォーマット）
+	Format Format
+
+	// Scale は倍率です（0の場合は未指定）。WidthやHeightと同時に指定できません
+	Scale float64
+	// Width と Height は出力の最大サイズ（ピクセル）です。縦横比は維持されます
+	Width  int
+	Height int
+	// Fit はWidthとHeightの両方を指定した場合の合わせ方です（空の場合はFitContain）
+	Fit Fit
+
+	// Quality はJPEG/WebPの品質（1-100、0の場合はDefaultQuality）です
+	Quality int
+
+	// FirstFrameOnly が true の場合、アニメーションGIFの最初のフレームのみを出力します
+	FirstFrameOnly bool
+
+	// MaxBytes は出力サイズの上限（バイト、0の場合は無制限）です。品質を自動的に下げて収めます
+	MaxBytes int64
+	// AllowDownscale が true の場合、最低品質でもMaxBytesを超えるときにさらに縮小します
+	AllowDownscale bool
+	// TargetSSIM は目標とするSSIM（0-1、0の場合は無効）です。MaxBytesと同時に指定できません
+	// 最高設定でも目標に届かない場合や、アニメーションのまま出力する場合は変換に失敗します
+	TargetSSIM float64
+
+	// Colors はGIF・パレットPNGの最大色数（2-256、0の場合は256）です
+	Colors int
+	// Dither はディザリング方式です（空の場合はFloyd-Steinberg）
+	Dither Dither
+	// PNGPalette が true の場合、PNGを8ビットパレット形式で出力します
+	PNGPalette bool
+	// PNGMaxError はパレットPNGの許容誤差（RMSE、0の場合は無制限）です
+	PNGMaxError float64
+	// JPEGProgressive が true の場合、プログレッシブJPEGで出力します
+	JPEGProgressive bool
+	// JPEGSubsampling はJPEGの色差サブサンプリングです（空の場合は4:2:0）
+	JPEGSubsampling Subsampling
+}
+
+// Info は変換結果の情報を表します
+type Info struct {
+	InputFormat Format  // 内容から判定した入力フォーマット
+	Format      Format  // 出力フォーマット
+	Width       int     // 出力画像の幅
+	Height      int     // 出力画像の高さ
+	Bytes       int64   // wに書き込んだバイト数
+	Quality     int     // MaxBytes・TargetSSIM指定時に採用した品質または色数
+	SSIM        float64 // TargetSSIM指定時に計測した入力とのSSIM
+}
+
+// Convert はrから画像を読み込み、optsに従って変換した結果をwに書き込みます
+// ctxは変換の開始前と入力の読み込み中に確認され、キャンセルされた場合はctx.Err()を返します
+func Convert(ctx context.Context, r io.Reader, w io.Writer, opts Options) (Info, error) {
+	if err := ctx.Err(); err != nil {
+		return Info{}, err
+	}
+	if err := opts.Validate(); err != nil {
+		return Info{}, err
+	}
+
+	conv := converter.NewConverter(opts.config())
+	result := conv.ConvertStream(&contextReader{ctx: ctx, r: r}, w, "")
+	if !result.Success {
+		if err := ctx.Err(); err != nil {
+			return Info{}, err
+		}
+		return Info{InputFormat: Format(result.SourceFormat)}, result.Error
+	}
+
+	return Info{
+		InputFormat: Format(result.SourceFormat),
+		Format:      Format(result.Format),
+		Width:       result.Width,
+		Height:      result.Height,
+		Bytes:       result.Bytes,
+		Quality:     result.Quality,
+		SSIM:        result.SSIM,
+	}, nil
+}
+
+// Validate は設定の妥当性を検証します
+func (o Options) Validate() error {
+	switch o.Format {
+	case "", FormatJPEG, FormatPNG, FormatWebP, FormatGIF, FormatBMP:
+	default:
+		return fmt.Errorf("unsupported format: %s", o.Format)
+	}
+
+	if o.Scale < 0 || o.Width < 0 || o.Height < 0 {
+		return fmt.Errorf("scale, width and height must not be negative")
+	}
+	if o.Scale > 0 && (o.Width > 0 || o.Height > 0) {
+		return fmt.Errorf("scale cannot be combined with width or height")
+	}
+	switch o.Fit {
+	case "", FitContain, FitCover:
+	default:
+		return fmt.Errorf("unsupported fit: %s", o.Fit)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	if o.MaxBytes < 0 {
+		return fmt.Errorf("max bytes must not be negative")
+	}
+	if o.TargetSSIM < 0 || o.TargetSSIM > 1 {
+		return fmt.Errorf("target SSIM must be between 0 and 1")
+	}
+	if o.TargetSSIM > 0 && o.MaxBytes > 0 {
+		return fmt.Errorf("target SSIM cannot be combined with max bytes")
+	}
+	if o.Colors != 0 && (o.Colors < 2 || o.Colors > 256) {
+		return fmt.Errorf("colors must be between 2 and 256")
+	}
+	if o.PNGMaxError < 0 {
+		return fmt.Errorf("PNG max error must not be negative")
+	}
+
+	switch o.Dither {
+	case "", DitherNone, DitherFloydSteinberg, DitherOrdered:
+	default:
+		return fmt.Errorf("unsupported dither mode: %s", o.Dither)
+	}
+
+	switch o.JPEGSubsampling {
+	case "", Subsampling444, Subsampling422, Subsampling420:
+	default:
+		return fmt.Errorf("unsupported subsampling: %s", o.JPEGSubsampling)
+	}
+
+	return nil
+}
+
+// config は設定を内部の設定に変換します
+func (o Options) config() types.Config {
+	quality := o.Quality
+	if quality == 0 {
+		quality = DefaultQuality
+	}
+
+	return types.Config{
+		Format:          string(o.Format),
+		Scale:           o.Scale,
+		Width:           o.Width,
+		Height:          o.Height,
+		Fit:             string(o.Fit),
+		JPEGQuality:     quality,
+		FirstFrameOnly:  o.FirstFrameOnly,
+		MaxBytes:        o.MaxBytes,
+		AllowDownscale:  o.AllowDownscale,
+		TargetSSIM:      o.TargetSSIM,
+		Colors:          o.Colors,
+		Dither:          string(o.Dither),
+		PNGPalette:      o.PNGPalette,
+		PNGMaxError:     o.PNGMaxError,
+		JPEGProgressive: o.JPEGProgressive,
+		JPEGSubsampling: string(o.JPEGSubsampling),
+	}
+}
+
+// contextReader はctxがキャンセルされると読み込みを中断するio.Readerです
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,115 @@
+package imageconv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// createTestPNG はテスト用のグラデーション画像をPNGでエンコードします
+func createTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / width), uint8(y * 255 / height), 128, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestConvert(t *testing.T) {
+	input := createTestPNG(t, 200, 100)
+
+	var output bytes.Buffer
+	info, err := Convert(context.Background(), bytes.NewReader(input), &output, Options{Format: FormatJPEG, Width: 50})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	expected := Info{InputFormat: FormatPNG, Format: FormatJPEG, Width: 50, Height: 25, Bytes: int64(output.Len())}
+	if info != expected {
+		t.Errorf("Convert() = %+v, want %+v", info, expected)
+	}
+
+	if _, format, err := image.DecodeConfig(&output); err != nil || format != "jpeg" {
+		t.Errorf("output format = %s (err=%v), want jpeg", format, err)
+	}
+}
+
+func TestConvert_Cover(t *testing.T) {
+	var output bytes.Buffer
+	info, err := Convert(context.Background(), bytes.NewReader(createTestPNG(t, 200, 100)), &output, Options{Width: 50, Height: 50, Fit: FitCover})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if info.Width != 50 || info.Height != 50 {
+		t.Errorf("Expected 50x50, got %dx%d", info.Width, info.Height)
+	}
+}
+
+func TestConvert_SameFormatByDefault(t *testing.T) {
+	var output bytes.Buffer
+	info, err := Convert(context.Background(), bytes.NewReader(createTestPNG(t, 10, 10)), &output, Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if info.Format != FormatPNG || info.Width != 10 {
+		t.Errorf("Convert() = %+v", info)
+	}
+}
+
+func TestConvert_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var output bytes.Buffer
+	_, err := Convert(ctx, bytes.NewReader(createTestPNG(t, 10, 10)), &output, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected no output, got %d bytes", output.Len())
+	}
+}
+
+func TestConvert_InvalidInput(t *testing.T) {
+	var output bytes.Buffer
+	if _, err := Convert(context.Background(), strings.NewReader("not an image"), &output, Options{}); err == nil {
+		t.Error("Expected error for invalid input")
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	invalid := map[string]Options{
+		"unsupported format":  {Format: "tiff"},
+		"scale with width":    {Scale: 0.5, Width: 100},
+		"negative width":      {Width: -1},
+		"unknown fit":         {Fit: "stretch"},
+		"quality too high":    {Quality: 101},
+		"SSIM with max bytes": {TargetSSIM: 0.9, MaxBytes: 1000},
+		"too many colors":     {Colors: 512},
+		"unknown dither":      {Dither: "random"},
+		"unknown subsampling": {JPEGSubsampling: "411"},
+	}
+	for name, opts := range invalid {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if err := (Options{Format: FormatWebP, Width: 800, Quality: 80}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
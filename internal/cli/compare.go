@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"image-converter/internal/types"
+)
+
+// ParseCompareArguments はcompareサブコマンドの引数を解析してCompareConfigを返します
+func ParseCompareArguments(args []string) (*types.CompareConfig, error) {
+	config := &types.CompareConfig{}
+
+	fs := flag.NewFlagSet("image-converter compare", flag.ContinueOnError)
+	fs.Usage = PrintCompareUsage
+	fs.BoolVar(&config.Resize, "resize", false, "サイズが異なる場合、2つ目の画像を1つ目のサイズにリサイズする")
+	fs.StringVar(&config.DiffPath, "diff", "", "差を可視化したヒートマップ画像の出力パス")
+
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 2 {
+		return nil, fmt.Errorf("比較する画像ファイルを2つ指定してください")
+	}
+	config.A, config.B = files[0], files[1]
+
+	if config.DiffPath != "" {
+		ext := strings.TrimPrefix(filepath.Ext(config.DiffPath), ".")
+		if _, err := normalizeFormat(ext); err != nil {
+			return nil, fmt.Errorf("ヒートマップの出力パスの拡張子からフォーマットを判定できません: %s", config.DiffPath)
+		}
+	}
+
+	return config, nil
+}
+
+// PrintCompareUsage はcompareサブコマンドの使用方法を表示します
+func PrintCompareUsage() {
+	fmt.Fprintf(os.Stderr, "Image Converter CLI - 画像の比較\n\n")
+	fmt.Fprintf(os.Stderr, "使用方法:\n")
+	fmt.Fprintf(os.Stderr, "  image-converter compare [-resize] [-diff <ヒートマップ>] <画像A> <画像B>\n")
+	fmt.Fprintf(os.Stderr, "  例: image-converter compare -diff diff.png original.png converted.webp\n\n")
+
+	fmt.Fprintf(os.Stderr, "オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -resize\n")
+	fmt.Fprintf(os.Stderr, "        サイズが異なる場合、画像Bを画像Aのサイズにリサイズして比較（縦横比が異なる場合は中央を切り取る）\n")
+	fmt.Fprintf(os.Stderr, "        指定しない場合、サイズが異なるとエラー\n")
+	fmt.Fprintf(os.Stderr, "  -diff path\n")
+	fmt.Fprintf(os.Stderr, "        差を可視化したヒートマップ画像を書き込む（フォーマットは拡張子から判定）\n")
+	fmt.Fprintf(os.Stderr, "        差のない画素は暗いグレー、差のある画素は青（小）から赤（大）で表示\n\n")
+
+	fmt.Fprintf(os.Stderr, "PSNR（RGB、dB）、SSIM（輝度）、チャンネルごとの最大誤差（0-255）を表示します\n")
+}
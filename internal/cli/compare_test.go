@@ -0,0 +1,24 @@
+package cli
+
+import "testing"
+
+func TestParseCompareArguments(t *testing.T) {
+	config, err := ParseCompareArguments([]string{"a.png", "-resize", "b.webp", "-diff", "diff.png"})
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.A != "a.png" || config.B != "b.webp" || !config.Resize || config.DiffPath != "diff.png" {
+		t.Errorf("設定が正しくありません: %+v", config)
+	}
+
+	tests := map[string][]string{
+		"ファイルが1つ":      {"a.png"},
+		"ファイルが3つ":      {"a.png", "b.png", "c.png"},
+		"ヒートマップの拡張子不明": {"-diff", "diff.txt", "a.png", "b.png"},
+	}
+	for name, args := range tests {
+		if _, err := ParseCompareArguments(args); err == nil {
+			t.Errorf("%s: エラーが返されるべき", name)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"image-converter/internal/types"
+)
+
+// configValues は設定ファイルの1つのセクション（トップレベルまたはプロファイル）を表します
+// キー名はフラグ名と同じで、指定されなかった項目はnilのままになります
+type configValues struct {
+	InputDir         *string         `yaml:"input-dir" toml:"input-dir" json:"input-dir"`
+	OutputDir        *string         `yaml:"output-dir" toml:"output-dir" json:"output-dir"`
+	Scale            *float64        `yaml:"scale" toml:"scale" json:"scale"`
+	Width            *int            `yaml:"width" toml:"width" json:"width"`
+	Height           *int            `yaml:"height" toml:"height" json:"height"`
+	Fit              *string         `yaml:"fit" toml:"fit" json:"fit"`
+	Format           *string         `yaml:"format" toml:"format" json:"format"`
+	JPEGQuality      *int            `yaml:"jpeg-quality" toml:"jpeg-quality" json:"jpeg-quality"`
+	JPEGProgressive  *bool           `yaml:"jpeg-progressive" toml:"jpeg-progressive" json:"jpeg-progressive"`
+	JPEGSubsampling  *string         `yaml:"jpeg-subsampling" toml:"jpeg-subsampling" json:"jpeg-subsampling"`
+	MaxBytes         *byteSizeField  `yaml:"max-bytes" toml:"max-bytes" json:"max-bytes"`
+	AllowDownscale   *bool           `yaml:"max-bytes-downscale" toml:"max-bytes-downscale" json:"max-bytes-downscale"`
+	TargetSSIM       *float64        `yaml:"target-ssim" toml:"target-ssim" json:"target-ssim"`
+	Variants         *[]variantField `yaml:"variants" toml:"variants" json:"variants"`
+	ManifestPath     *string         `yaml:"manifest" toml:"manifest" json:"manifest"`
+	ManifestHTMLPath *string         `yaml:"manifest-html" toml:"manifest-html" json:"manifest-html"`
+	Colors           *int            `yaml:"colors" toml:"colors" json:"colors"`
+	Dither           *string         `yaml:"dither" toml:"dither" json:"dither"`
+	PNGPalette       *bool           `yaml:"png-palette" toml:"png-palette" json:"png-palette"`
+	PNGMaxError      *float64        `yaml:"png-max-error" toml:"png-max-error" json:"png-max-error"`
+	FirstFrameOnly   *bool           `yaml:"first-frame-only" toml:"first-frame-only" json:"first-frame-only"`
+	CacheDir         *string         `yaml:"cache-dir" toml:"cache-dir" json:"cache-dir"`
+	CacheMaxBytes    *byteSizeField  `yaml:"cache-max-bytes" toml:"cache-max-bytes" json:"cache-max-bytes"`
+	Watch            *bool           `yaml:"watch" toml:"watch" json:"watch"`
+	WatchDelete      *bool           `yaml:"watch-delete" toml:"watch-delete" json:"watch-delete"`
+	WatchInterval    *durationField  `yaml:"watch-interval" toml:"watch-interval" json:"watch-interval"`
+	Sync             *bool           `yaml:"sync" toml:"sync" json:"sync"`
+	PlanFormat       *string         `yaml:"plan-format" toml:"plan-format" json:"plan-format"`
+	Rules            *[]ruleField    `yaml:"rules" toml:"rules" json:"rules"`
+}
+
+// configFile は設定ファイル全体を表します
+// トップレベルの値が共通設定で、profilesの各セクションがその上に適用されます
+type configFile struct {
+	configValues `yaml:",inline"`
+	Profiles     map[string]configValues `yaml:"profiles" toml:"profiles" json:"profiles"`
+	Serve        *serveValues            `yaml:"serve" toml:"serve" json:"serve"`
+}
+
+// serveValues は設定ファイルのserveセクション（serve・signサブコマンドの設定）を表します
+type serveValues struct {
+	Addr      *string        `yaml:"addr" toml:"addr" json:"addr"`
+	Root      *string        `yaml:"root" toml:"root" json:"root"`
+	MaxUpload *byteSizeField `yaml:"max-upload" toml:"max-upload" json:"max-upload"`
+	MaxPixels *int64         `yaml:"max-pixels" toml:"max-pixels" json:"max-pixels"`
+	Secret    *string        `yaml:"secret" toml:"secret" json:"secret"`
+	Presets   *[]string      `yaml:"presets" toml:"presets" json:"presets"`
+	CacheDir  *string        `yaml:"cache-dir" toml:"cache-dir" json:"cache-dir"`
+	CacheMax  *byteSizeField `yaml:"cache-max-bytes" toml:"cache-max-bytes" json:"cache-max-bytes"`
+}
+
+// variantField は設定ファイル内のバリエーション指定です
+type variantField struct {
+	Suffix  string  `yaml:"suffix" toml:"suffix" json:"suffix"`
+	Width   int     `yaml:"width" toml:"width" json:"width"`
+	Height  int     `yaml:"height" toml:"height" json:"height"`
+	Scale   float64 `yaml:"scale" toml:"scale" json:"scale"`
+	Format  string  `yaml:"format" toml:"format" json:"format"`
+	Quality int     `yaml:"quality" toml:"quality" json:"quality"`
+}
+
+// ruleField は設定ファイル内の規則の指定です
+type ruleField struct {
+	Name            string         `yaml:"name" toml:"name" json:"name"`
+	Match           ruleMatchField `yaml:"match" toml:"match" json:"match"`
+	Width           int            `yaml:"width" toml:"width" json:"width"`
+	Height          int            `yaml:"height" toml:"height" json:"height"`
+	Scale           float64        `yaml:"scale" toml:"scale" json:"scale"`
+	Format          string         `yaml:"format" toml:"format" json:"format"`
+	Quality         int            `yaml:"quality" toml:"quality" json:"quality"`
+	Colors          int            `yaml:"colors" toml:"colors" json:"colors"`
+	Dither          string         `yaml:"dither" toml:"dither" json:"dither"`
+	PNGPalette      *bool          `yaml:"png-palette" toml:"png-palette" json:"png-palette"`
+	JPEGProgressive *bool          `yaml:"jpeg-progressive" toml:"jpeg-progressive" json:"jpeg-progressive"`
+	JPEGSubsampling string         `yaml:"jpeg-subsampling" toml:"jpeg-subsampling" json:"jpeg-subsampling"`
+}
+
+// ruleMatchField は設定ファイル内の規則の適用条件です
+type ruleMatchField struct {
+	Glob      string   `yaml:"glob" toml:"glob" json:"glob"`
+	Formats   []string `yaml:"formats" toml:"formats" json:"formats"`
+	MinWidth  int      `yaml:"min-width" toml:"min-width" json:"min-width"`
+	MaxWidth  int      `yaml:"max-width" toml:"max-width" json:"max-width"`
+	MinHeight int      `yaml:"min-height" toml:"min-height" json:"min-height"`
+	MaxHeight int      `yaml:"max-height" toml:"max-height" json:"max-height"`
+}
+
+// byteSizeField は数値または"200k"のような単位付き文字列で指定できるバイト数です
+type byteSizeField int64
+
+func (b *byteSizeField) set(s string) error {
+	n, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = byteSizeField(n)
+	return nil
+}
+
+func (b *byteSizeField) UnmarshalYAML(node *yaml.Node) error {
+	return b.set(node.Value)
+}
+
+func (b *byteSizeField) UnmarshalTOML(value interface{}) error {
+	return b.set(fmt.Sprint(value))
+}
+
+func (b *byteSizeField) UnmarshalJSON(data []byte) error {
+	return b.set(strings.Trim(string(data), `"`))
+}
+
+// durationField は"2s"のような期間の文字列で指定する時間です
+type durationField time.Duration
+
+func (d *durationField) set(s string) error {
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = durationField(duration)
+	return nil
+}
+
+func (d *durationField) UnmarshalYAML(node *yaml.Node) error {
+	return d.set(node.Value)
+}
+
+func (d *durationField) UnmarshalTOML(value interface{}) error {
+	return d.set(fmt.Sprint(value))
+}
+
+func (d *durationField) UnmarshalJSON(data []byte) error {
+	return d.set(strings.Trim(string(data), `"`))
+}
+
+// LoadConfigFile は設定ファイルを読み込み、defaultsの上に値を適用したConfigを返します
+// フォーマットは拡張子（.yaml/.yml, .toml, .json）で判定し、未知のキーはエラーになります
+// profileが空でない場合は、共通設定の上に指定されたプロファイルを適用します
+func LoadConfigFile(path, profile string, defaults types.Config) (*types.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルを読み込めません: %w", err)
+	}
+
+	var file configFile
+	if err := decodeConfigFile(path, data, &file); err != nil {
+		return nil, fmt.Errorf("設定ファイルの解析に失敗しました（%s）: %w", path, err)
+	}
+
+	config := defaults
+	file.configValues.apply(&config)
+
+	if profile != "" {
+		values, ok := file.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("プロファイルが見つかりません: %s（利用可能: %s）", profile, strings.Join(profileNames(file.Profiles), ", "))
+		}
+		values.apply(&config)
+	}
+
+	return &config, nil
+}
+
+// LoadServeConfigFile は設定ファイルのserveセクションを読み込み、defaultsの上に値を適用したServeConfigを返します
+func LoadServeConfigFile(path string, defaults types.ServeConfig) (*types.ServeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルを読み込めません: %w", err)
+	}
+
+	var file configFile
+	if err := decodeConfigFile(path, data, &file); err != nil {
+		return nil, fmt.Errorf("設定ファイルの解析に失敗しました（%s）: %w", path, err)
+	}
+
+	config := defaults
+	if file.Serve != nil {
+		if err := file.Serve.apply(&config); err != nil {
+			return nil, fmt.Errorf("設定ファイルのserveセクションが不正です: %w", err)
+		}
+	}
+	return &config, nil
+}
+
+// decodeConfigFile は拡張子に応じたデコーダーで設定ファイルを厳密に解析します
+func decodeConfigFile(path string, data []byte, file *configFile) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		return nil
+
+	case ".toml":
+		meta, err := toml.Decode(string(data), file)
+		if err != nil {
+			return err
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("不明なキー: %s", strings.Join(keys, ", "))
+		}
+		return nil
+
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(file)
+
+	default:
+		return fmt.Errorf("サポートされていない設定ファイル形式: %s（.yaml, .yml, .toml, .jsonのいずれか）", filepath.Ext(path))
+	}
+}
+
+// apply は指定された項目のみをconfigに上書きします
+func (v configValues) apply(config *types.Config) {
+	setIfPresent(&config.InputDir, v.InputDir)
+	setIfPresent(&config.OutputDir, v.OutputDir)
+	setIfPresent(&config.Scale, v.Scale)
+	setIfPresent(&config.Width, v.Width)
+	setIfPresent(&config.Height, v.Height)
+	setIfPresent(&config.Fit, v.Fit)
+	setIfPresent(&config.Format, v.Format)
+	setIfPresent(&config.JPEGQuality, v.JPEGQuality)
+	setIfPresent(&config.JPEGProgressive, v.JPEGProgressive)
+	setIfPresent(&config.JPEGSubsampling, v.JPEGSubsampling)
+	if v.MaxBytes != nil {
+		config.MaxBytes = int64(*v.MaxBytes)
+	}
+	setIfPresent(&config.AllowDownscale, v.AllowDownscale)
+	setIfPresent(&config.TargetSSIM, v.TargetSSIM)
+	if v.Variants != nil {
+		config.Variants = make([]types.Variant, len(*v.Variants))
+		for i, variant := range *v.Variants {
+			config.Variants[i] = types.Variant{
+				Suffix:  variant.Suffix,
+				Resize:  types.ResizeSpec{Scale: variant.Scale, Width: variant.Width, Height: variant.Height},
+				Format:  variant.Format,
+				Quality: variant.Quality,
+			}
+		}
+	}
+	setIfPresent(&config.ManifestPath, v.ManifestPath)
+	setIfPresent(&config.ManifestHTMLPath, v.ManifestHTMLPath)
+	setIfPresent(&config.Colors, v.Colors)
+	setIfPresent(&config.Dither, v.Dither)
+	setIfPresent(&config.PNGPalette, v.PNGPalette)
+	setIfPresent(&config.PNGMaxError, v.PNGMaxError)
+	setIfPresent(&config.FirstFrameOnly, v.FirstFrameOnly)
+	setIfPresent(&config.CacheDir, v.CacheDir)
+	if v.CacheMaxBytes != nil {
+		config.CacheMaxBytes = int64(*v.CacheMaxBytes)
+	}
+	setIfPresent(&config.Watch, v.Watch)
+	setIfPresent(&config.WatchDelete, v.WatchDelete)
+	if v.WatchInterval != nil {
+		config.WatchInterval = time.Duration(*v.WatchInterval)
+	}
+	setIfPresent(&config.Sync, v.Sync)
+	setIfPresent(&config.PlanFormat, v.PlanFormat)
+	if v.Rules != nil {
+		config.Rules = make([]types.Rule, len(*v.Rules))
+		for i, rule := range *v.Rules {
+			config.Rules[i] = types.Rule{
+				Name: rule.Name,
+				Match: types.RuleMatch{
+					Glob:      rule.Match.Glob,
+					Formats:   rule.Match.Formats,
+					MinWidth:  rule.Match.MinWidth,
+					MaxWidth:  rule.Match.MaxWidth,
+					MinHeight: rule.Match.MinHeight,
+					MaxHeight: rule.Match.MaxHeight,
+				},
+				Resize:          types.ResizeSpec{Scale: rule.Scale, Width: rule.Width, Height: rule.Height},
+				Format:          rule.Format,
+				Quality:         rule.Quality,
+				Colors:          rule.Colors,
+				Dither:          rule.Dither,
+				PNGPalette:      rule.PNGPalette,
+				JPEGProgressive: rule.JPEGProgressive,
+				JPEGSubsampling: rule.JPEGSubsampling,
+			}
+		}
+	}
+}
+
+// apply は指定された項目のみをconfigに上書きします
+func (v serveValues) apply(config *types.ServeConfig) error {
+	setIfPresent(&config.Addr, v.Addr)
+	setIfPresent(&config.Root, v.Root)
+	if v.MaxUpload != nil {
+		config.MaxUploadBytes = int64(*v.MaxUpload)
+	}
+	setIfPresent(&config.MaxPixels, v.MaxPixels)
+	setIfPresent(&config.Secret, v.Secret)
+	if v.Presets != nil {
+		config.Presets = make([]types.SizePreset, len(*v.Presets))
+		for i, spec := range *v.Presets {
+			preset, err := ParseSizePreset(spec)
+			if err != nil {
+				return err
+			}
+			config.Presets[i] = preset
+		}
+	}
+	setIfPresent(&config.CacheDir, v.CacheDir)
+	if v.CacheMax != nil {
+		config.CacheMaxBytes = int64(*v.CacheMax)
+	}
+	return nil
+}
+
+// setIfPresent はvalueがnilでなければdstに代入します
+func setIfPresent[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// profileNames はプロファイル名を昇順で返します
+func profileNames(profiles map[string]configValues) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
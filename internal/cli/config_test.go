@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// writeConfigFile はテスト用の設定ファイルを作成します
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("設定ファイルの作成に失敗: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigFile_Formats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+input-dir: /input
+output-dir: /output
+format: webp
+max-bytes: 200k
+variants:
+  - width: 320
+    suffix: -sm
+profiles:
+  thumbnail:
+    width: 150
+    jpeg-quality: 70
+`,
+		"config.toml": `
+input-dir = "/input"
+output-dir = "/output"
+format = "webp"
+max-bytes = "200k"
+
+[[variants]]
+width = 320
+suffix = "-sm"
+
+[profiles.thumbnail]
+width = 150
+jpeg-quality = 70
+`,
+		"config.json": `{
+  "input-dir": "/input",
+  "output-dir": "/output",
+  "format": "webp",
+  "max-bytes": 204800,
+  "variants": [{"width": 320, "suffix": "-sm"}],
+  "profiles": {"thumbnail": {"width": 150, "jpeg-quality": 70}}
+}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := writeConfigFile(t, name, content)
+
+			config, err := LoadConfigFile(path, "", DefaultConfig())
+			if err != nil {
+				t.Fatalf("予期しないエラー: %v", err)
+			}
+			if config.InputDir != "/input" || config.Format != "webp" || config.MaxBytes != 200*1024 {
+				t.Errorf("設定が読み込まれていない: %+v", config)
+			}
+			if len(config.Variants) != 1 || config.Variants[0].Resize.Width != 320 || config.Variants[0].Suffix != "-sm" {
+				t.Errorf("バリエーション=%+v", config.Variants)
+			}
+			// 指定されなかった項目は既定値のまま
+			if config.JPEGQuality != 85 || config.Colors != 256 {
+				t.Errorf("既定値が保持されていない: %+v", config)
+			}
+
+			config, err = LoadConfigFile(path, "thumbnail", DefaultConfig())
+			if err != nil {
+				t.Fatalf("予期しないエラー: %v", err)
+			}
+			if config.Width != 150 || config.JPEGQuality != 70 || config.Format != "webp" {
+				t.Errorf("プロファイルが適用されていない: %+v", config)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		profile string
+	}{
+		{"YAMLの不明なキー", "config.yaml", "input-dir: /input\nqualty: 80\n", ""},
+		{"TOMLの不明なキー", "config.toml", "input-dir = \"/input\"\nqualty = 80\n", ""},
+		{"JSONの不明なキー", "config.json", `{"input-dir": "/input", "qualty": 80}`, ""},
+		{"プロファイル内の不明なキー", "config.yaml", "profiles:\n  hero:\n    widht: 100\n", ""},
+		{"存在しないプロファイル", "config.yaml", "profiles:\n  hero:\n    width: 100\n", "thumbnail"},
+		{"未対応の拡張子", "config.ini", "input-dir=/input\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+			if _, err := LoadConfigFile(path, tt.profile, DefaultConfig()); err == nil {
+				t.Error("エラーが返されるべき")
+			}
+		})
+	}
+}
+
+func TestParseArguments_ConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+input-dir: /input
+output-dir: /output
+format: png
+jpeg-quality: 60
+variants:
+  - width: 320
+profiles:
+  hero:
+    width: 1600
+`)
+
+	// フラグが設定ファイルより優先される
+	config, err := ParseArguments([]string{"-config", path, "-profile", "hero", "-format", "jpeg", "-variant", "w=640"})
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.Format != "jpeg" {
+		t.Errorf("フォーマット=%s, 期待=jpeg", config.Format)
+	}
+	if config.JPEGQuality != 60 || config.Width != 1600 {
+		t.Errorf("設定ファイルの値が適用されていない: %+v", config)
+	}
+	expected := []types.Variant{{Suffix: "-640w", Resize: types.ResizeSpec{Width: 640}}}
+	if len(config.Variants) != 1 || config.Variants[0] != expected[0] {
+		t.Errorf("バリエーション=%+v, 期待=%+v", config.Variants, expected)
+	}
+
+	// 結合後の設定が検証される
+	if _, err := ParseArguments([]string{"-config", path, "-profile", "hero", "-scale", "0.5"}); err == nil {
+		t.Error("結合後の設定が不正な場合、エラーが返されるべき")
+	}
+
+	// -profileには-configが必要
+	if _, err := ParseArguments([]string{"-input-dir", "/in", "-output-dir", "/out", "-profile", "hero"}); err == nil {
+		t.Error("-configなしの-profileはエラーになるべき")
+	}
+}
+
+func TestLoadConfigFile_Rules(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+input-dir: /input
+output-dir: /output
+rules:
+  - name: icons
+    match:
+      glob: "icons/*.png"
+    width: 64
+    format: png
+    png-palette: false
+  - match:
+      formats: [JPG]
+      min-width: 2000
+    width: 1600
+    format: webp
+    quality: 80
+`)
+
+	config, err := ParseArguments([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if len(config.Rules) != 2 {
+		t.Fatalf("規則の数=%d, 期待=2", len(config.Rules))
+	}
+
+	icons := config.Rules[0]
+	if icons.Name != "icons" || icons.Match.Glob != "icons/*.png" || icons.Resize.Width != 64 || icons.PNGPalette == nil || *icons.PNGPalette {
+		t.Errorf("規則1=%+v", icons)
+	}
+
+	photos := config.Rules[1]
+	if photos.Name != "rule2" || photos.Match.Formats[0] != "jpeg" || photos.Match.MinWidth != 2000 || photos.Quality != 80 {
+		t.Errorf("規則2=%+v", photos)
+	}
+}
+
+func TestValidateConfig_Rules(t *testing.T) {
+	invalid := map[string]types.Rule{
+		"無効なパターン":      {Match: types.RuleMatch{Glob: "[icons"}},
+		"不正な入力フォーマット":  {Match: types.RuleMatch{Formats: []string{"tiff"}}},
+		"寸法の下限が上限を超える": {Match: types.RuleMatch{MinWidth: 200, MaxWidth: 100}},
+		"倍率とピクセルの同時指定": {Resize: types.ResizeSpec{Scale: 0.5, Width: 10}},
+		"範囲外の品質":       {Quality: 101},
+		"不正なディザリング":    {Dither: "random"},
+	}
+	for name, rule := range invalid {
+		config := &types.Config{
+			InputDir:    "/input",
+			OutputDir:   "/output",
+			JPEGQuality: 85,
+			Rules:       []types.Rule{rule},
+		}
+		if err := ValidateConfig(config); err == nil {
+			t.Errorf("%s の場合、エラーが返されるべき", name)
+		}
+	}
+
+	config := &types.Config{
+		InputDir:    "/input",
+		OutputDir:   "/output",
+		JPEGQuality: 85,
+		Rules:       []types.Rule{{Name: "a"}, {Name: "a"}},
+	}
+	if err := ValidateConfig(config); err == nil {
+		t.Error("規則の名前が重複する場合、エラーが返されるべき")
+	}
+}
+
+func TestParseArguments_Cache(t *testing.T) {
+	config, err := parseArguments([]string{"-input-dir", "/in", "-output-dir", "/out"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.CacheDir != "" || config.CacheMaxBytes != 1<<30 {
+		t.Errorf("キャッシュの既定値=%q %d", config.CacheDir, config.CacheMaxBytes)
+	}
+
+	path := writeConfigFile(t, "config.yaml", "input-dir: /in\noutput-dir: /out\ncache-dir: /var/cache/ic\ncache-max-bytes: 256m\n")
+	env := envLookup(map[string]string{"IMAGE_CONVERTER_CACHE_MAX_BYTES": "64m"})
+	config, err = parseArguments([]string{"-config", path}, env)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	// 環境変数が設定ファイルより優先される
+	if config.CacheDir != "/var/cache/ic" || config.CacheMaxBytes != 64<<20 {
+		t.Errorf("キャッシュ設定=%q %d", config.CacheDir, config.CacheMaxBytes)
+	}
+
+	if _, err := parseArguments([]string{"-input-dir", "/in", "-output-dir", "/out", "-cache-max-bytes", "-1"}, envLookup(nil)); err == nil {
+		t.Error("負のキャッシュサイズはエラーになるべき")
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"image-converter/internal/types"
+)
+
+// EnvPrefix は環境変数名の接頭辞です
+const EnvPrefix = "IMAGE_CONVERTER_"
+
+// EnvVarName はフラグ名に対応する環境変数名を返します（例: jpeg-quality → IMAGE_CONVERTER_JPEG_QUALITY）
+func EnvVarName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyEnvironment はdefaultsの上に環境変数の値を適用したConfigを返します
+// 値の解釈はフラグと同じで、空の環境変数は未指定として扱います
+func applyEnvironment(defaults types.Config, lookupEnv func(string) (string, bool)) (*types.Config, error) {
+	config := &types.Config{}
+	var source configSource
+	fs := newFlagSet(config, defaults, &source)
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" || f.Name == "profile" {
+			return
+		}
+
+		name := EnvVarName(f.Name)
+		value, ok := lookupEnv(name)
+		if !ok || value == "" {
+			return
+		}
+
+		// -variantは;区切りで複数のバリエーションを指定できる
+		values := []string{value}
+		if f.Name == "variant" {
+			values = strings.Split(value, ";")
+		}
+		for _, v := range values {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			if setErr := f.Value.Set(strings.TrimSpace(v)); setErr != nil {
+				err = fmt.Errorf("環境変数%sの値が不正です: %w", name, setErr)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// flagNames は定義されているすべてのフラグ名を昇順で返します
+func flagNames() []string {
+	var names []string
+	var source configSource
+	newFlagSet(&types.Config{}, DefaultConfig(), &source).VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"testing"
+)
+
+// envLookup はテスト用の環境変数を返す関数を作成します
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestEnvVarName(t *testing.T) {
+	tests := map[string]string{
+		"format":              "IMAGE_CONVERTER_FORMAT",
+		"jpeg-quality":        "IMAGE_CONVERTER_JPEG_QUALITY",
+		"max-bytes-downscale": "IMAGE_CONVERTER_MAX_BYTES_DOWNSCALE",
+	}
+	for flagName, expected := range tests {
+		if got := EnvVarName(flagName); got != expected {
+			t.Errorf("EnvVarName(%q) = %s, 期待=%s", flagName, got, expected)
+		}
+	}
+}
+
+func TestParseArguments_Environment(t *testing.T) {
+	env := envLookup(map[string]string{
+		"IMAGE_CONVERTER_INPUT_DIR":        "/input",
+		"IMAGE_CONVERTER_OUTPUT_DIR":       "/output",
+		"IMAGE_CONVERTER_FORMAT":           "webp",
+		"IMAGE_CONVERTER_MAX_BYTES":        "100k",
+		"IMAGE_CONVERTER_JPEG_PROGRESSIVE": "true",
+		"IMAGE_CONVERTER_VARIANT":          "w=320;w=640,format=jpeg",
+		"IMAGE_CONVERTER_WIDTH":            "",
+	})
+
+	config, err := parseArguments([]string{"-format", "png"}, env)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	// フラグが環境変数より優先される
+	if config.Format != "png" {
+		t.Errorf("フォーマット=%s, 期待=png", config.Format)
+	}
+	if config.InputDir != "/input" || config.MaxBytes != 100*1024 || !config.JPEGProgressive {
+		t.Errorf("環境変数が適用されていない: %+v", config)
+	}
+	if len(config.Variants) != 2 || config.Variants[1].Format != "jpeg" {
+		t.Errorf("バリエーション=%+v", config.Variants)
+	}
+	// 空の環境変数は未指定として扱う
+	if config.Width != 0 {
+		t.Errorf("幅=%d, 期待=0", config.Width)
+	}
+}
+
+func TestParseArguments_EnvironmentOverridesConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "input-dir: /input\noutput-dir: /output\nformat: gif\njpeg-quality: 60\n")
+
+	env := envLookup(map[string]string{
+		"IMAGE_CONVERTER_CONFIG": path,
+		"IMAGE_CONVERTER_FORMAT": "webp",
+	})
+
+	config, err := parseArguments(nil, env)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.Format != "webp" {
+		t.Errorf("フォーマット=%s, 期待=webp", config.Format)
+	}
+	if config.JPEGQuality != 60 {
+		t.Errorf("品質=%d, 期待=60", config.JPEGQuality)
+	}
+}
+
+func TestParseArguments_InvalidEnvironment(t *testing.T) {
+	env := envLookup(map[string]string{
+		"IMAGE_CONVERTER_INPUT_DIR":  "/input",
+		"IMAGE_CONVERTER_OUTPUT_DIR": "/output",
+		"IMAGE_CONVERTER_WIDTH":      "wide",
+	})
+
+	if _, err := parseArguments(nil, env); err == nil {
+		t.Error("不正な環境変数の値はエラーになるべき")
+	}
+}
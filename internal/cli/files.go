@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"image-converter/internal/types"
+)
+
+// stdin は-files-from -で読み込む標準入力です（テストで差し替え可能）
+var stdin io.Reader = os.Stdin
+
+// readFileListFrom はpathから入力ファイルの一覧を読み込みます（"-"の場合は標準入力）
+func readFileListFrom(path string) ([]string, error) {
+	var r io.Reader
+	if path == "-" {
+		r = stdin
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("ファイル一覧を読み込めません: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	files, err := ReadFileList(r)
+	if err != nil {
+		return nil, fmt.Errorf("ファイル一覧を読み込めません: %w", err)
+	}
+	return files, nil
+}
+
+// ReadFileList は改行区切りまたはNUL区切り（find -print0の出力）のファイル一覧を読み込みます
+// NUL文字を含む場合はNUL区切りとして扱い、空の行は無視します
+func ReadFileList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	if bytes.IndexByte(data, 0) >= 0 {
+		entries = strings.Split(string(data), "\x00")
+	} else {
+		entries = strings.Split(string(data), "\n")
+		for i, entry := range entries {
+			entries[i] = strings.TrimSuffix(entry, "\r")
+		}
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		files = append(files, entry)
+	}
+	return files, nil
+}
+
+// StdioPath は入力ファイル・出力パスとして標準入力・標準出力を表す値です
+const StdioPath = "-"
+
+// readsStdin は入力ファイルに標準入力が含まれるか判定します
+func readsStdin(config *types.Config) bool {
+	for _, file := range config.Files {
+		if file == StdioPath {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSingleFileMode は-oまたは標準入力による単一ファイルモードの設定を検証します
+// 入力ファイルが"-"の場合は標準入力から読み込み、-oが空または"-"の場合は標準出力に書き込みます
+func validateSingleFileMode(config *types.Config) error {
+	if len(config.Files) != 1 {
+		return fmt.Errorf("-oまたは標準入力を使用する場合は入力ファイルを1つだけ指定してください")
+	}
+	if config.FilesFrom == StdioPath && readsStdin(config) {
+		return fmt.Errorf("標準入力を入力ファイルと-files-fromの両方に使用できません")
+	}
+	if config.OutputDir != "" {
+		return fmt.Errorf("-oまたは標準入力と出力ディレクトリを同時に指定できません")
+	}
+	if len(config.Variants) > 0 {
+		return fmt.Errorf("-oまたは標準入力とバリエーションを同時に使用できません")
+	}
+
+	// 出力パスの拡張子と-formatの整合性チェック
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(config.OutputPath), "."))
+	if ext == "" || config.Format == "" || config.OutputPath == StdioPath {
+		return nil
+	}
+	format, err := normalizeFormat(ext)
+	if err != nil {
+		// 画像の拡張子でなければ-formatに従う
+		return nil
+	}
+	normalized, err := normalizeFormat(config.Format)
+	if err == nil && normalized != format {
+		return fmt.Errorf("出力パスの拡張子（%s）と出力フォーマット（%s）が一致しません", ext, config.Format)
+	}
+	return nil
+}
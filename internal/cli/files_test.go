@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"改行区切り", "a.jpg\nb.png\n\nc d.gif\n", []string{"a.jpg", "b.png", "c d.gif"}},
+		{"CRLF", "a.jpg\r\nb.png\r\n", []string{"a.jpg", "b.png"}},
+		{"NUL区切り", "a.jpg\x00dir/with\nnewline.png\x00", []string{"a.jpg", "dir/with\nnewline.png"}},
+		{"空", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ReadFileList(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("予期しないエラー: %v", err)
+			}
+			if !reflect.DeepEqual(files, tt.expected) {
+				t.Errorf("ReadFileList() = %q, 期待=%q", files, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseArguments_PositionalFiles(t *testing.T) {
+	config, err := parseArguments([]string{"a.jpg", "-output-dir", "/out", "b.png", "--", "-c.gif"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	expected := []string{"a.jpg", "b.png", "-c.gif"}
+	if !reflect.DeepEqual(config.Files, expected) {
+		t.Errorf("Files = %q, 期待=%q", config.Files, expected)
+	}
+	if config.OutputDir != "/out" {
+		t.Errorf("出力ディレクトリ=%s, 期待=/out", config.OutputDir)
+	}
+}
+
+func TestParseArguments_FilesFrom(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(listPath, []byte("x.jpg\ny.jpg\n"), 0644); err != nil {
+		t.Fatalf("一覧ファイルの作成に失敗: %v", err)
+	}
+
+	config, err := parseArguments([]string{"-files-from", listPath, "-output-dir", "/out", "a.jpg"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if !reflect.DeepEqual(config.Files, []string{"a.jpg", "x.jpg", "y.jpg"}) {
+		t.Errorf("Files = %q", config.Files)
+	}
+
+	// 標準入力から読み込む
+	original := stdin
+	defer func() { stdin = original }()
+	stdin = strings.NewReader("p.png\x00q.png\x00")
+
+	config, err = parseArguments([]string{"-files-from", "-", "-output-dir", "/out"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if !reflect.DeepEqual(config.Files, []string{"p.png", "q.png"}) {
+		t.Errorf("Files = %q", config.Files)
+	}
+}
+
+func TestParseArguments_SingleFileMode(t *testing.T) {
+	config, err := parseArguments([]string{"-o", "out.webp", "in.jpg"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.OutputPath != "out.webp" || len(config.Files) != 1 {
+		t.Errorf("単一ファイルモードの設定が不正: %+v", config)
+	}
+
+	invalid := map[string][]string{
+		"入力ファイルなし":       {"-o", "out.webp"},
+		"複数の入力ファイル":      {"-o", "out.webp", "a.jpg", "b.jpg"},
+		"出力ディレクトリとの同時指定": {"-o", "out.webp", "-output-dir", "/out", "a.jpg"},
+		"拡張子とフォーマットの不一致": {"-o", "out.webp", "-format", "png", "a.jpg"},
+		"入力ディレクトリとの同時指定": {"-input-dir", "/in", "-output-dir", "/out", "a.jpg"},
+		"バリエーションとの同時指定":  {"-o", "out.webp", "-variant", "w=100", "a.jpg"},
+	}
+	for name, args := range invalid {
+		if _, err := parseArguments(args, envLookup(nil)); err == nil {
+			t.Errorf("%s の場合、エラーが返されるべき", name)
+		}
+	}
+}
+
+func TestParseArguments_Stdin(t *testing.T) {
+	config, err := parseArguments([]string{"-format", "webp", "-width", "800", "-"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if !reflect.DeepEqual(config.Files, []string{"-"}) || config.OutputPath != "" {
+		t.Errorf("標準入力モードの設定が不正: %+v", config)
+	}
+
+	invalid := map[string][]string{
+		"出力ディレクトリとの同時指定": {"-output-dir", "/out", "-"},
+		"他の入力ファイルとの同時指定": {"-o", "out.png", "-", "a.png"},
+	}
+	for name, args := range invalid {
+		if _, err := parseArguments(args, envLookup(nil)); err == nil {
+			t.Errorf("%s の場合、エラーが返されるべき", name)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"image-converter/internal/types"
+)
+
+// ParseInfoArguments はinfoサブコマンドの引数を解析してInfoConfigを返します
+func ParseInfoArguments(args []string) (*types.InfoConfig, error) {
+	config := &types.InfoConfig{}
+
+	fs := flag.NewFlagSet("image-converter info", flag.ContinueOnError)
+	fs.Usage = PrintInfoUsage
+	fs.BoolVar(&config.JSON, "json", false, "JSON形式で出力する")
+
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("画像ファイルが指定されていません")
+	}
+	config.Files = files
+
+	return config, nil
+}
+
+// PrintInfoUsage はinfoサブコマンドの使用方法を表示します
+func PrintInfoUsage() {
+	fmt.Fprintf(os.Stderr, "Image Converter CLI - 画像の情報表示\n\n")
+	fmt.Fprintf(os.Stderr, "使用方法:\n")
+	fmt.Fprintf(os.Stderr, "  image-converter info [-json] <ファイル>...\n")
+	fmt.Fprintf(os.Stderr, "  例: image-converter info -json photos/*.jpg\n\n")
+
+	fmt.Fprintf(os.Stderr, "オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -json\n")
+	fmt.Fprintf(os.Stderr, "        JSON形式で出力（デフォルト: テキスト）\n\n")
+
+	fmt.Fprintf(os.Stderr, "内容から判定したフォーマット、サイズ、カラーモデル、ビット深度、透過の有無、\n")
+	fmt.Fprintf(os.Stderr, "フレーム数（GIF/WebP）、EXIFの向き、ICCプロファイルの有無を表示します\n")
+	fmt.Fprintf(os.Stderr, "読み込めないファイルがある場合は終了コード1\n")
+}
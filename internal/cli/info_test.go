@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInfoArguments(t *testing.T) {
+	config, err := ParseInfoArguments([]string{"a.png", "-json", "b.jpg"})
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if !config.JSON || !reflect.DeepEqual(config.Files, []string{"a.png", "b.jpg"}) {
+		t.Errorf("設定が正しくありません: %+v", config)
+	}
+
+	if _, err := ParseInfoArguments([]string{"-json"}); err == nil {
+		t.Error("ファイルが指定されていない場合はエラーが返されるべき")
+	}
+	if _, err := ParseInfoArguments([]string{"-unknown", "a.png"}); err == nil {
+		t.Error("不明なフラグはエラーが返されるべき")
+	}
+}
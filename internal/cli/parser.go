@@ -4,25 +4,72 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"image-converter/internal/types"
 )
 
 // ParseArgs はコマンドライン引数を解析してConfig構造体を返します
 func ParseArgs() (*types.Config, error) {
-	config := &types.Config{}
+	return ParseArguments(os.Args[1:])
+}
 
-	// フラグの定義
-	flag.StringVar(&config.InputDir, "input-dir", "", "入力ディレクトリのパス（必須）")
-	flag.StringVar(&config.OutputDir, "output-dir", "", "出力ディレクトリのパス（必須）")
-	flag.Float64Var(&config.Scale, "scale", 0, "画像の倍率（例: 0.5で50%、2.0で200%）")
-	flag.IntVar(&config.Width, "width", 0, "出力画像の幅（ピクセル）")
-	flag.IntVar(&config.Height, "height", 0, "出力画像の高さ（ピクセル）")
-	flag.StringVar(&config.Format, "format", "", "出力フォーマット（jpeg, png, webp, gif, bmp）")
-	flag.IntVar(&config.JPEGQuality, "jpeg-quality", 85, "JPEG品質（1-100、デフォルト: 85）")
+// ParseArguments は引数リストを解析してConfig構造体を返します
+// 設定ファイル（-config）と環境変数（IMAGE_CONVERTER_*）の値を順に適用し、最後にフラグの値を適用します
+// （優先順位: フラグ > 環境変数 > 設定ファイル > 既定値）
+func ParseArguments(args []string) (*types.Config, error) {
+	return parseArguments(args, os.LookupEnv)
+}
 
-	flag.Parse()
+// parseArguments はlookupEnvで環境変数を参照してParseArgumentsを実行します
+func parseArguments(args []string, lookupEnv func(string) (string, bool)) (*types.Config, error) {
+	_, source, err := parseFlags(args, DefaultConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	// -config/-profileが指定されていなければ環境変数を参照
+	if source.path == "" {
+		source.path, _ = lookupEnv(EnvVarName("config"))
+	}
+	if source.profile == "" {
+		source.profile, _ = lookupEnv(EnvVarName("profile"))
+	}
+	if source.profile != "" && source.path == "" {
+		return nil, fmt.Errorf("-profileを使用するには-configで設定ファイルを指定してください")
+	}
+
+	defaults := DefaultConfig()
+	if source.path != "" {
+		fileConfig, err := LoadConfigFile(source.path, source.profile, defaults)
+		if err != nil {
+			return nil, err
+		}
+		defaults = *fileConfig
+	}
+
+	envConfig, err := applyEnvironment(defaults, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	// 設定ファイル・環境変数の値を既定値としてフラグを再解析する
+	config, _, err := parseFlags(args, *envConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// -files-fromで指定された入力ファイルを追加
+	if config.FilesFrom != "" {
+		files, err := readFileListFrom(config.FilesFrom)
+		if err != nil {
+			return nil, err
+		}
+		config.Files = append(config.Files, files...)
+	}
 
 	// 設定の検証
 	if err := ValidateConfig(config); err != nil {
@@ -32,17 +79,437 @@ func ParseArgs() (*types.Config, error) {
 	return config, nil
 }
 
+// DefaultConfig はフラグ・設定ファイルで指定されなかった場合の既定値を返します
+func DefaultConfig() types.Config {
+	return types.Config{
+		JPEGQuality:     85,
+		JPEGSubsampling: "420",
+		Colors:          256,
+		Dither:          "floyd-steinberg",
+		PNGMaxError:     8,
+		CacheMaxBytes:   1 << 30,
+		WatchInterval:   time.Second,
+		PlanFormat:      "table",
+	}
+}
+
+// configSource は-config/-profileで指定された設定ファイルの情報です
+type configSource struct {
+	path    string
+	profile string
+}
+
+// parseFlags はdefaultsを既定値としてフラグを解析します
+func parseFlags(args []string, defaults types.Config) (*types.Config, configSource, error) {
+	config := &types.Config{}
+	var source configSource
+
+	fs := newFlagSet(config, defaults, &source)
+	fs.Usage = PrintUsage
+
+	// 位置引数は入力ファイルとして扱う
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, source, err
+	}
+	if len(files) > 0 {
+		config.Files = files
+	}
+
+	return config, source, nil
+}
+
+// parseInterspersed はフラグと位置引数の混在を許可してargsを解析し、位置引数を返します
+// "--"以降はすべて位置引数として扱います
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	return positional, nil
+}
+
+// newFlagSet はすべてのフラグを定義したFlagSetを作成します
+func newFlagSet(config *types.Config, defaults types.Config, source *configSource) *flag.FlagSet {
+	fs := flag.NewFlagSet("image-converter", flag.ContinueOnError)
+	defineFlags(fs, config, defaults)
+	fs.StringVar(&source.path, "config", "", "設定ファイルのパス（YAML, TOML, JSON）")
+	fs.StringVar(&source.profile, "profile", "", "設定ファイル内で使用するプロファイル名")
+	return fs
+}
+
+// defineFlags はconfigの各項目に対応するフラグをdefaultsを既定値として定義します
+// フラグのない項目（規則など）はdefaultsの値を引き継ぎます
+func defineFlags(fs *flag.FlagSet, config *types.Config, defaults types.Config) {
+	*config = defaults
+
+	fs.StringVar(&config.InputDir, "input-dir", defaults.InputDir, "入力ディレクトリのパス（必須）")
+	fs.StringVar(&config.OutputDir, "output-dir", defaults.OutputDir, "出力ディレクトリのパス（必須）")
+	fs.Float64Var(&config.Scale, "scale", defaults.Scale, "画像の倍率（例: 0.5で50%、2.0で200%）")
+	fs.IntVar(&config.Width, "width", defaults.Width, "出力画像の幅（ピクセル）")
+	fs.IntVar(&config.Height, "height", defaults.Height, "出力画像の高さ（ピクセル）")
+	fs.StringVar(&config.Fit, "fit", defaults.Fit, "幅と高さの両方を指定した場合の合わせ方（contain, cover）")
+	fs.StringVar(&config.Format, "format", defaults.Format, "出力フォーマット（jpeg, png, webp, gif, bmp）")
+	fs.IntVar(&config.JPEGQuality, "jpeg-quality", defaults.JPEGQuality, "JPEG品質（1-100、デフォルト: 85）")
+	fs.BoolVar(&config.JPEGProgressive, "jpeg-progressive", defaults.JPEGProgressive, "プログレッシブJPEGで出力")
+	fs.StringVar(&config.JPEGSubsampling, "jpeg-subsampling", defaults.JPEGSubsampling, "JPEGの色差サブサンプリング（444, 422, 420）")
+	config.MaxBytes = defaults.MaxBytes
+	fs.Var((*byteSizeValue)(&config.MaxBytes), "max-bytes", "出力ファイルサイズの上限（例: 200k, 1.5m）。品質を自動調整して収める")
+	fs.BoolVar(&config.AllowDownscale, "max-bytes-downscale", defaults.AllowDownscale, "最低品質でも-max-bytesを超える場合はさらに縮小する")
+	fs.Float64Var(&config.TargetSSIM, "target-ssim", defaults.TargetSSIM, "目標とするSSIM（例: 0.98）。満たす最小の出力を自動選択")
+	config.Variants = append([]types.Variant(nil), defaults.Variants...)
+	fs.Var(&variantListValue{variants: &config.Variants}, "variant", "出力バリエーション（例: w=320,suffix=-sm）。複数指定可")
+	fs.StringVar(&config.ManifestPath, "manifest", defaults.ManifestPath, "変換後に出力ファイルの一覧をJSONマニフェストとして書き込むパス")
+	fs.StringVar(&config.ManifestHTMLPath, "manifest-html", defaults.ManifestHTMLPath, "変換後に<picture>タグのHTMLスニペットを書き込むパス")
+	fs.IntVar(&config.Colors, "colors", defaults.Colors, "GIF・パレットPNGの最大色数（2-256、デフォルト: 256）")
+	fs.StringVar(&config.Dither, "dither", defaults.Dither, "ディザリング方式（none, floyd-steinberg, ordered）")
+	fs.BoolVar(&config.PNGPalette, "png-palette", defaults.PNGPalette, "PNGを8ビットパレット形式で出力（-colorsで色数を指定）")
+	fs.Float64Var(&config.PNGMaxError, "png-max-error", defaults.PNGMaxError, "パレットPNGの許容誤差（RMSE）。超えた場合はフルカラーで出力（0で無制限）")
+	fs.BoolVar(&config.FirstFrameOnly, "first-frame-only", defaults.FirstFrameOnly, "アニメーション画像の最初のフレームのみを出力")
+	fs.StringVar(&config.FilesFrom, "files-from", defaults.FilesFrom, "入力ファイルの一覧（改行またはNUL区切り、-で標準入力）")
+	fs.StringVar(&config.OutputPath, "o", defaults.OutputPath, "単一ファイルモードの出力パス")
+	fs.StringVar(&config.CacheDir, "cache-dir", defaults.CacheDir, "変換結果のキャッシュディレクトリ（同じ入力・設定の出力を再利用）")
+	config.CacheMaxBytes = defaults.CacheMaxBytes
+	fs.Var((*byteSizeValue)(&config.CacheMaxBytes), "cache-max-bytes", "キャッシュの合計サイズの上限（例: 512m、0で無制限）")
+	fs.BoolVar(&config.Watch, "watch", defaults.Watch, "初回の変換後も入力ディレクトリを監視し、追加・変更された画像を変換する")
+	fs.BoolVar(&config.WatchDelete, "watch-delete", defaults.WatchDelete, "監視中に削除された入力の出力を削除する")
+	fs.DurationVar(&config.WatchInterval, "watch-interval", defaults.WatchInterval, "書き込みの完了を判定する間隔（例: 2s）")
+	fs.BoolVar(&config.Sync, "sync", defaults.Sync, "変換後、入力が存在しなくなった出力を削除する")
+	fs.BoolVar(&config.DryRun, "dry-run", defaults.DryRun, "画像の書き込みや削除を行わず、変換計画を表示する")
+	fs.StringVar(&config.PlanFormat, "plan-format", defaults.PlanFormat, "-dry-runの変換計画の表示形式（table, json）")
+}
+
+// byteSizeValue は"200k"のような単位付きのバイト数を受け付けるフラグ値です
+type byteSizeValue int64
+
+func (v *byteSizeValue) String() string {
+	return strconv.FormatInt(int64(*v), 10)
+}
+
+func (v *byteSizeValue) Set(s string) error {
+	n, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*v = byteSizeValue(n)
+	return nil
+}
+
+// ParseByteSize は単位付きのバイト数を解析します
+// 単位: なし（バイト）、k（KiB）、m（MiB）、g（GiB）。大文字小文字および末尾の"b"は無視されます
+func ParseByteSize(s string) (int64, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	str = strings.TrimSuffix(str, "b")
+
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(str, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(str, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(str, "g"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		str = str[:len(str)-1]
+	}
+
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("無効なサイズ指定: %s", s)
+	}
+
+	return int64(n * multiplier), nil
+}
+
+// variantListValue は複数回指定できる-variantフラグの値です
+// 最初の指定で既定値（設定ファイルのバリエーション）を置き換えます
+type variantListValue struct {
+	variants *[]types.Variant
+	set      bool
+}
+
+func (v *variantListValue) String() string {
+	if v == nil || v.variants == nil {
+		return ""
+	}
+	specs := make([]string, len(*v.variants))
+	for i, variant := range *v.variants {
+		specs[i] = FormatVariant(variant)
+	}
+	return strings.Join(specs, " ")
+}
+
+func (v *variantListValue) Set(s string) error {
+	variant, err := ParseVariant(s)
+	if err != nil {
+		return err
+	}
+	if !v.set {
+		*v.variants = nil
+		v.set = true
+	}
+	*v.variants = append(*v.variants, variant)
+	return nil
+}
+
+// ParseVariant は"w=320,format=webp,suffix=-sm"形式のバリエーション指定を解析します
+// キー: w/width, h/height, scale, format, q/quality, suffix
+func ParseVariant(spec string) (types.Variant, error) {
+	var variant types.Variant
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return variant, fmt.Errorf("無効なバリエーション指定: %s", part)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch key {
+		case "w", "width":
+			variant.Resize.Width, err = strconv.Atoi(value)
+		case "h", "height":
+			variant.Resize.Height, err = strconv.Atoi(value)
+		case "scale":
+			variant.Resize.Scale, err = strconv.ParseFloat(value, 64)
+		case "format":
+			variant.Format = value
+		case "q", "quality":
+			variant.Quality, err = strconv.Atoi(value)
+		case "suffix":
+			variant.Suffix = value
+		default:
+			return variant, fmt.Errorf("不明なバリエーションのキー: %s", key)
+		}
+		if err != nil {
+			return variant, fmt.Errorf("無効なバリエーションの値: %s", part)
+		}
+	}
+
+	return variant, nil
+}
+
+// FormatVariant はバリエーションを-variantフラグの形式の文字列に変換します
+func FormatVariant(variant types.Variant) string {
+	var parts []string
+	if variant.Resize.Width > 0 {
+		parts = append(parts, fmt.Sprintf("w=%d", variant.Resize.Width))
+	}
+	if variant.Resize.Height > 0 {
+		parts = append(parts, fmt.Sprintf("h=%d", variant.Resize.Height))
+	}
+	if variant.Resize.Scale > 0 {
+		parts = append(parts, "scale="+strconv.FormatFloat(variant.Resize.Scale, 'g', -1, 64))
+	}
+	if variant.Format != "" {
+		parts = append(parts, "format="+variant.Format)
+	}
+	if variant.Quality > 0 {
+		parts = append(parts, fmt.Sprintf("q=%d", variant.Quality))
+	}
+	if variant.Suffix != "" {
+		parts = append(parts, "suffix="+variant.Suffix)
+	}
+	return strings.Join(parts, ",")
+}
+
+// defaultVariantSuffix はリサイズ仕様から接尾辞を生成します（例: -320w, -2x）
+func defaultVariantSuffix(spec types.ResizeSpec) string {
+	switch {
+	case spec.Width > 0 && spec.Height > 0:
+		return fmt.Sprintf("-%dx%d", spec.Width, spec.Height)
+	case spec.Width > 0:
+		return fmt.Sprintf("-%dw", spec.Width)
+	case spec.Height > 0:
+		return fmt.Sprintf("-%dh", spec.Height)
+	case spec.Scale > 0:
+		return "-" + strconv.FormatFloat(spec.Scale, 'g', -1, 64) + "x"
+	default:
+		return ""
+	}
+}
+
+// validateVariants はバリエーションの妥当性を検証し、フォーマットと接尾辞を正規化します
+func validateVariants(config *types.Config) error {
+	seen := make(map[string][]string) // 接尾辞 → 出力フォーマット（""は入力と同じ）
+
+	for i := range config.Variants {
+		variant := &config.Variants[i]
+		spec := variant.Resize
+
+		if spec.Scale < 0 || spec.Width < 0 || spec.Height < 0 {
+			return fmt.Errorf("バリエーション%d: サイズ指定は0以上である必要があります", i+1)
+		}
+		if spec.Scale > 0 && (spec.Width > 0 || spec.Height > 0) {
+			return fmt.Errorf("バリエーション%d: 倍率指定とピクセル指定を同時に使用できません", i+1)
+		}
+		if variant.Quality < 0 || variant.Quality > 100 {
+			return fmt.Errorf("バリエーション%d: 品質は1から100の範囲で指定してください", i+1)
+		}
+
+		if variant.Format != "" {
+			format, err := normalizeFormat(variant.Format)
+			if err != nil {
+				return fmt.Errorf("バリエーション%d: %w", i+1, err)
+			}
+			variant.Format = format
+		}
+
+		if variant.Suffix == "" {
+			variant.Suffix = defaultVariantSuffix(spec)
+		}
+		if strings.ContainsAny(variant.Suffix, `/\`) {
+			return fmt.Errorf("バリエーション%d: 接尾辞にパス区切り文字は使用できません: %s", i+1, variant.Suffix)
+		}
+
+		// 出力ファイル名の重複チェック（フォーマット未指定は基本設定のフォーマットを使用）
+		// 基本設定のフォーマットもない場合は入力ごとに決まるため、同じ接尾辞のどのフォーマットとも重複しうる
+		format := variant.Format
+		if format == "" {
+			format = config.Format
+		}
+		for _, other := range seen[variant.Suffix] {
+			if other == format || other == "" || format == "" {
+				return fmt.Errorf("バリエーション%d: 出力ファイル名が他のバリエーションと重複します（接尾辞: %q）", i+1, variant.Suffix)
+			}
+		}
+		seen[variant.Suffix] = append(seen[variant.Suffix], format)
+	}
+
+	return nil
+}
+
+// normalizeFormat はフォーマット名を検証して正規化します
+func normalizeFormat(value string) (string, error) {
+	format := strings.ToLower(value)
+	validFormats := map[string]bool{
+		"jpeg": true,
+		"jpg":  true,
+		"png":  true,
+		"webp": true,
+		"gif":  true,
+		"bmp":  true,
+	}
+
+	if !validFormats[format] {
+		return "", fmt.Errorf("サポートされていないフォーマット: %s", value)
+	}
+
+	// jpegとjpgを正規化
+	if format == "jpg" {
+		return "jpeg", nil
+	}
+	return format, nil
+}
+
 // ValidateConfig は設定の妥当性を検証します
 func ValidateConfig(config *types.Config) error {
 	// 必須パラメータのチェック
-	if config.InputDir == "" {
+	hasFiles := len(config.Files) > 0 || config.FilesFrom != ""
+	if config.InputDir == "" && !hasFiles {
 		return fmt.Errorf("入力ディレクトリが指定されていません")
 	}
 
-	if config.OutputDir == "" {
+	if config.InputDir != "" && hasFiles {
+		return fmt.Errorf("入力ディレクトリと入力ファイルを同時に指定できません")
+	}
+
+	if config.OutputPath != "" || readsStdin(config) {
+		// 単一ファイルモード（標準入力・標準出力を含む）
+		if err := validateSingleFileMode(config); err != nil {
+			return err
+		}
+	} else if config.OutputDir == "" {
 		return fmt.Errorf("出力ディレクトリが指定されていません")
 	}
 
+	if config.CacheMaxBytes < 0 {
+		return fmt.Errorf("キャッシュサイズの上限は0以上である必要があります")
+	}
+
+	if err := validateWatch(config); err != nil {
+		return err
+	}
+
+	if config.Sync && config.InputDir == "" {
+		return fmt.Errorf("-syncには入力ディレクトリ（-input-dir）が必要です")
+	}
+	if err := validateDryRun(config); err != nil {
+		return err
+	}
+
+	return ValidateOptions(config)
+}
+
+// validateDryRun は-dry-run（変換計画の表示）の設定を検証します
+func validateDryRun(config *types.Config) error {
+	if config.PlanFormat != "" && config.PlanFormat != "table" && config.PlanFormat != "json" {
+		return fmt.Errorf("無効な変換計画の表示形式です: %s（table, jsonのいずれかを指定してください）", config.PlanFormat)
+	}
+	if !config.DryRun {
+		return nil
+	}
+	if config.OutputPath != "" || readsStdin(config) {
+		return fmt.Errorf("-dry-runは単一ファイルモード（-oまたは標準入力）では使用できません")
+	}
+	if config.Watch {
+		return fmt.Errorf("-dry-runと-watchを同時に使用できません")
+	}
+	return nil
+}
+
+// validateWatch は監視モードの設定を検証します
+func validateWatch(config *types.Config) error {
+	if config.WatchDelete && !config.Watch {
+		return fmt.Errorf("-watch-deleteは-watchと同時に指定する必要があります")
+	}
+	if !config.Watch {
+		return nil
+	}
+	if config.InputDir == "" {
+		return fmt.Errorf("監視モードには入力ディレクトリ（-input-dir）が必要です")
+	}
+	if config.WatchInterval <= 0 {
+		return fmt.Errorf("監視の間隔は0より大きい必要があります")
+	}
+	// 出力を新しい入力として変換し続け、入力を上書きしないように出力先を分ける
+	if sameDirectory(config.InputDir, config.OutputDir) {
+		return fmt.Errorf("監視モードでは出力ディレクトリ（-output-dir）を入力ディレクトリと別にする必要があります")
+	}
+	return nil
+}
+
+// sameDirectory は2つのパスが同じディレクトリを指すかを返します
+func sameDirectory(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// ValidateOptions は入出力の指定以外の変換設定（リサイズ、フォーマット、エンコーダー設定など）を検証して正規化します
+func ValidateOptions(config *types.Config) error {
 	// スケールとピクセル指定の排他チェック（要件 2.8）
 	hasScale := config.Scale > 0
 	hasPixels := config.Width > 0 || config.Height > 0
@@ -65,43 +532,130 @@ func ValidateConfig(config *types.Config) error {
 		return fmt.Errorf("高さは0以上である必要があります")
 	}
 
+	// 合わせ方の検証
+	if config.Fit != "" {
+		fit, err := normalizeFit(config.Fit)
+		if err != nil {
+			return err
+		}
+		config.Fit = fit
+	}
+
 	// フォーマットの検証（要件 3.6）
 	if config.Format != "" {
-		format := strings.ToLower(config.Format)
-		validFormats := map[string]bool{
-			"jpeg": true,
-			"jpg":  true,
-			"png":  true,
-			"webp": true,
-			"gif":  true,
-			"bmp":  true,
+		format, err := normalizeFormat(config.Format)
+		if err != nil {
+			return err
 		}
+		config.Format = format
+	}
 
-		if !validFormats[format] {
-			return fmt.Errorf("サポートされていないフォーマット: %s", config.Format)
+	// JPEG品質の検証（要件 8.1, 8.4）
+	if config.JPEGQuality < 1 || config.JPEGQuality > 100 {
+		return fmt.Errorf("JPEG品質は1から100の範囲で指定してください")
+	}
+
+	// JPEGサブサンプリングの検証（"4:4:4"のような表記も受け付ける）
+	if config.JPEGSubsampling != "" {
+		subsampling, err := normalizeSubsampling(config.JPEGSubsampling)
+		if err != nil {
+			return err
 		}
+		config.JPEGSubsampling = subsampling
+	}
+
+	// サイズ上限の検証
+	if config.MaxBytes < 0 {
+		return fmt.Errorf("サイズ上限は0以上である必要があります")
+	}
 
-		// jpegとjpgを正規化
-		if format == "jpg" {
-			config.Format = "jpeg"
-		} else {
-			config.Format = format
+	// 目標SSIMの検証
+	if config.TargetSSIM < 0 || config.TargetSSIM > 1 {
+		return fmt.Errorf("目標SSIMは0から1の範囲で指定してください")
+	}
+
+	if config.TargetSSIM > 0 && config.MaxBytes > 0 {
+		return fmt.Errorf("サイズ上限と目標SSIMを同時に使用できません")
+	}
+
+	// バリエーションの検証
+	if err := validateVariants(config); err != nil {
+		return err
+	}
+
+	// 色数の検証（0は未指定としてデフォルトの256色を使用）
+	if config.Colors != 0 && (config.Colors < 2 || config.Colors > 256) {
+		return fmt.Errorf("色数は2から256の範囲で指定してください")
+	}
+
+	// パレットPNGの許容誤差の検証
+	if config.PNGMaxError < 0 {
+		return fmt.Errorf("パレットPNGの許容誤差は0以上である必要があります")
+	}
+
+	// ディザリング方式の検証
+	if config.Dither != "" {
+		dither, err := normalizeDither(config.Dither)
+		if err != nil {
+			return err
 		}
+		config.Dither = dither
 	}
 
-	// JPEG品質の検証（要件 8.1, 8.4）
-	if config.JPEGQuality < 1 || config.JPEGQuality > 100 {
-		return fmt.Errorf("JPEG品質は1から100の範囲で指定してください")
+	// 規則の検証
+	if err := validateRules(config); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// normalizeFit は幅と高さの合わせ方を検証して正規化します
+func normalizeFit(value string) (string, error) {
+	fit := strings.ToLower(value)
+	switch types.FitMode(fit) {
+	case types.FitContain, types.FitCover:
+		return fit, nil
+	default:
+		return "", fmt.Errorf("サポートされていない合わせ方: %s", value)
+	}
+}
+
+// normalizeSubsampling はJPEGサブサンプリングを検証して正規化します（"4:4:4"のような表記も受け付ける）
+func normalizeSubsampling(value string) (string, error) {
+	subsampling := strings.ReplaceAll(value, ":", "")
+	switch types.ChromaSubsampling(subsampling) {
+	case types.Subsampling444, types.Subsampling422, types.Subsampling420:
+		return subsampling, nil
+	default:
+		return "", fmt.Errorf("サポートされていないサブサンプリング: %s", value)
+	}
+}
+
+// normalizeDither はディザリング方式を検証して正規化します
+func normalizeDither(value string) (string, error) {
+	dither := strings.ToLower(value)
+	switch types.DitherMode(dither) {
+	case types.DitherNone, types.DitherFloydSteinberg, types.DitherOrdered:
+		return dither, nil
+	default:
+		return "", fmt.Errorf("サポートされていないディザリング方式: %s", value)
+	}
+}
+
 // PrintUsage は使用方法を表示します
 func PrintUsage() {
 	fmt.Fprintf(os.Stderr, "Image Converter CLI - 画像一括変換ツール\n\n")
 	fmt.Fprintf(os.Stderr, "使用方法:\n")
-	fmt.Fprintf(os.Stderr, "  image-converter -input-dir <入力ディレクトリ> -output-dir <出力ディレクトリ> [オプション]\n\n")
+	fmt.Fprintf(os.Stderr, "  image-converter -input-dir <入力ディレクトリ> -output-dir <出力ディレクトリ> [オプション]\n")
+	fmt.Fprintf(os.Stderr, "  image-converter -output-dir <出力ディレクトリ> [オプション] <ファイル>...\n")
+	fmt.Fprintf(os.Stderr, "  image-converter -files-from <一覧ファイル|-> -output-dir <出力ディレクトリ> [オプション]\n")
+	fmt.Fprintf(os.Stderr, "  image-converter -o <出力ファイル> [オプション] <ファイル>\n")
+	fmt.Fprintf(os.Stderr, "  cat in.png | image-converter [オプション] - > out.png\n")
+	fmt.Fprintf(os.Stderr, "  image-converter serve [オプション]（HTTP変換サーバー、詳細は image-converter serve -h）\n")
+	fmt.Fprintf(os.Stderr, "  image-converter sign -secret <秘密鍵> <URL>...（変換サーバーの署名付きURLを生成）\n")
+	fmt.Fprintf(os.Stderr, "  image-converter info [-json] <ファイル>...（画像のフォーマット・サイズなどを表示）\n")
+	fmt.Fprintf(os.Stderr, "  image-converter compare [-resize] [-diff <ヒートマップ>] <画像A> <画像B>（PSNR・SSIMで比較）\n\n")
 	
 	fmt.Fprintf(os.Stderr, "必須オプション:\n")
 	fmt.Fprintf(os.Stderr, "  -input-dir string\n")
@@ -117,15 +671,119 @@ func PrintUsage() {
 	fmt.Fprintf(os.Stderr, "  -height int\n")
 	fmt.Fprintf(os.Stderr, "        出力画像の高さ（ピクセル）。縦横比を維持して幅を自動計算\n")
 	fmt.Fprintf(os.Stderr, "  -width と -height\n")
-	fmt.Fprintf(os.Stderr, "        両方指定した場合、縦横比を維持しながら指定範囲内に収める\n\n")
+	fmt.Fprintf(os.Stderr, "        両方指定した場合、縦横比を維持しながら指定範囲内に収める\n")
+	fmt.Fprintf(os.Stderr, "  -fit string\n")
+	fmt.Fprintf(os.Stderr, "        -widthと-heightの合わせ方: contain（範囲内に収める）, cover（範囲を覆い中央を切り取る）\n\n")
 	
 	fmt.Fprintf(os.Stderr, "フォーマットオプション:\n")
 	fmt.Fprintf(os.Stderr, "  -format string\n")
 	fmt.Fprintf(os.Stderr, "        出力フォーマット: jpeg, png, webp, gif, bmp\n")
 	fmt.Fprintf(os.Stderr, "        指定しない場合は元のフォーマットを維持\n")
 	fmt.Fprintf(os.Stderr, "  -jpeg-quality int\n")
-	fmt.Fprintf(os.Stderr, "        JPEG品質（1-100）（デフォルト: 85）\n\n")
+	fmt.Fprintf(os.Stderr, "        JPEG品質（1-100）（デフォルト: 85）\n")
+	fmt.Fprintf(os.Stderr, "  -jpeg-progressive\n")
+	fmt.Fprintf(os.Stderr, "        プログレッシブJPEGで出力（低速な回線で段階的に表示される）\n")
+	fmt.Fprintf(os.Stderr, "  -jpeg-subsampling string\n")
+	fmt.Fprintf(os.Stderr, "        色差サブサンプリング: 444, 422, 420（デフォルト: 420）\n")
+	fmt.Fprintf(os.Stderr, "        444は赤い文字など色の境界を鮮明に保つ\n\n")
+
+	fmt.Fprintf(os.Stderr, "入力ファイルオプション:\n")
+	fmt.Fprintf(os.Stderr, "  <ファイル>...\n")
+	fmt.Fprintf(os.Stderr, "        -input-dirの代わりに変換するファイルを位置引数で指定\n")
+	fmt.Fprintf(os.Stderr, "  -files-from path\n")
+	fmt.Fprintf(os.Stderr, "        変換するファイルの一覧を読み込む（改行またはNUL区切り、-で標準入力）\n")
+	fmt.Fprintf(os.Stderr, "        例: find photos -name '*.jpg' -print0 | image-converter -files-from - -output-dir out\n")
+	fmt.Fprintf(os.Stderr, "  -o path\n")
+	fmt.Fprintf(os.Stderr, "        単一ファイルモードの出力パス（フォーマットは拡張子から決定、-で標準出力）\n")
+	fmt.Fprintf(os.Stderr, "  -\n")
+	fmt.Fprintf(os.Stderr, "        入力ファイルに-を指定すると標準入力から読み込み、-oがなければ標準出力に書き込む\n")
+	fmt.Fprintf(os.Stderr, "        入力フォーマットは内容から判定し、-formatがなければ同じフォーマットで出力\n\n")
+
+	fmt.Fprintf(os.Stderr, "設定ファイルオプション:\n")
+	fmt.Fprintf(os.Stderr, "  -config path\n")
+	fmt.Fprintf(os.Stderr, "        設定ファイル（.yaml, .yml, .toml, .json）を読み込む。キー名はフラグ名と同じ\n")
+	fmt.Fprintf(os.Stderr, "        コマンドラインのフラグは設定ファイルの値より優先されます\n")
+	fmt.Fprintf(os.Stderr, "        ファイルごとの規則（rules）は設定ファイルでのみ指定できます\n")
+	fmt.Fprintf(os.Stderr, "  -profile name\n")
+	fmt.Fprintf(os.Stderr, "        設定ファイルのprofilesセクションから使用するプロファイル\n\n")
+
+	fmt.Fprintf(os.Stderr, "バリエーションオプション:\n")
+	fmt.Fprintf(os.Stderr, "  -variant spec\n")
+	fmt.Fprintf(os.Stderr, "        1回の読み込みで複数の出力を生成（複数指定可）\n")
+	fmt.Fprintf(os.Stderr, "        キー: w, h, scale, format, q, suffix（例: w=1280,format=webp,suffix=-lg）\n")
+	fmt.Fprintf(os.Stderr, "        suffixを省略するとサイズから生成（例: -320w）\n\n")
+
+	fmt.Fprintf(os.Stderr, "マニフェストオプション:\n")
+	fmt.Fprintf(os.Stderr, "  -manifest string\n")
+	fmt.Fprintf(os.Stderr, "        入力ファイルごとの出力（パス、幅、高さ、フォーマット、サイズ、SHA-256）をJSONで出力\n")
+	fmt.Fprintf(os.Stderr, "  -manifest-html string\n")
+	fmt.Fprintf(os.Stderr, "        <picture>/srcsetのHTMLスニペットを出力\n")
+	fmt.Fprintf(os.Stderr, "        パスは出力するファイルのディレクトリからの相対パスになります\n\n")
+
+	fmt.Fprintf(os.Stderr, "サイズ上限オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -max-bytes size\n")
+	fmt.Fprintf(os.Stderr, "        出力ファイルサイズの上限（例: 200k, 1.5m）\n")
+	fmt.Fprintf(os.Stderr, "        JPEG/WebPは品質、GIF/PNGは色数を二分探索して上限内で最高の品質を選択\n")
+	fmt.Fprintf(os.Stderr, "  -max-bytes-downscale\n")
+	fmt.Fprintf(os.Stderr, "        最低品質でも上限を超える場合、画像をさらに縮小する\n\n")
+
+	fmt.Fprintf(os.Stderr, "知覚品質オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -target-ssim float\n")
+	fmt.Fprintf(os.Stderr, "        目標とするSSIM（0-1、例: 0.98）。候補をデコードして元画像と比較し、\n")
+	fmt.Fprintf(os.Stderr, "        目標を満たす最小の出力を選択（-max-bytesとは同時に使用できません）\n\n")
+
+	fmt.Fprintf(os.Stderr, "キャッシュオプション:\n")
+	fmt.Fprintf(os.Stderr, "  -cache-dir path\n")
+	fmt.Fprintf(os.Stderr, "        変換結果を入力内容のハッシュと変換設定をキーとして保存し、同じ変換では読み込みと変換を省略\n")
+	fmt.Fprintf(os.Stderr, "  -cache-max-bytes size\n")
+	fmt.Fprintf(os.Stderr, "        キャッシュの合計サイズの上限（デフォルト: 1g、0で無制限）。超えた場合は最後に使用した時刻が古いものから削除\n\n")
+
+	fmt.Fprintf(os.Stderr, "パレットオプション（GIF出力、-png-palette指定時のPNG出力）:\n")
+	fmt.Fprintf(os.Stderr, "  -colors int\n")
+	fmt.Fprintf(os.Stderr, "        最大色数（2-256）（デフォルト: 256）。透過がある場合は1色を透過色に使用\n")
+	fmt.Fprintf(os.Stderr, "  -dither string\n")
+	fmt.Fprintf(os.Stderr, "        ディザリング方式: none, floyd-steinberg, ordered（デフォルト: floyd-steinberg）\n")
+	fmt.Fprintf(os.Stderr, "  -png-palette\n")
+	fmt.Fprintf(os.Stderr, "        PNGを半透明対応の8ビットパレット形式で出力\n")
+	fmt.Fprintf(os.Stderr, "  -png-max-error float\n")
+	fmt.Fprintf(os.Stderr, "        パレットPNGの許容誤差（RMSE、0-255）。超えた場合はフルカラーで出力（デフォルト: 8、0で無制限）\n\n")
+
+	fmt.Fprintf(os.Stderr, "監視オプション（-input-dirを指定した場合）:\n")
+	fmt.Fprintf(os.Stderr, "  -watch\n")
+	fmt.Fprintf(os.Stderr, "        初回の変換後も入力ディレクトリを監視し、追加・変更・名前を変更された画像を変換（Ctrl+Cで終了）\n")
+	fmt.Fprintf(os.Stderr, "        書き込み途中のファイルは、サイズと更新時刻が-watch-intervalの間変わらなくなるまで待つ\n")
+	fmt.Fprintf(os.Stderr, "  -watch-delete\n")
+	fmt.Fprintf(os.Stderr, "        入力が削除された場合、その入力から生成した出力を削除する\n")
+	fmt.Fprintf(os.Stderr, "  -watch-interval duration\n")
+	fmt.Fprintf(os.Stderr, "        書き込みの完了を判定する間隔（デフォルト: 1s）\n\n")
+
+	fmt.Fprintf(os.Stderr, "同期オプション（-input-dirを指定した場合）:\n")
+	fmt.Fprintf(os.Stderr, "  -sync\n")
+	fmt.Fprintf(os.Stderr, "        変換後、入力が削除された（またはフォーマットの変更で不要になった）出力を削除\n")
+	fmt.Fprintf(os.Stderr, "        削除するのは出力ディレクトリの.image-converter-sync.jsonに記録された、このツールが生成した出力のみ\n\n")
+
+	fmt.Fprintf(os.Stderr, "変換計画オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -dry-run\n")
+	fmt.Fprintf(os.Stderr, "        画像を読み込まずにヘッダーのみから、入力ごとの出力パス・フォーマット・サイズを表示\n")
+	fmt.Fprintf(os.Stderr, "        出力パスの衝突（複数の入力が同じ出力に書き込む、または入力を上書きする）を検出した場合は終了コード1\n")
+	fmt.Fprintf(os.Stderr, "        画像の保存、出力ディレクトリ・キャッシュ・マニフェストの作成は行わない\n")
+	fmt.Fprintf(os.Stderr, "        -syncと併用した場合、削除する予定の出力も表示（削除は行わない）\n")
+	fmt.Fprintf(os.Stderr, "  -plan-format string\n")
+	fmt.Fprintf(os.Stderr, "        変換計画の表示形式: table, json（デフォルト: table）\n\n")
+
+	fmt.Fprintf(os.Stderr, "アニメーションオプション:\n")
+	fmt.Fprintf(os.Stderr, "  -first-frame-only\n")
+	fmt.Fprintf(os.Stderr, "        アニメーションGIFの最初のフレームのみを出力\n")
+	fmt.Fprintf(os.Stderr, "        指定しない場合、GIF/WebP出力ではフレーム・表示時間・ループ回数を保持\n\n")
 	
+	fmt.Fprintf(os.Stderr, "環境変数:\n")
+	fmt.Fprintf(os.Stderr, "  すべてのオプションは環境変数でも指定できます（優先順位: フラグ > 環境変数 > 設定ファイル > 既定値）\n")
+	fmt.Fprintf(os.Stderr, "  -variantは;区切りで複数指定できます\n")
+	for _, name := range flagNames() {
+		fmt.Fprintf(os.Stderr, "  %-40s -%s\n", EnvVarName(name), name)
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+
 	fmt.Fprintf(os.Stderr, "サポートされているフォーマット:\n")
 	fmt.Fprintf(os.Stderr, "  入力: JPEG, PNG, WebP, GIF, BMP\n")
 	fmt.Fprintf(os.Stderr, "  出力: JPEG, PNG, WebP, GIF, BMP\n\n")
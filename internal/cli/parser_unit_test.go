@@ -2,6 +2,7 @@ package cli
 
 import (
 	"testing"
+	"time"
 
 	"image-converter/internal/types"
 )
@@ -139,3 +140,295 @@ func TestValidateConfig_JPEGQualityBoundary(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateConfig_Colors(t *testing.T) {
+	tests := []struct {
+		colors  int
+		wantErr bool
+	}{
+		{0, false},
+		{2, false},
+		{256, false},
+		{1, true},
+		{257, true},
+	}
+
+	for _, tt := range tests {
+		config := &types.Config{
+			InputDir:    "/input",
+			OutputDir:   "/output",
+			JPEGQuality: 85,
+			Colors:      tt.colors,
+		}
+		err := ValidateConfig(config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("色数 %d: エラー期待=%v, 実際=%v", tt.colors, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateConfig_Dither(t *testing.T) {
+	config := &types.Config{
+		InputDir:    "/input",
+		OutputDir:   "/output",
+		JPEGQuality: 85,
+		Dither:      "Ordered",
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("有効なディザリング方式でエラーが返された: %v", err)
+	}
+	if config.Dither != "ordered" {
+		t.Errorf("ディザリング方式が正規化されていない: %s", config.Dither)
+	}
+
+	config.Dither = "random"
+	if err := ValidateConfig(config); err == nil {
+		t.Error("無効なディザリング方式の場合、エラーが返されるべき")
+	}
+}
+
+func TestValidateConfig_NegativePNGMaxError(t *testing.T) {
+	config := &types.Config{
+		InputDir:    "/input",
+		OutputDir:   "/output",
+		JPEGQuality: 85,
+		PNGMaxError: -1,
+	}
+	if err := ValidateConfig(config); err == nil {
+		t.Error("負の許容誤差の場合、エラーが返されるべき")
+	}
+}
+
+func TestValidateConfig_JPEGSubsampling(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"444", "444", false},
+		{"4:2:2", "422", false},
+		{"420", "420", false},
+		{"411", "", true},
+	}
+
+	for _, tt := range tests {
+		config := &types.Config{
+			InputDir:        "/input",
+			OutputDir:       "/output",
+			JPEGQuality:     85,
+			JPEGSubsampling: tt.input,
+		}
+		err := ValidateConfig(config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("サブサンプリング %s: エラー期待=%v, 実際=%v", tt.input, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && config.JPEGSubsampling != tt.expected {
+			t.Errorf("サブサンプリング %s: 期待=%s, 実際=%s", tt.input, tt.expected, config.JPEGSubsampling)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1000", 1000, false},
+		{"200k", 200 * 1024, false},
+		{"200KB", 200 * 1024, false},
+		{"1.5m", 1536 * 1024, false},
+		{"1g", 1 << 30, false},
+		{"abc", 0, true},
+		{"-5k", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q): エラー期待=%v, 実際=%v", tt.input, tt.wantErr, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseByteSize(%q) = %d, 期待=%d", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateConfig_TargetSSIM(t *testing.T) {
+	config := &types.Config{
+		InputDir:    "/input",
+		OutputDir:   "/output",
+		JPEGQuality: 85,
+		TargetSSIM:  1.5,
+	}
+	if err := ValidateConfig(config); err == nil {
+		t.Error("範囲外の目標SSIMの場合、エラーが返されるべき")
+	}
+
+	config.TargetSSIM = 0.98
+	config.MaxBytes = 1000
+	if err := ValidateConfig(config); err == nil {
+		t.Error("サイズ上限と目標SSIMを同時に指定した場合、エラーが返されるべき")
+	}
+}
+
+func TestParseVariant(t *testing.T) {
+	variant, err := ParseVariant("w=320, format=webp, q=70, suffix=-sm")
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	expected := types.Variant{
+		Suffix:  "-sm",
+		Resize:  types.ResizeSpec{Width: 320},
+		Format:  "webp",
+		Quality: 70,
+	}
+	if variant != expected {
+		t.Errorf("ParseVariant() = %+v, 期待=%+v", variant, expected)
+	}
+
+	for _, spec := range []string{"w", "w=abc", "unknown=1"} {
+		if _, err := ParseVariant(spec); err == nil {
+			t.Errorf("ParseVariant(%q) はエラーを返すべき", spec)
+		}
+	}
+}
+
+func TestValidateConfig_Variants(t *testing.T) {
+	newConfig := func(variants ...types.Variant) *types.Config {
+		return &types.Config{
+			InputDir:    "/input",
+			OutputDir:   "/output",
+			JPEGQuality: 85,
+			Variants:    variants,
+		}
+	}
+
+	// 接尾辞の自動生成とフォーマットの正規化
+	config := newConfig(
+		types.Variant{Resize: types.ResizeSpec{Width: 320}, Format: "JPG"},
+		types.Variant{Resize: types.ResizeSpec{Width: 640, Height: 480}},
+		types.Variant{Resize: types.ResizeSpec{Scale: 2}},
+	)
+	if err := ValidateConfig(config); err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.Variants[0].Suffix != "-320w" || config.Variants[0].Format != "jpeg" {
+		t.Errorf("バリエーション1が正規化されていない: %+v", config.Variants[0])
+	}
+	if config.Variants[1].Suffix != "-640x480" {
+		t.Errorf("接尾辞=%q, 期待=-640x480", config.Variants[1].Suffix)
+	}
+	if config.Variants[2].Suffix != "-2x" {
+		t.Errorf("接尾辞=%q, 期待=-2x", config.Variants[2].Suffix)
+	}
+
+	invalid := map[string]*types.Config{
+		"倍率とピクセルの同時指定": newConfig(types.Variant{Resize: types.ResizeSpec{Scale: 0.5, Width: 100}}),
+		"負のサイズ":        newConfig(types.Variant{Resize: types.ResizeSpec{Width: -1}}),
+		"不正なフォーマット":    newConfig(types.Variant{Format: "tiff"}),
+		"範囲外の品質":       newConfig(types.Variant{Quality: 101}),
+		"出力ファイル名の重複":   newConfig(types.Variant{Resize: types.ResizeSpec{Width: 100}}, types.Variant{Resize: types.ResizeSpec{Width: 100}, Quality: 50}),
+
+		"入力と同じフォーマットとの重複": newConfig(types.Variant{Suffix: "-a"}, types.Variant{Suffix: "-a", Format: "png"}),
+	}
+	for name, config := range invalid {
+		if err := ValidateConfig(config); err == nil {
+			t.Errorf("%s の場合、エラーが返されるべき", name)
+		}
+	}
+
+	// 基本設定のフォーマットが決まっていれば、同じ接尾辞でもフォーマットが異なる出力は重複しない
+	config = newConfig(types.Variant{Suffix: "-a"}, types.Variant{Suffix: "-a", Format: "png"})
+	config.Format = "jpeg"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("予期しないエラー: %v", err)
+	}
+}
+
+func TestValidateConfig_Fit(t *testing.T) {
+	config := &types.Config{
+		InputDir:    "/input",
+		OutputDir:   "/output",
+		JPEGQuality: 85,
+		Width:       100,
+		Height:      100,
+		Fit:         "Cover",
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("有効な合わせ方でエラーが返された: %v", err)
+	}
+	if config.Fit != "cover" {
+		t.Errorf("合わせ方が正規化されていない: %s", config.Fit)
+	}
+
+	config.Fit = "stretch"
+	if err := ValidateConfig(config); err == nil {
+		t.Error("無効な合わせ方の場合、エラーが返されるべき")
+	}
+}
+
+func TestValidateOptions_WithoutDirectories(t *testing.T) {
+	// 入出力の指定がなくても変換設定のみを検証できる
+	config := DefaultConfig()
+	config.Width = 400
+	config.Format = "JPG"
+	if err := ValidateOptions(&config); err != nil {
+		t.Errorf("予期しないエラー: %v", err)
+	}
+	if config.Format != "jpeg" {
+		t.Errorf("フォーマット=%s, 期待=jpeg", config.Format)
+	}
+
+	config.Scale = 0.5
+	if err := ValidateOptions(&config); err == nil {
+		t.Error("倍率指定とピクセル指定を同時に使用した場合、エラーが返されるべき")
+	}
+}
+
+func TestValidateConfig_Watch(t *testing.T) {
+	valid := types.Config{InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, Watch: true, WatchDelete: true, WatchInterval: time.Second}
+	if err := ValidateConfig(&valid); err != nil {
+		t.Errorf("予期しないエラー: %v", err)
+	}
+
+	tests := map[string]types.Config{
+		"-watchなしの-watch-delete": {InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, WatchDelete: true, WatchInterval: time.Second},
+		"入力ファイルの監視":              {Files: []string{"a.png"}, OutputDir: "/out", JPEGQuality: 85, Watch: true, WatchInterval: time.Second},
+		"0の間隔":                   {InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, Watch: true},
+		"入力と同じ出力先":               {InputDir: "/in", OutputDir: "/in/", JPEGQuality: 85, Watch: true, WatchInterval: time.Second},
+		"相対パスで同じ出力先":             {InputDir: "photos", OutputDir: "./photos", JPEGQuality: 85, Watch: true, WatchInterval: time.Second},
+	}
+	for name, config := range tests {
+		if err := ValidateConfig(&config); err == nil {
+			t.Errorf("%s: エラーが返されるべき", name)
+		}
+	}
+}
+
+func TestValidateConfig_SyncAndDryRun(t *testing.T) {
+	valid := []types.Config{
+		{InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, Sync: true, DryRun: true},
+		{InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, DryRun: true, PlanFormat: "json"},
+		{Files: []string{"a.png"}, OutputDir: "/out", JPEGQuality: 85, DryRun: true},
+	}
+	for _, config := range valid {
+		if err := ValidateConfig(&config); err != nil {
+			t.Errorf("予期しないエラー: %v", err)
+		}
+	}
+
+	tests := map[string]types.Config{
+		"入力ファイルの同期":          {Files: []string{"a.png"}, OutputDir: "/out", JPEGQuality: 85, Sync: true},
+		"単一ファイルモードの-dry-run": {Files: []string{"a.png"}, OutputPath: "b.png", JPEGQuality: 85, DryRun: true},
+		"-watchと-dry-run":    {InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, DryRun: true, Watch: true, WatchInterval: time.Second},
+		"無効な表示形式":            {InputDir: "/in", OutputDir: "/out", JPEGQuality: 85, DryRun: true, PlanFormat: "xml"},
+	}
+	for name, config := range tests {
+		if err := ValidateConfig(&config); err == nil {
+			t.Errorf("%s: エラーが返されるべき", name)
+		}
+	}
+}
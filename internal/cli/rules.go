@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+
+	"image-converter/internal/types"
+)
+
+// validateRules は規則の妥当性を検証し、名前・フォーマット等を正規化します
+// 名前が省略された規則には"rule1"のような連番の名前を付けます
+func validateRules(config *types.Config) error {
+	names := make(map[string]bool)
+
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule%d", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("規則の名前が重複しています: %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if err := validateRule(rule); err != nil {
+			return fmt.Errorf("規則%s: %w", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateRule は1つの規則を検証します
+func validateRule(rule *types.Rule) error {
+	match := &rule.Match
+
+	// パターンの構文チェック
+	if match.Glob != "" {
+		if _, err := path.Match(match.Glob, ""); err != nil {
+			return fmt.Errorf("無効なパターン: %s", match.Glob)
+		}
+	}
+
+	for i, format := range match.Formats {
+		normalized, err := normalizeFormat(format)
+		if err != nil {
+			return err
+		}
+		match.Formats[i] = normalized
+	}
+
+	if match.MinWidth < 0 || match.MaxWidth < 0 || match.MinHeight < 0 || match.MaxHeight < 0 {
+		return fmt.Errorf("寸法の条件は0以上である必要があります")
+	}
+	if (match.MaxWidth > 0 && match.MinWidth > match.MaxWidth) || (match.MaxHeight > 0 && match.MinHeight > match.MaxHeight) {
+		return fmt.Errorf("寸法の下限が上限を超えています")
+	}
+
+	spec := rule.Resize
+	if spec.Scale < 0 || spec.Width < 0 || spec.Height < 0 {
+		return fmt.Errorf("サイズ指定は0以上である必要があります")
+	}
+	if spec.Scale > 0 && (spec.Width > 0 || spec.Height > 0) {
+		return fmt.Errorf("倍率指定とピクセル指定を同時に使用できません")
+	}
+
+	if rule.Format != "" {
+		format, err := normalizeFormat(rule.Format)
+		if err != nil {
+			return err
+		}
+		rule.Format = format
+	}
+
+	if rule.Quality < 0 || rule.Quality > 100 {
+		return fmt.Errorf("品質は1から100の範囲で指定してください")
+	}
+	if rule.Colors != 0 && (rule.Colors < 2 || rule.Colors > 256) {
+		return fmt.Errorf("色数は2から256の範囲で指定してください")
+	}
+
+	if rule.Dither != "" {
+		dither, err := normalizeDither(rule.Dither)
+		if err != nil {
+			return err
+		}
+		rule.Dither = dither
+	}
+
+	if rule.JPEGSubsampling != "" {
+		subsampling, err := normalizeSubsampling(rule.JPEGSubsampling)
+		if err != nil {
+			return err
+		}
+		rule.JPEGSubsampling = subsampling
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+// DefaultServeConfig はserveサブコマンドの既定値を返します
+func DefaultServeConfig() types.ServeConfig {
+	return types.ServeConfig{
+		Addr:           ":8080",
+		MaxUploadBytes: 32 << 20,
+		MaxPixels:      50_000_000,
+		CacheMaxBytes:  1 << 30,
+	}
+}
+
+// ParseServeArguments はserveサブコマンドの引数を解析してServeConfigを返します
+// 設定ファイル（-configのserveセクション）と環境変数（IMAGE_CONVERTER_ADDRなど）の値を順に適用し、最後にフラグの値を適用します
+func ParseServeArguments(args []string) (*types.ServeConfig, error) {
+	return parseServeArguments(args, os.LookupEnv)
+}
+
+// parseServeArguments はlookupEnvで環境変数を参照してParseServeArgumentsを実行します
+func parseServeArguments(args []string, lookupEnv func(string) (string, bool)) (*types.ServeConfig, error) {
+	config, rest, err := parseServeLayers("serve", args, lookupEnv, PrintServeUsage, func(fs *flag.FlagSet, config *types.ServeConfig, defaults types.ServeConfig) {
+		fs.StringVar(&config.Addr, "addr", defaults.Addr, "待ち受けアドレス")
+		fs.StringVar(&config.Root, "root", defaults.Root, "GET /img/{path} で配信する画像のルートディレクトリ")
+		config.MaxUploadBytes = defaults.MaxUploadBytes
+		fs.Var((*byteSizeValue)(&config.MaxUploadBytes), "max-upload", "POST /convert で受け付ける本文の最大サイズ（例: 32m）")
+		fs.Int64Var(&config.MaxPixels, "max-pixels", defaults.MaxPixels, "デコードする入力画像の最大画素数（幅×高さ）")
+		fs.StringVar(&config.CacheDir, "cache-dir", defaults.CacheDir, "変換結果のキャッシュディレクトリ")
+		config.CacheMaxBytes = defaults.CacheMaxBytes
+		fs.Var((*byteSizeValue)(&config.CacheMaxBytes), "cache-max-bytes", "キャッシュの合計サイズの上限（例: 512m、0で無制限）")
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("serveサブコマンドに不明な引数があります: %s", strings.Join(rest, " "))
+	}
+
+	if err := ValidateServeConfig(config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// ParseSignArguments はsignサブコマンドの引数を解析してSignConfigを返します
+// 秘密鍵と許可するサイズはserveサブコマンドと同じ設定ファイル・環境変数からも読み込みます
+func ParseSignArguments(args []string) (*types.SignConfig, error) {
+	return parseSignArguments(args, os.LookupEnv)
+}
+
+// parseSignArguments はlookupEnvで環境変数を参照してParseSignArgumentsを実行します
+func parseSignArguments(args []string, lookupEnv func(string) (string, bool)) (*types.SignConfig, error) {
+	var ttl time.Duration
+	serve, urls, err := parseServeLayers("sign", args, lookupEnv, PrintSignUsage, func(fs *flag.FlagSet, config *types.ServeConfig, defaults types.ServeConfig) {
+		fs.DurationVar(&ttl, "ttl", 0, "署名の有効期間（例: 24h、0で無期限）")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if serve.Secret == "" {
+		return nil, fmt.Errorf("秘密鍵が指定されていません（-secret、%s、または設定ファイルのserve.secret）", EnvVarName("secret"))
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("署名するURLが指定されていません")
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("有効期間は0以上である必要があります")
+	}
+
+	return &types.SignConfig{Serve: *serve, URLs: urls, TTL: ttl}, nil
+}
+
+// parseServeLayers はserve・signサブコマンドの共通フラグ（-config, -secret, -presets）とdefineで定義したフラグを解析します
+// 優先順位はフラグ > 環境変数 > 設定ファイル > 既定値で、位置引数を返します
+func parseServeLayers(name string, args []string, lookupEnv func(string) (string, bool), usage func(), define func(*flag.FlagSet, *types.ServeConfig, types.ServeConfig)) (*types.ServeConfig, []string, error) {
+	newFlagSet := func(config *types.ServeConfig, defaults types.ServeConfig, configPath *string) *flag.FlagSet {
+		*config = defaults
+		fs := flag.NewFlagSet("image-converter "+name, flag.ContinueOnError)
+		fs.Usage = usage
+		fs.StringVar(configPath, "config", "", "設定ファイルのパス（serveセクションを使用）")
+		fs.StringVar(&config.Secret, "secret", defaults.Secret, "URL署名の秘密鍵")
+		config.Presets = append([]types.SizePreset(nil), defaults.Presets...)
+		fs.Var(&presetListValue{presets: &config.Presets}, "presets", "許可するサイズ（例: 400x300,800x）")
+		define(fs, config, defaults)
+		return fs
+	}
+
+	// 1回目の解析で設定ファイルのパスを取得
+	var config types.ServeConfig
+	var configPath string
+	if _, err := parseInterspersed(newFlagSet(&config, DefaultServeConfig(), &configPath), args); err != nil {
+		return nil, nil, err
+	}
+	if configPath == "" {
+		configPath, _ = lookupEnv(EnvVarName("config"))
+	}
+
+	defaults := DefaultServeConfig()
+	if configPath != "" {
+		fileConfig, err := LoadServeConfigFile(configPath, defaults)
+		if err != nil {
+			return nil, nil, err
+		}
+		defaults = *fileConfig
+	}
+
+	// 環境変数の値を適用してからフラグを再解析する
+	fs := newFlagSet(&config, defaults, &configPath)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" {
+			return
+		}
+		envName := EnvVarName(f.Name)
+		value, ok := lookupEnv(envName)
+		if !ok || strings.TrimSpace(value) == "" {
+			return
+		}
+		if setErr := f.Value.Set(strings.TrimSpace(value)); setErr != nil {
+			err = fmt.Errorf("環境変数%sの値が不正です: %w", envName, setErr)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &config, positional, nil
+}
+
+// presetListValue はカンマ区切りのサイズ一覧を受け付けるフラグ値です
+// 最初の指定で既定値（設定ファイル・環境変数の値）を置き換えます
+type presetListValue struct {
+	presets *[]types.SizePreset
+	set     bool
+}
+
+func (v *presetListValue) String() string {
+	if v.presets == nil {
+		return ""
+	}
+	specs := make([]string, len(*v.presets))
+	for i, preset := range *v.presets {
+		specs[i] = FormatSizePreset(preset)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (v *presetListValue) Set(s string) error {
+	if !v.set {
+		*v.presets = nil
+		v.set = true
+	}
+	for _, spec := range strings.Split(s, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		preset, err := ParseSizePreset(spec)
+		if err != nil {
+			return err
+		}
+		*v.presets = append(*v.presets, preset)
+	}
+	return nil
+}
+
+// ParseSizePreset は"400x300"、"400x"（幅のみ）、"x300"（高さのみ）形式のサイズを解析します
+func ParseSizePreset(spec string) (types.SizePreset, error) {
+	width, height, ok := strings.Cut(strings.ToLower(strings.TrimSpace(spec)), "x")
+	if !ok || (width == "" && height == "") {
+		return types.SizePreset{}, fmt.Errorf("無効なサイズ: %s（例: 400x300, 400x, x300）", spec)
+	}
+
+	var preset types.SizePreset
+	var err error
+	if width != "" {
+		if preset.Width, err = strconv.Atoi(width); err != nil || preset.Width <= 0 {
+			return types.SizePreset{}, fmt.Errorf("無効なサイズ: %s（幅は正の整数）", spec)
+		}
+	}
+	if height != "" {
+		if preset.Height, err = strconv.Atoi(height); err != nil || preset.Height <= 0 {
+			return types.SizePreset{}, fmt.Errorf("無効なサイズ: %s（高さは正の整数）", spec)
+		}
+	}
+	return preset, nil
+}
+
+// FormatSizePreset はサイズをParseSizePresetで解析できる文字列に変換します
+func FormatSizePreset(preset types.SizePreset) string {
+	var b strings.Builder
+	if preset.Width > 0 {
+		b.WriteString(strconv.Itoa(preset.Width))
+	}
+	b.WriteString("x")
+	if preset.Height > 0 {
+		b.WriteString(strconv.Itoa(preset.Height))
+	}
+	return b.String()
+}
+
+// ValidateServeConfig はserveサブコマンドの設定の妥当性を検証します
+func ValidateServeConfig(config *types.ServeConfig) error {
+	if config.Addr == "" {
+		return fmt.Errorf("待ち受けアドレスが指定されていません")
+	}
+	if config.MaxUploadBytes <= 0 {
+		return fmt.Errorf("アップロードの最大サイズは0より大きい必要があります")
+	}
+	if config.MaxPixels <= 0 {
+		return fmt.Errorf("最大画素数は0より大きい必要があります")
+	}
+	if config.CacheMaxBytes < 0 {
+		return fmt.Errorf("キャッシュサイズの上限は0以上である必要があります")
+	}
+	return nil
+}
+
+// PrintServeUsage はserveサブコマンドの使用方法を表示します
+func PrintServeUsage() {
+	fmt.Fprintf(os.Stderr, "Image Converter CLI - HTTP変換サーバー\n\n")
+	fmt.Fprintf(os.Stderr, "使用方法:\n")
+	fmt.Fprintf(os.Stderr, "  image-converter serve [-addr :8080] [-root <画像ディレクトリ>] [-max-upload 32m]\n\n")
+
+	fmt.Fprintf(os.Stderr, "オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -addr string\n")
+	fmt.Fprintf(os.Stderr, "        待ち受けアドレス（デフォルト: :8080）\n")
+	fmt.Fprintf(os.Stderr, "  -root path\n")
+	fmt.Fprintf(os.Stderr, "        GET /img/{path} で配信する画像のルートディレクトリ（省略時はPOST /convertのみ）\n")
+	fmt.Fprintf(os.Stderr, "  -max-upload size\n")
+	fmt.Fprintf(os.Stderr, "        POST /convert で受け付ける本文の最大サイズ（デフォルト: 32m）\n")
+	fmt.Fprintf(os.Stderr, "  -max-pixels n\n")
+	fmt.Fprintf(os.Stderr, "        デコードする入力画像の最大画素数（幅×高さ、アニメーションGIFは×フレーム数）。超える画像はデコードせずに拒否（413、デフォルト: 50000000）\n")
+	fmt.Fprintf(os.Stderr, "  -cache-dir path\n")
+	fmt.Fprintf(os.Stderr, "        変換結果のキャッシュディレクトリ。同じ画像・パラメーターの要求はキャッシュから返す（X-Cache: HIT）\n")
+	fmt.Fprintf(os.Stderr, "  -cache-max-bytes size\n")
+	fmt.Fprintf(os.Stderr, "        キャッシュの合計サイズの上限（デフォルト: 1g、0で無制限）\n")
+	printServeSecurityOptions()
+
+	fmt.Fprintf(os.Stderr, "エンドポイント:\n")
+	fmt.Fprintf(os.Stderr, "  GET  /img/{path}?w=400&h=300&fit=cover&format=webp&q=80\n")
+	fmt.Fprintf(os.Stderr, "        ルートディレクトリの画像を変換して返す\n")
+	fmt.Fprintf(os.Stderr, "  POST /convert?w=400&format=webp\n")
+	fmt.Fprintf(os.Stderr, "        リクエスト本文の画像を変換して返す\n\n")
+
+	fmt.Fprintf(os.Stderr, "クエリパラメーター:\n")
+	fmt.Fprintf(os.Stderr, "  w, h     出力の幅・高さ（ピクセル、縦横比を維持）\n")
+	fmt.Fprintf(os.Stderr, "  scale    倍率（w, hとは同時に使用できません）\n")
+	fmt.Fprintf(os.Stderr, "  fit      w, hの両方を指定した場合の合わせ方: contain, cover\n")
+	fmt.Fprintf(os.Stderr, "  format   出力フォーマット: jpeg, png, webp, gif, bmp\n")
+	fmt.Fprintf(os.Stderr, "           省略時はAcceptヘッダーから決定（image/webpを受け付ける場合はWebP）\n")
+	fmt.Fprintf(os.Stderr, "  q        JPEG/WebP品質（1-100、デフォルト: 85）\n")
+	fmt.Fprintf(os.Stderr, "  exp, sig 有効期限（UNIX時間）と署名（signサブコマンドで生成）\n\n")
+
+	fmt.Fprintf(os.Stderr, "環境変数:\n")
+	for _, name := range []string{"addr", "root", "max-upload", "max-pixels", "cache-dir", "cache-max-bytes", "secret", "presets", "config"} {
+		fmt.Fprintf(os.Stderr, "  %-40s -%s\n", EnvVarName(name), name)
+	}
+}
+
+// PrintSignUsage はsignサブコマンドの使用方法を表示します
+func PrintSignUsage() {
+	fmt.Fprintf(os.Stderr, "Image Converter CLI - 変換サーバーのURL署名\n\n")
+	fmt.Fprintf(os.Stderr, "使用方法:\n")
+	fmt.Fprintf(os.Stderr, "  image-converter sign -secret <秘密鍵> [-ttl 24h] <URL>...\n")
+	fmt.Fprintf(os.Stderr, "  例: image-converter sign -secret s3cr3t '/img/photos/cat.jpg?w=400&h=300&fit=cover'\n\n")
+
+	fmt.Fprintf(os.Stderr, "オプション:\n")
+	fmt.Fprintf(os.Stderr, "  -ttl duration\n")
+	fmt.Fprintf(os.Stderr, "        署名の有効期間（例: 24h）。指定するとexpパラメーターを付加（デフォルト: 無期限）\n")
+	printServeSecurityOptions()
+
+	fmt.Fprintf(os.Stderr, "パラメーターはserveサブコマンドと同じ規則で検証され、署名付きのURLを1行ずつ出力します\n")
+}
+
+// printServeSecurityOptions はserve・signサブコマンド共通のオプションを表示します
+func printServeSecurityOptions() {
+	fmt.Fprintf(os.Stderr, "  -secret string\n")
+	fmt.Fprintf(os.Stderr, "        URL署名（HMAC-SHA256）の秘密鍵。指定すると署名のないリクエストを拒否（403）\n")
+	fmt.Fprintf(os.Stderr, "  -presets list\n")
+	fmt.Fprintf(os.Stderr, "        許可するサイズのカンマ区切り一覧（例: 400x300,800x,x200）。一致しないw, hを拒否（403）\n")
+	fmt.Fprintf(os.Stderr, "  -config path\n")
+	fmt.Fprintf(os.Stderr, "        設定ファイルのserveセクション（addr, root, max-upload, max-pixels, cache-dir, cache-max-bytes, secret, presets）を読み込む\n\n")
+}
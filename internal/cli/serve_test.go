@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+func TestParseServeArguments(t *testing.T) {
+	config, err := parseServeArguments([]string{"-root", "./images", "-max-upload", "10m", "-max-pixels", "1000000"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.Addr != ":8080" || config.Root != "./images" || config.MaxUploadBytes != 10*1024*1024 || config.MaxPixels != 1000000 {
+		t.Errorf("設定=%+v", config)
+	}
+}
+
+func TestParseServeArguments_Environment(t *testing.T) {
+	env := envLookup(map[string]string{
+		"IMAGE_CONVERTER_ADDR": ":9000",
+		"IMAGE_CONVERTER_ROOT": "/srv/images",
+	})
+
+	config, err := parseServeArguments([]string{"-root", "./images"}, env)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	// フラグが環境変数より優先される
+	if config.Addr != ":9000" || config.Root != "./images" {
+		t.Errorf("設定=%+v", config)
+	}
+}
+
+func TestParseServeArguments_Errors(t *testing.T) {
+	tests := map[string][]string{
+		"空のアドレス":  {"-addr", ""},
+		"0バイトの上限": {"-max-upload", "0"},
+		"不正なサイズ":  {"-max-upload", "abc"},
+		"0の最大画素数": {"-max-pixels", "0"},
+		"余分な位置引数": {"extra"},
+	}
+	for name, args := range tests {
+		if _, err := parseServeArguments(args, envLookup(nil)); err == nil {
+			t.Errorf("%s: エラーが返されるべき", name)
+		}
+	}
+}
+
+func TestParseSizePreset(t *testing.T) {
+	valid := map[string]types.SizePreset{
+		"400x300": {Width: 400, Height: 300},
+		"400x":    {Width: 400},
+		"x300":    {Height: 300},
+		" 64X64 ": {Width: 64, Height: 64},
+	}
+	for spec, expected := range valid {
+		preset, err := ParseSizePreset(spec)
+		if err != nil {
+			t.Errorf("%q: 予期しないエラー: %v", spec, err)
+			continue
+		}
+		if preset != expected {
+			t.Errorf("%q: %+v, 期待=%+v", spec, preset, expected)
+		}
+		if FormatSizePreset(preset) != strings.ToLower(strings.TrimSpace(spec)) {
+			t.Errorf("%q: FormatSizePreset=%s", spec, FormatSizePreset(preset))
+		}
+	}
+
+	for _, spec := range []string{"", "x", "400", "0x300", "-1x", "axb"} {
+		if _, err := ParseSizePreset(spec); err == nil {
+			t.Errorf("%q: エラーが返されるべき", spec)
+		}
+	}
+}
+
+func TestParseServeArguments_ConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "format: webp\nserve:\n  addr: \":9000\"\n  secret: from-file\n  presets: [\"400x300\", \"800x\"]\n  max-upload: 1m\n  max-pixels: 2000000\n")
+
+	env := envLookup(map[string]string{"IMAGE_CONVERTER_SECRET": "from-env"})
+	config, err := parseServeArguments([]string{"-config", path, "-presets", "64x64"}, env)
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	// 優先順位: フラグ > 環境変数 > 設定ファイル
+	if config.Addr != ":9000" || config.MaxUploadBytes != 1024*1024 || config.MaxPixels != 2000000 {
+		t.Errorf("設定ファイルの値が適用されていない: %+v", config)
+	}
+	if config.Secret != "from-env" {
+		t.Errorf("秘密鍵=%s, 期待=from-env", config.Secret)
+	}
+	if len(config.Presets) != 1 || config.Presets[0] != (types.SizePreset{Width: 64, Height: 64}) {
+		t.Errorf("サイズ=%+v", config.Presets)
+	}
+
+	// 通常の変換では設定ファイルのserveセクションは無視される
+	if _, err := LoadConfigFile(path, "", DefaultConfig()); err != nil {
+		t.Errorf("予期しないエラー: %v", err)
+	}
+
+	invalid := writeConfigFile(t, "invalid.yaml", "serve:\n  presets: [\"big\"]\n")
+	if _, err := parseServeArguments([]string{"-config", invalid}, envLookup(nil)); err == nil {
+		t.Error("無効なサイズの場合、エラーが返されるべき")
+	}
+}
+
+func TestParseSignArguments(t *testing.T) {
+	config, err := parseSignArguments([]string{"-secret", "s3cr3t", "-ttl", "1h", "/img/a.png?w=400", "-presets", "400x", "/img/b.png"}, envLookup(nil))
+	if err != nil {
+		t.Fatalf("予期しないエラー: %v", err)
+	}
+	if config.Serve.Secret != "s3cr3t" || config.TTL != time.Hour || len(config.URLs) != 2 || len(config.Serve.Presets) != 1 {
+		t.Errorf("設定=%+v", config)
+	}
+
+	tests := map[string][]string{
+		"秘密鍵なし":  {"/img/a.png"},
+		"URLなし":  {"-secret", "s3cr3t"},
+		"負の有効期間": {"-secret", "s3cr3t", "-ttl", "-1h", "/img/a.png"},
+	}
+	for name, args := range tests {
+		if _, err := parseSignArguments(args, envLookup(nil)); err == nil {
+			t.Errorf("%s: エラーが返されるべき", name)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package converter
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+
+	"image-converter/internal/types"
+)
+
+// AnimatedImage は複数フレームからなるアニメーション画像を表します
+// 各フレームは廃棄方法（disposal）を適用済みのキャンバス全体の画像です
+type AnimatedImage struct {
+	Frames    []image.Image // 合成済みのフレーム（すべて同じサイズ）
+	Delays    []int         // 各フレームの表示時間（1/100秒単位）
+	LoopCount int           // ループ回数（0は無限ループ、-1はループなし）
+}
+
+// FrameCount はフレーム数を返します
+func (a *AnimatedImage) FrameCount() int {
+	return len(a.Frames)
+}
+
+// IsAnimated は2フレーム以上を持つかどうかを返します
+func (a *AnimatedImage) IsAnimated() bool {
+	return len(a.Frames) > 1
+}
+
+// Bounds は最初のフレームの境界を返します
+func (a *AnimatedImage) Bounds() image.Rectangle {
+	if len(a.Frames) == 0 {
+		return image.Rectangle{}
+	}
+	return a.Frames[0].Bounds()
+}
+
+// CountGIFFrames はGIFのブロックをたどり、画素データをデコードせずにフレーム数を数えます
+// 壊れたデータの場合は、それまでに数えたフレーム数とエラーを返します
+func CountGIFFrames(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+
+	// ヘッダー（6バイト）と論理画面記述子（7バイト）
+	var header [13]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return 0, fmt.Errorf("failed to read GIF header: %w", err)
+	}
+	if err := skipColorTable(br, header[10]); err != nil {
+		return 0, err
+	}
+
+	frames := 0
+	for {
+		introducer, err := br.ReadByte()
+		if err != nil {
+			return frames, fmt.Errorf("failed to read GIF block: %w", err)
+		}
+		switch introducer {
+		case 0x21: // 拡張ブロック: ラベルとサブブロック
+			if _, err := br.ReadByte(); err != nil {
+				return frames, fmt.Errorf("failed to read GIF extension: %w", err)
+			}
+			if err := skipSubBlocks(br); err != nil {
+				return frames, err
+			}
+		case 0x2c: // イメージ記述子: 位置・サイズ（8バイト）、フラグ、LZWの最小符号長、画素データ
+			var descriptor [9]byte
+			if _, err := io.ReadFull(br, descriptor[:]); err != nil {
+				return frames, fmt.Errorf("failed to read GIF image descriptor: %w", err)
+			}
+			if err := skipColorTable(br, descriptor[8]); err != nil {
+				return frames, err
+			}
+			if _, err := br.ReadByte(); err != nil {
+				return frames, fmt.Errorf("failed to read GIF image data: %w", err)
+			}
+			if err := skipSubBlocks(br); err != nil {
+				return frames, err
+			}
+			frames++
+		case 0x3b: // トレーラー
+			return frames, nil
+		default:
+			return frames, fmt.Errorf("unknown GIF block: 0x%02x", introducer)
+		}
+	}
+}
+
+// skipColorTable はフラグがカラーテーブルの存在を示す場合、その分を読み飛ばします
+func skipColorTable(br *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	if _, err := br.Discard(3 << (flags&0x07 + 1)); err != nil {
+		return fmt.Errorf("failed to read GIF color table: %w", err)
+	}
+	return nil
+}
+
+// skipSubBlocks はサイズ0のブロックで終わるサブブロックの並びを読み飛ばします
+func skipSubBlocks(br *bufio.Reader) error {
+	for {
+		size, err := br.ReadByte()
+		if err != nil {
+			return fmt.Errorf("failed to read GIF sub-block: %w", err)
+		}
+		if size == 0 {
+			return nil
+		}
+		if _, err := br.Discard(int(size)); err != nil {
+			return fmt.Errorf("failed to read GIF sub-block: %w", err)
+		}
+	}
+}
+
+// compositeGIF はGIFの各フレームを廃棄方法に従ってキャンバスに合成します
+// GIFのフレームは前のフレームとの差分であることが多いため、
+// 合成しないと単独のフレームとしては正しく表示できません
+func compositeGIF(g *gif.GIF) *AnimatedImage {
+	width, height := g.Config.Width, g.Config.Height
+	if width == 0 || height == 0 {
+		// 論理画面サイズが無い場合はフレームの外接矩形を使用
+		var rect image.Rectangle
+		for _, frame := range g.Image {
+			rect = rect.Union(frame.Bounds())
+		}
+		width, height = rect.Max.X, rect.Max.Y
+	}
+
+	canvasRect := image.Rect(0, 0, width, height)
+	canvas := image.NewRGBA(canvasRect)
+
+	anim := &AnimatedImage{
+		Frames:    make([]image.Image, 0, len(g.Image)),
+		Delays:    make([]int, 0, len(g.Image)),
+		LoopCount: g.LoopCount,
+	}
+
+	for i, frame := range g.Image {
+		disposal := byte(gif.DisposalNone)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+
+		// DisposalPreviousの場合は描画前のキャンバスを退避
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		// 透過色を考慮してフレームを重ねる
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		anim.Frames = append(anim.Frames, cloneRGBA(canvas))
+		delay := 0
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		anim.Delays = append(anim.Delays, delay)
+
+		// 次のフレームのために廃棄方法を適用
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return anim
+}
+
+// cloneRGBA はRGBA画像の複製を作成します
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+// ResizeAnimation はアニメーションの全フレームを同じリサイズ仕様でリサイズします
+func (rc *ResizeCalculator) ResizeAnimation(anim *AnimatedImage, spec types.ResizeSpec) *AnimatedImage {
+	resized := &AnimatedImage{
+		Frames:    make([]image.Image, len(anim.Frames)),
+		Delays:    append([]int(nil), anim.Delays...),
+		LoopCount: anim.LoopCount,
+	}
+
+	for i, frame := range anim.Frames {
+		resized.Frames[i] = rc.ResizeImage(frame, spec)
+	}
+
+	return resized
+}
+
+// SupportsAnimation は出力フォーマットがアニメーションに対応しているかを返します
+func SupportsAnimation(format types.ImageFormat) bool {
+	return format == types.FormatGIF || format == types.FormatWebP
+}
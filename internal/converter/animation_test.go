@@ -0,0 +1,233 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// createTestGIF はテスト用のアニメーションGIFを生成します
+// 各フレームは単色で塗りつぶされ、左上の一部分のみを更新する差分フレームを含みます
+func createTestGIF(width, height int, colors []color.RGBA, disposal byte) *gif.GIF {
+	pal := color.Palette{color.RGBA{}}
+	for _, c := range colors {
+		pal = append(pal, c)
+	}
+
+	g := &gif.GIF{
+		LoopCount: 3,
+		Config:    image.Config{ColorModel: pal, Width: width, Height: height},
+	}
+
+	for i := range colors {
+		// 最初のフレームは全体、以降は左上1/4のみ
+		rect := image.Rect(0, 0, width, height)
+		if i > 0 {
+			rect = image.Rect(0, 0, width/2, height/2)
+		}
+		frame := image.NewPaletted(rect, pal)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i + 1)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, (i+1)*10)
+		g.Disposal = append(g.Disposal, disposal)
+	}
+
+	return g
+}
+
+// saveTestGIF はアニメーションGIFをファイルに保存します
+func saveTestGIF(t *testing.T, path string, g *gif.GIF) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create test GIF file: %v", err)
+	}
+	defer file.Close()
+
+	if err := gif.EncodeAll(file, g); err != nil {
+		t.Fatalf("Failed to encode test GIF: %v", err)
+	}
+}
+
+func TestCountGIFFrames(t *testing.T) {
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, createTestGIF(20, 20, colors, gif.DisposalNone)); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	data := buf.Bytes()
+
+	frames, err := CountGIFFrames(bytes.NewReader(data))
+	if err != nil || frames != len(colors) {
+		t.Errorf("CountGIFFrames = %d, %v; want %d", frames, err, len(colors))
+	}
+
+	// 途中で切れたデータは、それまでのフレーム数とエラーを返す
+	frames, err = CountGIFFrames(bytes.NewReader(data[:len(data)-4]))
+	if err == nil || frames != len(colors)-1 {
+		t.Errorf("Truncated: CountGIFFrames = %d, %v; want %d and an error", frames, err, len(colors)-1)
+	}
+}
+
+func TestCompositeGIF_DisposalNone(t *testing.T) {
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}}
+	anim := compositeGIF(createTestGIF(20, 20, colors, gif.DisposalNone))
+
+	if anim.FrameCount() != 2 {
+		t.Fatalf("Expected 2 frames, got %d", anim.FrameCount())
+	}
+
+	// 2フレーム目: 左上は緑、右下は前のフレームの赤が残る
+	second := anim.Frames[1]
+	if r, g, _, _ := second.At(2, 2).RGBA(); r != 0 || g != 0xffff {
+		t.Errorf("Expected green at top-left, got %v", second.At(2, 2))
+	}
+	if r, g, _, _ := second.At(15, 15).RGBA(); r != 0xffff || g != 0 {
+		t.Errorf("Expected red at bottom-right, got %v", second.At(15, 15))
+	}
+}
+
+func TestCompositeGIF_DisposalBackground(t *testing.T) {
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	anim := compositeGIF(createTestGIF(20, 20, colors, gif.DisposalBackground))
+
+	// 1フレーム目の全体が背景（透明）に戻された後に2フレーム目が描画される
+	second := anim.Frames[1]
+	if _, _, _, a := second.At(15, 15).RGBA(); a != 0 {
+		t.Errorf("Expected transparent at bottom-right after background disposal, got %v", second.At(15, 15))
+	}
+	if _, g, _, _ := second.At(2, 2).RGBA(); g != 0xffff {
+		t.Errorf("Expected green at top-left, got %v", second.At(2, 2))
+	}
+}
+
+func TestCompositeGIF_DisposalPrevious(t *testing.T) {
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	g := createTestGIF(20, 20, colors, gif.DisposalPrevious)
+	// 1フレーム目は残す
+	g.Disposal[0] = gif.DisposalNone
+	anim := compositeGIF(g)
+
+	// 2フレーム目は破棄されるため、3フレーム目の右下は赤のまま
+	third := anim.Frames[2]
+	if r, _, _, _ := third.At(15, 15).RGBA(); r != 0xffff {
+		t.Errorf("Expected red at bottom-right, got %v", third.At(15, 15))
+	}
+	if _, _, b, _ := third.At(2, 2).RGBA(); b != 0xffff {
+		t.Errorf("Expected blue at top-left, got %v", third.At(2, 2))
+	}
+}
+
+func TestImageLoader_LoadAnimation(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "anim.gif")
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	saveTestGIF(t, path, createTestGIF(40, 30, colors, gif.DisposalNone))
+
+	anim, err := NewImageLoader().LoadAnimation(path)
+	if err != nil {
+		t.Fatalf("Failed to load animation: %v", err)
+	}
+
+	if anim.FrameCount() != 3 {
+		t.Errorf("Expected 3 frames, got %d", anim.FrameCount())
+	}
+	if anim.LoopCount != 3 {
+		t.Errorf("Expected loop count 3, got %d", anim.LoopCount)
+	}
+	for i, delay := range []int{10, 20, 30} {
+		if anim.Delays[i] != delay {
+			t.Errorf("Frame %d: expected delay %d, got %d", i, delay, anim.Delays[i])
+		}
+	}
+	for i, frame := range anim.Frames {
+		if frame.Bounds().Dx() != 40 || frame.Bounds().Dy() != 30 {
+			t.Errorf("Frame %d: expected 40x30, got %v", i, frame.Bounds())
+		}
+	}
+}
+
+func TestConverter_ConvertImage_AnimatedGIF(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "anim.gif")
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	saveTestGIF(t, inputPath, createTestGIF(100, 60, colors, gif.DisposalNone))
+
+	converter := NewConverter(types.Config{Scale: 0.5, JPEGQuality: 85})
+	result := converter.ConvertImage(inputPath, outputDir)
+	if !result.Success {
+		t.Fatalf("Expected conversion to succeed, got error: %v", result.Error)
+	}
+
+	file, err := os.Open(result.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer file.Close()
+
+	out, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatalf("Failed to decode output GIF: %v", err)
+	}
+
+	if len(out.Image) != 3 {
+		t.Errorf("Expected 3 frames, got %d", len(out.Image))
+	}
+	if out.LoopCount != 3 {
+		t.Errorf("Expected loop count 3, got %d", out.LoopCount)
+	}
+	for i, delay := range []int{10, 20, 30} {
+		if out.Delay[i] != delay {
+			t.Errorf("Frame %d: expected delay %d, got %d", i, delay, out.Delay[i])
+		}
+	}
+	if out.Config.Width != 50 || out.Config.Height != 30 {
+		t.Errorf("Expected 50x30, got %dx%d", out.Config.Width, out.Config.Height)
+	}
+}
+
+func TestConverter_ConvertImage_FirstFrameOnly(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "anim.gif")
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}}
+	saveTestGIF(t, inputPath, createTestGIF(40, 40, colors, gif.DisposalNone))
+
+	converter := NewConverter(types.Config{FirstFrameOnly: true, JPEGQuality: 85})
+	result := converter.ConvertImage(inputPath, outputDir)
+	if !result.Success {
+		t.Fatalf("Expected conversion to succeed, got error: %v", result.Error)
+	}
+
+	file, err := os.Open(result.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer file.Close()
+
+	out, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatalf("Failed to decode output GIF: %v", err)
+	}
+	if len(out.Image) != 1 {
+		t.Errorf("Expected 1 frame, got %d", len(out.Image))
+	}
+}
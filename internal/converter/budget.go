@@ -0,0 +1,211 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"image-converter/internal/types"
+)
+
+// MinBudgetQuality は容量制限モードで探索するJPEG/WebP品質の下限です
+const MinBudgetQuality = 10
+
+// minBudgetColors は容量制限モードで探索するパレット色数の下限です
+const minBudgetColors = 2
+
+// maxDownscaleAttempts は容量制限モードで追加縮小を行う最大回数です
+const maxDownscaleAttempts = 8
+
+// outputImage は保存対象の画像（静止画またはアニメーション）を表します
+type outputImage struct {
+	still image.Image
+	anim  *AnimatedImage
+}
+
+// bounds は出力画像の境界を返します
+func (o outputImage) bounds() image.Rectangle {
+	if o.anim != nil {
+		return o.anim.Bounds()
+	}
+	return o.still.Bounds()
+}
+
+// encode は出力画像を指定されたフォーマットでwに書き込みます
+func (o outputImage) encode(saver *ImageSaver, w io.Writer, format types.ImageFormat, quality int) error {
+	if o.anim != nil {
+		return saver.EncodeAnimation(w, o.anim, format, quality)
+	}
+	return saver.Encode(w, o.still, format, quality)
+}
+
+// downscale は出力画像を倍率指定で縮小します
+func (o outputImage) downscale(resizer *ResizeCalculator, scale float64) outputImage {
+	spec := types.ResizeSpec{Scale: scale}
+	if o.anim != nil {
+		return outputImage{anim: resizer.ResizeAnimation(o.anim, spec)}
+	}
+	return outputImage{still: resizer.ResizeImage(o.still, spec)}
+}
+
+// BudgetResult は容量制限内でのエンコード結果を表します
+type BudgetResult struct {
+	Data    []byte
+	Quality int // 採用した品質（JPEG/WebP）または色数（GIF/パレットPNG）、0はフルカラー
+	Width   int
+	Height  int
+}
+
+// encodeWithinBudget は出力がmaxBytes以下になる最も高い品質（または色数）を二分探索します
+// 最低品質でも超える場合、allowDownscaleが有効なら画像をさらに縮小して再探索します
+func encodeWithinBudget(saver *ImageSaver, resizer *ResizeCalculator, out outputImage, format types.ImageFormat, quality int, maxBytes int64, allowDownscale bool) (*BudgetResult, error) {
+	current := out
+	for attempt := 0; ; attempt++ {
+		data, knob, smallest, err := searchBudget(saver, current, format, quality, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if data != nil {
+			bounds := current.bounds()
+			return &BudgetResult{
+				Data:    data,
+				Quality: knob,
+				Width:   bounds.Dx(),
+				Height:  bounds.Dy(),
+			}, nil
+		}
+
+		if !allowDownscale || attempt >= maxDownscaleAttempts {
+			return nil, fmt.Errorf("cannot fit within %d bytes (smallest: %d bytes)", maxBytes, smallest)
+		}
+
+		// ファイルサイズが面積にほぼ比例すると仮定して縮小率を見積もる
+		scale := math.Sqrt(float64(maxBytes)/float64(smallest)) * 0.95
+		scale = math.Max(0.5, math.Min(0.95, scale))
+
+		bounds := current.bounds()
+		if int(math.Round(float64(bounds.Dx())*scale)) < 1 || int(math.Round(float64(bounds.Dy())*scale)) < 1 {
+			return nil, fmt.Errorf("cannot fit within %d bytes (smallest: %d bytes)", maxBytes, smallest)
+		}
+		current = current.downscale(resizer, scale)
+	}
+}
+
+// searchBudget はフォーマットに応じた調整項目で容量制限内に収まる設定を探索します
+// 収まらない場合はdataがnilで、smallestに最小のサイズを返します
+func searchBudget(saver *ImageSaver, out outputImage, format types.ImageFormat, quality int, maxBytes int64) (data []byte, knob int, smallest int64, err error) {
+	switch format {
+	case types.FormatJPEG, types.FormatWebP:
+		lo := MinBudgetQuality
+		if quality < lo {
+			lo = quality
+		}
+		return binarySearchBudget(lo, quality, maxBytes, func(q int) ([]byte, error) {
+			return encodeToBytes(saver, out, format, q)
+		})
+
+	case types.FormatGIF:
+		return binarySearchBudget(minBudgetColors, saver.maxColors(), maxBytes, func(colors int) ([]byte, error) {
+			options := saver.options
+			options.Colors = colors
+			return encodeToBytes(NewImageSaverWithOptions(options), out, format, quality)
+		})
+
+	case types.FormatPNG:
+		// パレットモードでなければ、まずフルカラーで試す
+		if !saver.options.PNGPalette {
+			data, err := encodeToBytes(saver, out, format, quality)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			if int64(len(data)) <= maxBytes {
+				return data, 0, int64(len(data)), nil
+			}
+		}
+		return binarySearchBudget(minBudgetColors, saver.maxColors(), maxBytes, func(colors int) ([]byte, error) {
+			options := saver.options
+			options.Colors = colors
+			options.PNGPalette = true
+			// 誤差によるフルカラーへのフォールバックはサイズの単調性を崩すため無効化
+			options.PNGMaxError = 0
+			return encodeToBytes(NewImageSaverWithOptions(options), out, format, quality)
+		})
+
+	default:
+		// 調整項目のないフォーマットはそのままエンコード
+		data, err := encodeToBytes(saver, out, format, quality)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if int64(len(data)) <= maxBytes {
+			return data, 0, int64(len(data)), nil
+		}
+		return nil, 0, int64(len(data)), nil
+	}
+}
+
+// binarySearchBudget はencodeの出力サイズがkに対して単調増加すると仮定し、
+// maxBytes以下となる最大のkを[lo, hi]の範囲で探索します
+func binarySearchBudget(lo, hi int, maxBytes int64, encode func(k int) ([]byte, error)) ([]byte, int, int64, error) {
+	smallest := int64(math.MaxInt64)
+	var best []byte
+	bestK := 0
+
+	try := func(k int) (bool, error) {
+		data, err := encode(k)
+		if err != nil {
+			return false, err
+		}
+		size := int64(len(data))
+		if size < smallest {
+			smallest = size
+		}
+		if size <= maxBytes {
+			best, bestK = data, k
+			return true, nil
+		}
+		return false, nil
+	}
+
+	// 最高品質で収まればそれを採用
+	fits, err := try(hi)
+	if err != nil || fits {
+		return best, bestK, smallest, err
+	}
+
+	hi--
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		fits, err := try(mid)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if fits {
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return best, bestK, smallest, nil
+}
+
+// encodeToBytes は出力画像をメモリ上にエンコードします
+func encodeToBytes(saver *ImageSaver, out outputImage, format types.ImageFormat, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := out.encode(saver, &buf, format, quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// maxColors は探索するパレット色数の上限を返します
+func (is *ImageSaver) maxColors() int {
+	if is.options.Colors >= minBudgetColors && is.options.Colors <= 256 {
+		return is.options.Colors
+	}
+	return DefaultColors
+}
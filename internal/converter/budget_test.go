@@ -0,0 +1,160 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// createNoisyImage は圧縮しにくいノイズ画像を生成します（乱数は固定シード）
+func createNoisyImage(width, height int) image.Image {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(x*2 + rng.Intn(64)),
+				G: uint8(y*2 + rng.Intn(64)),
+				B: uint8(rng.Intn(256)),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func TestEncodeWithinBudget_JPEG(t *testing.T) {
+	saver := NewImageSaver()
+	resizer := NewResizeCalculator()
+	out := outputImage{still: createNoisyImage(128, 128)}
+
+	full, err := encodeToBytes(saver, out, types.FormatJPEG, 95)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	budget := int64(len(full)) / 2
+
+	result, err := encodeWithinBudget(saver, resizer, out, types.FormatJPEG, 95, budget, false)
+	if err != nil {
+		t.Fatalf("Expected to fit within budget: %v", err)
+	}
+	if int64(len(result.Data)) > budget {
+		t.Errorf("Output %d bytes exceeds budget %d", len(result.Data), budget)
+	}
+	if result.Quality >= 95 || result.Quality < MinBudgetQuality {
+		t.Errorf("Expected reduced quality, got %d", result.Quality)
+	}
+
+	// 1段階高い品質では上限を超えることを確認（最高品質が選ばれている）
+	higher, err := encodeToBytes(saver, out, types.FormatJPEG, result.Quality+1)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if int64(len(higher)) <= budget {
+		t.Errorf("Quality %d also fits (%d bytes), expected the highest fitting quality", result.Quality+1, len(higher))
+	}
+}
+
+func TestEncodeWithinBudget_KeepsQualityWhenFits(t *testing.T) {
+	result, err := encodeWithinBudget(NewImageSaver(), NewResizeCalculator(), outputImage{still: createTestImage(32, 32)}, types.FormatJPEG, 85, 1<<20, false)
+	if err != nil {
+		t.Fatalf("Expected to fit within budget: %v", err)
+	}
+	if result.Quality != 85 {
+		t.Errorf("Expected quality 85, got %d", result.Quality)
+	}
+}
+
+func TestEncodeWithinBudget_GIFColors(t *testing.T) {
+	saver := NewImageSaver()
+	out := outputImage{still: createNoisyImage(96, 96)}
+
+	full, err := encodeToBytes(saver, out, types.FormatGIF, 85)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	budget := int64(len(full)) * 2 / 3
+
+	result, err := encodeWithinBudget(saver, NewResizeCalculator(), out, types.FormatGIF, 85, budget, false)
+	if err != nil {
+		t.Fatalf("Expected to fit within budget: %v", err)
+	}
+	if int64(len(result.Data)) > budget {
+		t.Errorf("Output %d bytes exceeds budget %d", len(result.Data), budget)
+	}
+	if result.Quality < minBudgetColors || result.Quality >= 256 {
+		t.Errorf("Expected reduced color count, got %d", result.Quality)
+	}
+}
+
+func TestEncodeWithinBudget_PNGFallsBackToPalette(t *testing.T) {
+	saver := NewImageSaver()
+	out := outputImage{still: createNoisyImage(64, 64)}
+
+	full, err := encodeToBytes(saver, out, types.FormatPNG, 85)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	result, err := encodeWithinBudget(saver, NewResizeCalculator(), out, types.FormatPNG, 85, int64(len(full))/2, false)
+	if err != nil {
+		t.Fatalf("Expected to fit within budget: %v", err)
+	}
+	if result.Quality == 0 {
+		t.Error("Expected paletted PNG (non-zero color count)")
+	}
+}
+
+func TestEncodeWithinBudget_Downscale(t *testing.T) {
+	saver := NewImageSaver()
+	resizer := NewResizeCalculator()
+	out := outputImage{still: createNoisyImage(200, 200)}
+
+	// BMPは品質の調整ができないため、縮小しなければ収まらない
+	if _, err := encodeWithinBudget(saver, resizer, out, types.FormatBMP, 85, 40000, false); err == nil {
+		t.Fatal("Expected error without downscale")
+	}
+
+	result, err := encodeWithinBudget(saver, resizer, out, types.FormatBMP, 85, 40000, true)
+	if err != nil {
+		t.Fatalf("Expected to fit with downscale: %v", err)
+	}
+	if len(result.Data) > 40000 {
+		t.Errorf("Output %d bytes exceeds budget", len(result.Data))
+	}
+	if result.Width >= 200 || result.Height >= 200 {
+		t.Errorf("Expected downscaled image, got %dx%d", result.Width, result.Height)
+	}
+}
+
+func TestConverter_ConvertImage_MaxBytes(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "photo.png")
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	saveTestImage(t, inputPath, createNoisyImage(160, 120))
+
+	converter := NewConverter(types.Config{Format: "jpeg", JPEGQuality: 95, MaxBytes: 8000})
+	result := converter.ConvertImage(inputPath, outputDir)
+	if !result.Success {
+		t.Fatalf("Expected conversion to succeed, got error: %v", result.Error)
+	}
+
+	info, err := os.Stat(result.OutputPath)
+	if err != nil {
+		t.Fatalf("Output file does not exist: %v", err)
+	}
+	if info.Size() > 8000 {
+		t.Errorf("Output %d bytes exceeds budget", info.Size())
+	}
+	if result.Quality == 0 || result.Quality > 95 {
+		t.Errorf("Unexpected reported quality: %d", result.Quality)
+	}
+}
@@ -0,0 +1,406 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+// cacheVersion はキャッシュキーの形式のバージョンです（出力が変わる変更を行った場合は増やす）
+const cacheVersion = 1
+
+// cacheTempPrefix は書き込み中のキャッシュエントリの一時ファイル名の接頭辞です
+const cacheTempPrefix = ".tmp-"
+
+// CacheEntry はキャッシュした出力の情報です
+type CacheEntry struct {
+	Format  types.ImageFormat `json:"format"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Quality int               `json:"quality,omitempty"`
+	SSIM    float64           `json:"ssim,omitempty"`
+}
+
+// ResultCache は変換結果を内容アドレスで保存するディスクキャッシュです
+// 各エントリは「メタデータ（JSON1行）＋出力のバイト列」の1ファイルで、
+// 合計サイズが上限を超えた場合は最後に参照された時刻（ファイルの更新時刻）が古いものから削除します
+// 複数のゴルーチンから安全に使用できます
+type ResultCache struct {
+	dir      string
+	maxBytes int64 // 合計サイズの上限（0の場合は無制限）
+
+	mu      sync.Mutex
+	entries map[string]*cacheFile
+	total   int64
+}
+
+// cacheFile はキャッシュエントリのサイズと最終参照時刻です
+type cacheFile struct {
+	size     int64
+	accessed time.Time
+}
+
+// OpenResultCache はdirをキャッシュディレクトリとして開きます（存在しない場合は作成します）
+// 既存のエントリを読み込み、合計サイズがmaxBytesを超えている場合は古いものから削除します
+func OpenResultCache(dir string, maxBytes int64) (*ResultCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	rc := &ResultCache{dir: dir, maxBytes: maxBytes, entries: map[string]*cacheFile{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if strings.HasPrefix(name, cacheTempPrefix) {
+			// 中断された書き込みの残骸
+			os.Remove(path)
+			return nil
+		}
+		if !isCacheKey(name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rc.entries[name] = &cacheFile{size: info.Size(), accessed: info.ModTime()}
+		rc.total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+
+	rc.mu.Lock()
+	rc.evict("")
+	rc.mu.Unlock()
+	return rc, nil
+}
+
+// Dir はキャッシュディレクトリを返します
+func (rc *ResultCache) Dir() string {
+	return rc.dir
+}
+
+// Size はキャッシュエントリの合計サイズ（バイト）を返します
+func (rc *ResultCache) Size() int64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.total
+}
+
+// Get はkeyに対応する出力とその情報を返します（存在しない場合はokがfalse）
+// 参照したエントリは最近使用したものとして扱われます
+func (rc *ResultCache) Get(key string) (data []byte, entry CacheEntry, ok bool) {
+	path := rc.path(key)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		// 別のプロセスに削除された場合など
+		rc.forget(key)
+		return nil, CacheEntry{}, false
+	}
+
+	meta, data, found := bytes.Cut(raw, []byte{'\n'})
+	if !found || json.Unmarshal(meta, &entry) != nil {
+		// 壊れたエントリは削除する
+		rc.remove(key)
+		return nil, CacheEntry{}, false
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	rc.mu.Lock()
+	if file, exists := rc.entries[key]; exists {
+		file.accessed = now
+	} else {
+		// 別のプロセスが追加したエントリ
+		rc.entries[key] = &cacheFile{size: int64(len(raw)), accessed: now}
+		rc.total += int64(len(raw))
+	}
+	rc.mu.Unlock()
+
+	return data, entry, true
+}
+
+// Put はkeyに出力とその情報を保存し、上限を超えた分の古いエントリを削除します
+// 単独で上限を超える出力は保存しません
+func (rc *ResultCache) Put(key string, data []byte, entry CacheEntry) error {
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	size := int64(len(meta) + 1 + len(data))
+	if rc.maxBytes > 0 && size > rc.maxBytes {
+		return nil
+	}
+
+	path := rc.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// 読み込み中のエントリが壊れないよう、一時ファイルに書き込んでから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(path), cacheTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	w.Write(meta)
+	w.WriteByte('\n')
+	w.Write(data)
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if file, exists := rc.entries[key]; exists {
+		rc.total -= file.size
+	}
+	rc.entries[key] = &cacheFile{size: size, accessed: time.Now()}
+	rc.total += size
+	rc.evict(key)
+	return nil
+}
+
+// evict は合計サイズが上限以下になるまで最終参照時刻の古いエントリを削除します
+// keepのエントリは削除しません（呼び出し側でrc.muをロックしていること）
+func (rc *ResultCache) evict(keep string) {
+	if rc.maxBytes <= 0 || rc.total <= rc.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(rc.entries))
+	for key := range rc.entries {
+		if key != keep {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return rc.entries[keys[i]].accessed.Before(rc.entries[keys[j]].accessed)
+	})
+
+	for _, key := range keys {
+		if rc.total <= rc.maxBytes {
+			break
+		}
+		os.Remove(rc.path(key))
+		rc.total -= rc.entries[key].size
+		delete(rc.entries, key)
+	}
+}
+
+// remove はエントリをファイルごと削除します
+func (rc *ResultCache) remove(key string) {
+	os.Remove(rc.path(key))
+	rc.forget(key)
+}
+
+// forget はエントリを索引から取り除きます
+func (rc *ResultCache) forget(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if file, exists := rc.entries[key]; exists {
+		rc.total -= file.size
+		delete(rc.entries, key)
+	}
+}
+
+// path はエントリのファイルパスを返します（キーの先頭2文字でディレクトリを分ける）
+func (rc *ResultCache) path(key string) string {
+	return filepath.Join(rc.dir, key[:2], key)
+}
+
+// isCacheKey はnameがキャッシュキー（SHA-256の16進文字列）か判定します
+func isCacheKey(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// cacheParams はキャッシュキーの計算に使用する変換パラメーターです
+type cacheParams struct {
+	Version        int
+	Source         string // 入力内容のSHA-256
+	Resize         types.ResizeSpec
+	Format         types.ImageFormat
+	Quality        int
+	Encode         types.EncodeOptions
+	MaxBytes       int64
+	AllowDownscale bool
+	TargetSSIM     float64
+	Animation      bool
+}
+
+// CacheKey は入力内容のハッシュと変換パラメーターからキャッシュキーを計算します
+// 出力に影響しないパラメーターの違いでキーが変わらないよう、リサイズ仕様とエンコーダー設定を正規化します
+func CacheKey(sourceHash string, spec types.ResizeSpec, format types.ImageFormat, quality int, options types.EncodeOptions, config types.Config, preserveAnimation bool) string {
+	params := cacheParams{
+		Version:        cacheVersion,
+		Source:         sourceHash,
+		Resize:         normalizeResizeSpec(spec),
+		Format:         format,
+		Quality:        quality,
+		Encode:         options,
+		MaxBytes:       config.MaxBytes,
+		AllowDownscale: config.AllowDownscale && config.MaxBytes > 0,
+		TargetSSIM:     config.TargetSSIM,
+		Animation:      preserveAnimation,
+	}
+
+	// 品質の探索を行わない場合、品質はJPEG/WebP以外の出力に影響しない
+	if format != types.FormatJPEG && format != types.FormatWebP && config.MaxBytes == 0 && config.TargetSSIM == 0 {
+		params.Quality = 0
+	}
+	if format != types.FormatJPEG {
+		params.Encode.JPEGProgressive = false
+		params.Encode.JPEGSubsampling = ""
+	}
+	if format != types.FormatGIF && format != types.FormatPNG {
+		params.Encode.Colors = 0
+		params.Encode.Dither = ""
+	}
+	if format != types.FormatPNG {
+		params.Encode.PNGPalette = false
+		params.Encode.PNGMaxError = 0
+	}
+
+	encoded, _ := json.Marshal(params)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeResizeSpec は同じ出力になるリサイズ仕様を同じ値にそろえます
+func normalizeResizeSpec(spec types.ResizeSpec) types.ResizeSpec {
+	if spec.Scale > 0 {
+		return types.ResizeSpec{Scale: spec.Scale}
+	}
+	if spec.Width == 0 || spec.Height == 0 {
+		// 合わせ方は幅と高さの両方を指定した場合のみ意味を持つ
+		spec.Fit = ""
+	} else if spec.Fit == "" {
+		spec.Fit = types.FitContain
+	}
+	return spec
+}
+
+// hashReader はrの内容のSHA-256ハッシュを16進文字列で返します
+func hashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cacheKey はこのConverterの設定で変換する場合のキャッシュキーを計算します
+func (c *Converter) cacheKey(sourceHash string, spec types.ResizeSpec, format types.ImageFormat, quality int, preserveAnimation bool) string {
+	return CacheKey(sourceHash, spec, format, quality, c.saver.options, c.config, preserveAnimation)
+}
+
+// sourceHash はキャッシュが有効な場合に入力ファイルのハッシュを返します（無効な場合や読み込めない場合は空）
+func (c *Converter) sourceHash(sourcePath string) string {
+	if c.cache == nil {
+		return ""
+	}
+	file, err := os.Open(sourcePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	hash, err := hashReader(file)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// restoreCached はキャッシュに一致する出力があればresult.OutputPathに書き込み、trueを返します
+func (c *Converter) restoreCached(result *types.ConversionResult, key string) bool {
+	if key == "" {
+		return false
+	}
+	data, entry, ok := c.cache.Get(key)
+	if !ok {
+		return false
+	}
+	err := WriteFileAtomic(result.OutputPath, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		result.Error = fmt.Errorf("failed to save image: %w", err)
+		return true
+	}
+	applyCacheEntry(result, entry, int64(len(data)))
+	return true
+}
+
+// storeCached は書き込み済みの出力ファイルをキャッシュに保存します
+// キャッシュへの保存は最適化のため、失敗しても変換結果には影響しません
+func (c *Converter) storeCached(result *types.ConversionResult, key string) {
+	if key == "" || !result.Success {
+		return
+	}
+	result.Cache = types.CacheMiss
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil {
+		return
+	}
+	c.cache.Put(key, data, cacheEntryFromResult(*result))
+}
+
+// cacheEntryFromResult は変換結果からキャッシュに保存する情報を作成します
+func cacheEntryFromResult(result types.ConversionResult) CacheEntry {
+	return CacheEntry{
+		Format:  result.Format,
+		Width:   result.Width,
+		Height:  result.Height,
+		Quality: result.Quality,
+		SSIM:    result.SSIM,
+	}
+}
+
+// applyCacheEntry はキャッシュの情報を変換結果に記録します
+func applyCacheEntry(result *types.ConversionResult, entry CacheEntry, size int64) {
+	result.Format = entry.Format
+	result.Width = entry.Width
+	result.Height = entry.Height
+	result.Quality = entry.Quality
+	result.SSIM = entry.SSIM
+	result.Bytes = size
+	result.Cache = types.CacheHit
+	result.Success = true
+}
@@ -0,0 +1,267 @@
+package converter
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+func TestCacheKey_Normalization(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	options := types.EncodeOptions{Colors: 256, JPEGSubsampling: types.Subsampling420}
+	key := func(spec types.ResizeSpec, format types.ImageFormat, quality int, options types.EncodeOptions) string {
+		return CacheKey(hash, spec, format, quality, options, types.Config{}, false)
+	}
+
+	same := []struct {
+		name string
+		a, b string
+	}{
+		{"fit defaults to contain",
+			key(types.ResizeSpec{Width: 100, Height: 100}, types.FormatJPEG, 85, options),
+			key(types.ResizeSpec{Width: 100, Height: 100, Fit: types.FitContain}, types.FormatJPEG, 85, options)},
+		{"fit ignored without both dimensions",
+			key(types.ResizeSpec{Width: 100}, types.FormatJPEG, 85, options),
+			key(types.ResizeSpec{Width: 100, Fit: types.FitCover}, types.FormatJPEG, 85, options)},
+		{"quality ignored for PNG",
+			key(types.ResizeSpec{Width: 100}, types.FormatPNG, 85, options),
+			key(types.ResizeSpec{Width: 100}, types.FormatPNG, 50, options)},
+		{"JPEG options ignored for PNG",
+			key(types.ResizeSpec{Width: 100}, types.FormatPNG, 85, options),
+			key(types.ResizeSpec{Width: 100}, types.FormatPNG, 85, types.EncodeOptions{Colors: 256, JPEGProgressive: true})},
+	}
+	for _, tt := range same {
+		if tt.a != tt.b {
+			t.Errorf("%s: expected equal keys", tt.name)
+		}
+	}
+
+	base := key(types.ResizeSpec{Width: 100}, types.FormatJPEG, 85, options)
+	different := []struct {
+		name string
+		key  string
+	}{
+		{"width", key(types.ResizeSpec{Width: 200}, types.FormatJPEG, 85, options)},
+		{"format", key(types.ResizeSpec{Width: 100}, types.FormatWebP, 85, options)},
+		{"quality", key(types.ResizeSpec{Width: 100}, types.FormatJPEG, 70, options)},
+		{"progressive", key(types.ResizeSpec{Width: 100}, types.FormatJPEG, 85, types.EncodeOptions{Colors: 256, JPEGSubsampling: types.Subsampling420, JPEGProgressive: true})},
+		{"source", CacheKey(strings.Repeat("cd", 32), types.ResizeSpec{Width: 100}, types.FormatJPEG, 85, options, types.Config{}, false)},
+		{"max bytes", CacheKey(hash, types.ResizeSpec{Width: 100}, types.FormatJPEG, 85, options, types.Config{MaxBytes: 1000}, false)},
+	}
+	for _, tt := range different {
+		if tt.key == base {
+			t.Errorf("%s: expected a different key", tt.name)
+		}
+	}
+}
+
+func TestResultCache_PutGet(t *testing.T) {
+	cache, err := OpenResultCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+
+	key := strings.Repeat("01", 32)
+	if _, _, ok := cache.Get(key); ok {
+		t.Fatal("Expected a miss on an empty cache")
+	}
+
+	entry := CacheEntry{Format: types.FormatJPEG, Width: 40, Height: 20, Quality: 80}
+	if err := cache.Put(key, []byte("data\nwith newline"), entry); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	data, got, ok := cache.Get(key)
+	if !ok {
+		t.Fatal("Expected a hit after Put")
+	}
+	if string(data) != "data\nwith newline" || got != entry {
+		t.Errorf("Get = %q %+v, want %q %+v", data, got, "data\nwith newline", entry)
+	}
+
+	// 開き直しても既存のエントリを使用できる
+	reopened, err := OpenResultCache(cache.Dir(), 0)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+	if reopened.Size() != cache.Size() {
+		t.Errorf("Size after reopen = %d, want %d", reopened.Size(), cache.Size())
+	}
+	if _, _, ok := reopened.Get(key); !ok {
+		t.Error("Expected a hit after reopening")
+	}
+}
+
+func TestResultCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	entry := CacheEntry{Format: types.FormatPNG}
+	data := bytes.Repeat([]byte{1}, 100)
+
+	// 各エントリはメタデータを含めて約140バイトで、上限には3つまで収まる
+	cache, err := OpenResultCache(t.TempDir(), 450)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+
+	keys := []string{strings.Repeat("a1", 32), strings.Repeat("b2", 32), strings.Repeat("c3", 32), strings.Repeat("d4", 32)}
+	for _, key := range keys[:3] {
+		if err := cache.Put(key, data, entry); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 最初のエントリを参照して最近使用したものにする
+	if _, _, ok := cache.Get(keys[0]); !ok {
+		t.Fatal("Expected a hit")
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if err := cache.Put(keys[3], data, entry); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if cache.Size() > 450 {
+		t.Errorf("Size = %d, want <= 450", cache.Size())
+	}
+	if _, _, ok := cache.Get(keys[1]); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{keys[0], keys[2], keys[3]} {
+		if _, _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected %s... to remain", key[:4])
+		}
+	}
+
+	// 上限を超える出力は保存しない
+	if err := cache.Put(strings.Repeat("e5", 32), bytes.Repeat([]byte{1}, 500), entry); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, _, ok := cache.Get(strings.Repeat("e5", 32)); ok {
+		t.Error("Expected an entry larger than the limit not to be stored")
+	}
+}
+
+func TestConverter_ConvertImageCached(t *testing.T) {
+	tempDir := t.TempDir()
+	sourcePath := filepath.Join(tempDir, "photo.png")
+	if err := saveImageWithFormat(createTestImage(80, 40), sourcePath, types.FormatPNG); err != nil {
+		t.Fatalf("Failed to save test image: %v", err)
+	}
+
+	cache, err := OpenResultCache(filepath.Join(tempDir, "cache"), 0)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+	config := types.Config{Width: 40, Format: "jpeg", JPEGQuality: 85}
+
+	outputs := []string{filepath.Join(tempDir, "a"), filepath.Join(tempDir, "b")}
+	var results []types.ConversionResult
+	for _, dir := range outputs {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		conv := NewConverterWithCache(config, cache)
+		result := conv.ConvertImage(sourcePath, dir)
+		if !result.Success {
+			t.Fatalf("ConvertImage failed: %v", result.Error)
+		}
+		conv.UpdateStats(result)
+		results = append(results, result)
+
+		stats := conv.GetStats()
+		if stats.CacheHits+stats.CacheMisses != 1 {
+			t.Errorf("Expected one cache lookup in stats, got %+v", stats)
+		}
+	}
+
+	if results[0].Cache != types.CacheMiss || results[1].Cache != types.CacheHit {
+		t.Fatalf("Cache = %q, %q; want miss, hit", results[0].Cache, results[1].Cache)
+	}
+	if results[1].Width != 40 || results[1].Height != 20 || results[1].Format != types.FormatJPEG || results[1].Bytes != results[0].Bytes {
+		t.Errorf("Cached result = %+v, want the same as %+v", results[1], results[0])
+	}
+
+	a, _ := os.ReadFile(results[0].OutputPath)
+	b, _ := os.ReadFile(results[1].OutputPath)
+	if !bytes.Equal(a, b) {
+		t.Error("Expected the cached output to be identical")
+	}
+
+	// 設定が変わればキャッシュを使用しない
+	config.Width = 30
+	result := NewConverterWithCache(config, cache).ConvertImage(sourcePath, outputs[0])
+	if result.Cache != types.CacheMiss {
+		t.Errorf("Cache = %q after changing the width, want miss", result.Cache)
+	}
+}
+
+func TestConverter_ConvertImageVariantsCached(t *testing.T) {
+	tempDir := t.TempDir()
+	sourcePath := filepath.Join(tempDir, "photo.png")
+	if err := saveImageWithFormat(createTestImage(80, 40), sourcePath, types.FormatPNG); err != nil {
+		t.Fatalf("Failed to save test image: %v", err)
+	}
+
+	cache, err := OpenResultCache(filepath.Join(tempDir, "cache"), 0)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+	config := types.Config{JPEGQuality: 85, Variants: []types.Variant{
+		{Suffix: "-sm", Resize: types.ResizeSpec{Width: 20}},
+		{Suffix: "-md", Resize: types.ResizeSpec{Width: 40}, Format: "webp"},
+	}}
+
+	NewConverterWithCache(config, cache).ConvertImageVariants(sourcePath, tempDir)
+
+	// 1つのバリエーションを追加すると、既存のバリエーションはキャッシュから復元される
+	config.Variants = append(config.Variants, types.Variant{Suffix: "-lg", Resize: types.ResizeSpec{Width: 60}})
+	results := NewConverterWithCache(config, cache).ConvertImageVariants(sourcePath, tempDir)
+
+	expected := []types.CacheStatus{types.CacheHit, types.CacheHit, types.CacheMiss}
+	for i, result := range results {
+		if !result.Success {
+			t.Fatalf("Variant %s failed: %v", result.Variant, result.Error)
+		}
+		if result.Cache != expected[i] {
+			t.Errorf("Variant %s: Cache = %q, want %q", result.Variant, result.Cache, expected[i])
+		}
+	}
+}
+
+func TestConverter_ConvertStreamCached(t *testing.T) {
+	var input bytes.Buffer
+	if err := png.Encode(&input, createTestImage(80, 40)); err != nil {
+		t.Fatalf("Failed to encode input: %v", err)
+	}
+
+	cache, err := OpenResultCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+	config := types.Config{Width: 40, JPEGQuality: 85}
+
+	var outputs [2]bytes.Buffer
+	expected := []types.CacheStatus{types.CacheMiss, types.CacheHit}
+	for i := range outputs {
+		result := NewConverterWithCache(config, cache).ConvertStream(bytes.NewReader(input.Bytes()), &outputs[i], types.FormatWebP)
+		if !result.Success {
+			t.Fatalf("ConvertStream failed: %v", result.Error)
+		}
+		if result.Cache != expected[i] {
+			t.Errorf("Cache = %q, want %q", result.Cache, expected[i])
+		}
+		if result.Width != 40 || result.Height != 20 || result.Bytes != int64(outputs[i].Len()) {
+			t.Errorf("result = %dx%d %d bytes, want 40x20 %d bytes", result.Width, result.Height, result.Bytes, outputs[i].Len())
+		}
+	}
+
+	if !bytes.Equal(outputs[0].Bytes(), outputs[1].Bytes()) {
+		t.Error("Expected the cached output to be identical")
+	}
+}
@@ -0,0 +1,161 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"image-converter/internal/types"
+)
+
+// Comparison は2つの画像の差の指標を表します
+type Comparison struct {
+	Width    int
+	Height   int
+	PSNR     float64 // RGBチャンネルのピーク信号対雑音比（dB、完全に一致する場合は+Inf）
+	SSIM     float64 // 輝度の構造的類似度（1.0が完全一致）
+	MaxError int     // チャンネルごとの差（RGBA、0-255）の最大値
+}
+
+// Identical は2つの画像の画素が完全に一致するかを返します
+func (c Comparison) Identical() bool {
+	return c.MaxError == 0
+}
+
+// CompareImages は2つの画像のPSNR・SSIM・最大誤差を計算します
+// 2つの画像は同じサイズである必要があります（異なる場合はResizeImageで揃えてから比較します）
+func CompareImages(a, b image.Image) (Comparison, error) {
+	ssim, err := SSIM(a, b)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	result := Comparison{Width: a.Bounds().Dx(), Height: a.Bounds().Dy(), SSIM: ssim}
+	var sumSquares float64
+	forEachPixelPair(a, b, func(x, y int, ca, cb [4]int) {
+		for i := 0; i < 4; i++ {
+			diff := ca[i] - cb[i]
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > result.MaxError {
+				result.MaxError = diff
+			}
+			if i < 3 {
+				sumSquares += float64(diff * diff)
+			}
+		}
+	})
+
+	mse := sumSquares / float64(result.Width*result.Height*3)
+	if mse == 0 {
+		result.PSNR = math.Inf(1)
+	} else {
+		result.PSNR = 10 * math.Log10(255*255/mse)
+	}
+	return result, nil
+}
+
+// LoadComparisonImages は比較する2つの画像ファイルを読み込みます（アニメーションは最初のフレーム）
+// resizeが有効でサイズが異なる場合は、bをaと同じサイズにリサイズします（縦横比が異なる場合は中央を切り取ります）
+func LoadComparisonImages(pathA, pathB string, resize bool) (image.Image, image.Image, error) {
+	loader := NewImageLoader()
+	a, err := loader.Load(pathA)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", pathA, err)
+	}
+	b, err := loader.Load(pathB)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", pathB, err)
+	}
+
+	size := a.Bounds().Size()
+	if resize && b.Bounds().Size() != size {
+		b = NewResizeCalculator().ResizeImage(b, types.ResizeSpec{Width: size.X, Height: size.Y, Fit: types.FitCover})
+	}
+	return a, b, nil
+}
+
+// DiffHeatmap は2つの画像の差を可視化した画像を返します
+// 差のない画素はaの輝度を暗くしたグレー、差のある画素は最大誤差に対する割合に応じて青（小）から赤（大）の色で表します
+func DiffHeatmap(a, b image.Image) (image.Image, error) {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return nil, fmt.Errorf("image size mismatch: %v vs %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+
+	width, height := a.Bounds().Dx(), a.Bounds().Dy()
+	diffs := make([]int, width*height)
+	gray := make([]uint8, width*height)
+	maxError := 0
+	forEachPixelPair(a, b, func(x, y int, ca, cb [4]int) {
+		e := 0
+		for i := 0; i < 4; i++ {
+			diff := ca[i] - cb[i]
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff > e {
+				e = diff
+			}
+		}
+		diffs[y*width+x] = e
+		if e > maxError {
+			maxError = e
+		}
+		gray[y*width+x] = uint8((299*ca[0] + 587*ca[1] + 114*ca[2]) / 1000 / 4)
+	})
+
+	heatmap := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			if diffs[i] == 0 {
+				g := gray[i]
+				heatmap.SetRGBA(x, y, color.RGBA{g, g, g, 255})
+				continue
+			}
+			heatmap.SetRGBA(x, y, heatColor(float64(diffs[i])/float64(maxError)))
+		}
+	}
+	return heatmap, nil
+}
+
+// heatColor は0-1の値を青・シアン・緑・黄・赤の順に変化する色に変換します
+func heatColor(t float64) color.RGBA {
+	stops := []color.RGBA{
+		{0, 0, 255, 255},
+		{0, 255, 255, 255},
+		{0, 255, 0, 255},
+		{255, 255, 0, 255},
+		{255, 0, 0, 255},
+	}
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	f := pos - float64(i)
+	lerp := func(p, q uint8) uint8 {
+		return uint8(math.Round(float64(p) + (float64(q)-float64(p))*f))
+	}
+	from, to := stops[i], stops[i+1]
+	return color.RGBA{lerp(from.R, to.R), lerp(from.G, to.G), lerp(from.B, to.B), 255}
+}
+
+// forEachPixelPair は同じサイズの2つの画像の対応する画素（RGBA、0-255）をfnに渡します
+// x, yは画像の左上を原点とする座標です
+func forEachPixelPair(a, b image.Image, fn func(x, y int, ca, cb [4]int)) {
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	for y := 0; y < boundsA.Dy(); y++ {
+		for x := 0; x < boundsA.Dx(); x++ {
+			fn(x, y, rgba8(a.At(boundsA.Min.X+x, boundsA.Min.Y+y)), rgba8(b.At(boundsB.Min.X+x, boundsB.Min.Y+y)))
+		}
+	}
+}
+
+// rgba8 は色をチャンネルごとの0-255の値に変換します
+func rgba8(c color.Color) [4]int {
+	r, g, b, a := c.RGBA()
+	return [4]int{int(r >> 8), int(g >> 8), int(b >> 8), int(a >> 8)}
+}
@@ -0,0 +1,136 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestCompareImages_Identical(t *testing.T) {
+	img := createTestImage(32, 24)
+
+	result, err := CompareImages(img, img)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if !result.Identical() || !math.IsInf(result.PSNR, 1) || result.SSIM < 0.9999 {
+		t.Errorf("Expected identical images, got %+v", result)
+	}
+	if result.Width != 32 || result.Height != 24 {
+		t.Errorf("Unexpected dimensions: %dx%d", result.Width, result.Height)
+	}
+}
+
+func TestCompareImages_KnownDifference(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	b := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			a.SetRGBA(x, y, color.RGBA{100, 100, 100, 255})
+			b.SetRGBA(x, y, color.RGBA{110, 100, 100, 255})
+		}
+	}
+
+	result, err := CompareImages(a, b)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if result.MaxError != 10 {
+		t.Errorf("MaxError = %d, want 10", result.MaxError)
+	}
+	// MSE = 10^2 / 3（3チャンネルのうち1チャンネルのみ差がある）
+	expected := 10 * math.Log10(255*255/(100.0/3))
+	if math.Abs(result.PSNR-expected) > 1e-9 {
+		t.Errorf("PSNR = %f, want %f", result.PSNR, expected)
+	}
+}
+
+func TestCompareImages_SizeMismatch(t *testing.T) {
+	if _, err := CompareImages(createTestImage(10, 10), createTestImage(20, 10)); err == nil {
+		t.Error("Expected an error for different sizes")
+	}
+	if _, err := DiffHeatmap(createTestImage(10, 10), createTestImage(20, 10)); err == nil {
+		t.Error("Expected an error for different sizes")
+	}
+}
+
+func TestDiffHeatmap(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	b := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		a.SetRGBA(x, 0, color.RGBA{200, 200, 200, 255})
+		b.SetRGBA(x, 0, color.RGBA{200, 200, 200, 255})
+	}
+	b.SetRGBA(1, 0, color.RGBA{190, 200, 200, 255}) // 小さい差
+	b.SetRGBA(2, 0, color.RGBA{0, 200, 200, 255})   // 最大の差
+
+	heatmap, err := DiffHeatmap(a, b)
+	if err != nil {
+		t.Fatalf("DiffHeatmap failed: %v", err)
+	}
+
+	// 差のない画素は暗いグレー
+	if c := color.RGBAModel.Convert(heatmap.At(0, 0)).(color.RGBA); c.R != c.G || c.G != c.B || c.R != 50 {
+		t.Errorf("Expected dimmed gray for an unchanged pixel, got %v", c)
+	}
+	// 小さい差は青寄り、最大の差は赤
+	if c := color.RGBAModel.Convert(heatmap.At(1, 0)).(color.RGBA); c.B < c.R {
+		t.Errorf("Expected a bluish color for a small difference, got %v", c)
+	}
+	if c := color.RGBAModel.Convert(heatmap.At(2, 0)).(color.RGBA); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Expected red for the largest difference, got %v", c)
+	}
+}
+
+func TestLoadComparisonImages_Resize(t *testing.T) {
+	tempDir := t.TempDir()
+	pathA := filepath.Join(tempDir, "a.png")
+	pathB := filepath.Join(tempDir, "b.png")
+	saveTestImage(t, pathA, createTestImage(40, 20))
+	saveTestImage(t, pathB, createTestImage(20, 10))
+
+	a, b, err := LoadComparisonImages(pathA, pathB, false)
+	if err != nil {
+		t.Fatalf("LoadComparisonImages failed: %v", err)
+	}
+	if b.Bounds().Size() == a.Bounds().Size() {
+		t.Error("Expected the images not to be resized without resize")
+	}
+
+	a, b, err = LoadComparisonImages(pathA, pathB, true)
+	if err != nil {
+		t.Fatalf("LoadComparisonImages failed: %v", err)
+	}
+	if b.Bounds().Size() != a.Bounds().Size() {
+		t.Errorf("Expected b to be resized to %v, got %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+
+	if _, _, err := LoadComparisonImages(pathA, filepath.Join(tempDir, "missing.png"), true); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestCompareImages_EncodedCopy(t *testing.T) {
+	// エンコードによる劣化は小さい差として計測される
+	src := createTestImage(64, 64)
+	path := filepath.Join(t.TempDir(), "copy.jpg")
+	if err := NewImageSaver().Save(src, path, types.FormatJPEG, 90); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	decoded, err := NewImageLoader().Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	result, err := CompareImages(src, decoded)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	if result.Identical() || result.PSNR < 25 || result.SSIM < 0.8 {
+		t.Errorf("Unexpected comparison for a JPEG copy: %+v", result)
+	}
+}
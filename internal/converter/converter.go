@@ -1,13 +1,27 @@
 package converter
 
 import (
+	"errors"
 	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 
 	"image-converter/internal/types"
 )
 
+// ErrDecode は入力画像の読み込み（デコード）に失敗したことを表します
+var ErrDecode = errors.New("failed to load image")
+
+// ErrPanic は変換中にpanicが発生したことを表します（細工された入力によるデコーダーの不具合など）
+var ErrPanic = errors.New("panic during conversion")
+
+// ErrOutputCollision は複数の入力またはバリエーションが同じ出力パスに書き込む予定であることを表します
+var ErrOutputCollision = errors.New("output path collides with another output")
+
 // Converter は画像変換処理を統合します
 type Converter struct {
 	config          types.Config
@@ -17,82 +31,433 @@ type Converter struct {
 	resizer         *ResizeCalculator
 	saver           *ImageSaver
 	formatDetector  *FormatDetector
+	cache           *ResultCache // 変換結果のキャッシュ（nilの場合はキャッシュしない）
+	outputs         map[string][]string // 入力ごとの変換済みの出力（statsMutexで保護、監視モードの削除に使用）
 }
 
 // NewConverter は新しいConverterを作成します
 func NewConverter(config types.Config) *Converter {
+	return NewConverterWithCache(config, nil)
+}
+
+// NewConverterWithCache は変換結果をcacheに保存・再利用するConverterを作成します
+// cacheがnilの場合はNewConverterと同じです
+func NewConverterWithCache(config types.Config, cache *ResultCache) *Converter {
 	return &Converter{
 		config:         config,
 		stats:          types.ConversionStats{},
 		loader:         NewImageLoader(),
 		resizer:        NewResizeCalculator(),
-		saver:          NewImageSaver(),
+		saver:          NewImageSaverWithOptions(EncodeOptionsFromConfig(config)),
 		formatDetector: NewFormatDetector(),
+		cache:          cache,
+	}
+}
+
+// EncodeOptionsFromConfig はCLI設定からエンコーダー設定を作成します
+func EncodeOptionsFromConfig(config types.Config) types.EncodeOptions {
+	return types.EncodeOptions{
+		Colors:          config.Colors,
+		Dither:          types.DitherMode(config.Dither),
+		PNGPalette:      config.PNGPalette,
+		PNGMaxError:     config.PNGMaxError,
+		JPEGProgressive: config.JPEGProgressive,
+		JPEGSubsampling: types.ChromaSubsampling(config.JPEGSubsampling),
 	}
 }
 
 // ConvertImage は単一の画像ファイルを変換します
 // 変換処理のフロー:
-// 1. 画像の読み込み
-// 2. リサイズ仕様の適用
-// 3. 出力フォーマットの決定
+// 1. 出力フォーマットの決定
+// 2. 画像の読み込み（アニメーションGIFは全フレーム）
+// 3. リサイズ仕様の適用
 // 4. 画像の保存
-func (c *Converter) ConvertImage(sourcePath, outputDir string) types.ConversionResult {
+// 規則（Config.Rules）に一致した場合は、その規則を適用した設定で変換します
+// 変換中にpanicが発生した場合は、ErrPanicをラップしたエラーを持つ失敗した結果を返します
+func (c *Converter) ConvertImage(sourcePath, outputDir string) (result types.ConversionResult) {
+	defer recoverConversion(&result, sourcePath)
+
+	rule, err := c.MatchRule(sourcePath)
+	if err != nil {
+		return types.ConversionResult{SourcePath: sourcePath, Error: err}
+	}
+	if rule != nil {
+		result := c.forRule(rule).convertImage(sourcePath, outputDir)
+		result.Rule = rule.Name
+		return result
+	}
+	return c.convertImage(sourcePath, outputDir)
+}
+
+// convertImage は規則を評価せずに単一の画像ファイルを変換します
+func (c *Converter) convertImage(sourcePath, outputDir string) types.ConversionResult {
+	// 1. 出力フォーマットの決定
+	outputFormat, err := c.resolveOutputFormat(sourcePath, c.config.Format)
+	if err != nil {
+		return types.ConversionResult{SourcePath: sourcePath, Error: err}
+	}
+
+	// 出力パスの生成
+	outputPath := c.formatDetector.GenerateOutputPath(sourcePath, outputDir, outputFormat)
+
+	return c.convertImageTo(sourcePath, outputPath, outputFormat)
+}
+
+// ConvertImageTo は単一の画像ファイルを指定された出力パスに変換します
+// 出力フォーマットは出力パスの拡張子から決定し、判定できない場合はConvertImageと同じ規則に従います
+func (c *Converter) ConvertImageTo(sourcePath, outputPath string) (result types.ConversionResult) {
+	defer recoverConversion(&result, sourcePath)
+
+	rule, err := c.MatchRule(sourcePath)
+	if err != nil {
+		return types.ConversionResult{SourcePath: sourcePath, OutputPath: outputPath, Error: err}
+	}
+
+	converter := c
+	if rule != nil {
+		converter = c.forRule(rule)
+	}
+
+	outputFormat, err := c.formatDetector.DetectFormat(outputPath)
+	if err != nil {
+		outputFormat, err = converter.resolveOutputFormat(sourcePath, converter.config.Format)
+		if err != nil {
+			return types.ConversionResult{SourcePath: sourcePath, OutputPath: outputPath, Error: err}
+		}
+	}
+
+	result = converter.convertImageTo(sourcePath, outputPath, outputFormat)
+	if rule != nil {
+		result.Rule = rule.Name
+	}
+	return result
+}
+
+// convertImageTo は出力パスとフォーマットを指定して画像を変換します
+func (c *Converter) convertImageTo(sourcePath, outputPath string, outputFormat types.ImageFormat) types.ConversionResult {
 	result := types.ConversionResult{
 		SourcePath: sourcePath,
+		OutputPath: outputPath,
 		Success:    false,
 	}
 
-	// 1. 画像の読み込み
-	img, err := c.loader.Load(sourcePath)
+	// リサイズ仕様の作成
+	resizeSpec := c.resizeSpec()
+	preserveAnimation := c.shouldPreserveAnimation(sourcePath, outputFormat)
+
+	// キャッシュに同じ入力・設定の出力があれば読み込みと変換を省略
+	var key string
+	if hash := c.sourceHash(sourcePath); hash != "" {
+		key = c.cacheKey(hash, resizeSpec, outputFormat, c.quality(0), preserveAnimation)
+		if c.restoreCached(&result, key) {
+			return result
+		}
+	}
+
+	// 2. 画像の読み込み
+	out, err := c.loadOutputImage(sourcePath, preserveAnimation)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to load image: %w", err)
+		result.Error = fmt.Errorf("%w: %w", ErrDecode, err)
 		return result
 	}
 
-	// 2. リサイズ仕様の作成と適用
-	resizeSpec := types.ResizeSpec{
+	// 3. リサイズと 4. 保存
+	c.writeOutput(&result, out, resizeSpec, outputFormat, c.quality(0))
+	c.storeCached(&result, key)
+	return result
+}
+
+// ConvertImageVariants は画像を1回だけ読み込み、設定されたすべてのバリエーションを出力します
+// 各バリエーションは個別のConversionResultとして返されます
+// バリエーションが設定されていない場合はConvertImageの結果を1つ返します
+// 規則に一致した場合は、その規則を適用した設定を各バリエーションの基本設定とします
+func (c *Converter) ConvertImageVariants(sourcePath, outputDir string) (results []types.ConversionResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			results = []types.ConversionResult{{SourcePath: sourcePath, Error: panicError(r)}}
+		}
+	}()
+
+	rule, err := c.MatchRule(sourcePath)
+	if err != nil {
+		return []types.ConversionResult{{SourcePath: sourcePath, Error: err}}
+	}
+	if rule != nil {
+		results := c.forRule(rule).convertImageVariants(sourcePath, outputDir)
+		for i := range results {
+			results[i].Rule = rule.Name
+		}
+		return results
+	}
+	return c.convertImageVariants(sourcePath, outputDir)
+}
+
+// recoverConversion は変換中のpanicを回復し、失敗した変換結果としてresultに記録します
+// 1つの入力でのpanicが並行処理中の他のファイルの変換を中断しないように、公開する変換関数でdeferします
+func recoverConversion(result *types.ConversionResult, sourcePath string) {
+	if r := recover(); r != nil {
+		*result = types.ConversionResult{SourcePath: sourcePath, OutputPath: result.OutputPath, Error: panicError(r)}
+	}
+}
+
+// panicError はrecoverした値をErrPanicをラップしたエラーに変換します
+func panicError(recovered any) error {
+	return fmt.Errorf("%w: %v", ErrPanic, recovered)
+}
+
+// convertImageVariants は規則を評価せずにすべてのバリエーションを出力します
+func (c *Converter) convertImageVariants(sourcePath, outputDir string) []types.ConversionResult {
+	if len(c.config.Variants) == 0 {
+		return []types.ConversionResult{c.convertImage(sourcePath, outputDir)}
+	}
+
+	results := make([]types.ConversionResult, len(c.config.Variants))
+	formats := make([]types.ImageFormat, len(c.config.Variants))
+	preserveAnimation := false
+	resolved := true
+
+	// 各バリエーションの出力フォーマットとパスを決定
+	for i, variant := range c.config.Variants {
+		results[i] = types.ConversionResult{SourcePath: sourcePath, Variant: variant.Suffix}
+
+		format := variant.Format
+		if format == "" {
+			format = c.config.Format
+		}
+		outputFormat, err := c.resolveOutputFormat(sourcePath, format)
+		if err != nil {
+			results[i].Error = err
+			resolved = false
+			continue
+		}
+		formats[i] = outputFormat
+		results[i].OutputPath = c.formatDetector.GenerateVariantOutputPath(sourcePath, outputDir, variant.Suffix, outputFormat)
+
+		if c.shouldPreserveAnimation(sourcePath, outputFormat) {
+			preserveAnimation = true
+		}
+	}
+	if !resolved {
+		return results
+	}
+
+	// 規則でフォーマットが変わる場合など、同じ出力パスに書き込むバリエーションは先の出力を上書きしないよう失敗とする
+	written := make(map[string]int)
+	for i := range results {
+		key := filepath.Clean(results[i].OutputPath)
+		if j, ok := written[key]; ok {
+			results[i].Error = fmt.Errorf("%w: %s is also written by variant %q", ErrOutputCollision, results[i].OutputPath, results[j].Variant)
+			continue
+		}
+		written[key] = i
+	}
+
+	// 各バリエーションのリサイズ仕様を決定し、キャッシュに出力があるものは復元
+	specs := make([]types.ResizeSpec, len(c.config.Variants))
+	keys := make([]string, len(c.config.Variants))
+	hash := c.sourceHash(sourcePath)
+	pending := 0
+	for i, variant := range c.config.Variants {
+		specs[i] = c.variantResizeSpec(variant)
+		if results[i].Error != nil {
+			continue
+		}
+
+		if hash != "" {
+			variantAnimation := preserveAnimation && SupportsAnimation(formats[i])
+			keys[i] = c.cacheKey(hash, specs[i], formats[i], c.quality(variant.Quality), variantAnimation)
+			if c.restoreCached(&results[i], keys[i]) {
+				continue
+			}
+		}
+		pending++
+	}
+	if pending == 0 {
+		return results
+	}
+
+	// 画像の読み込み（キャッシュにないすべてのバリエーションで共有）
+	out, err := c.loadOutputImage(sourcePath, preserveAnimation)
+	if err != nil {
+		for i := range results {
+			if results[i].Cache != types.CacheHit && results[i].Error == nil {
+				results[i].Error = fmt.Errorf("%w: %w", ErrDecode, err)
+			}
+		}
+		return results
+	}
+
+	for i, variant := range c.config.Variants {
+		if results[i].Cache == types.CacheHit || results[i].Error != nil {
+			continue
+		}
+
+		variantOut := out
+		if out.anim != nil && !SupportsAnimation(formats[i]) {
+			// アニメーション非対応のフォーマットには最初のフレームを使用
+			variantOut = outputImage{still: out.anim.Frames[0]}
+		}
+
+		c.writeOutput(&results[i], variantOut, specs[i], formats[i], c.quality(variant.Quality))
+		c.storeCached(&results[i], keys[i])
+	}
+
+	return results
+}
+
+// resolveOutputFormat は出力フォーマットを決定します
+// formatが空の場合は元画像と同じフォーマットを使用します
+func (c *Converter) resolveOutputFormat(sourcePath, format string) (types.ImageFormat, error) {
+	if format != "" {
+		// ユーザーが指定したフォーマットを使用
+		return c.formatDetector.NormalizeFormat(format), nil
+	}
+
+	// 元画像と同じフォーマットを使用
+	detectedFormat, err := c.formatDetector.DetectFormat(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect format: %w", err)
+	}
+	return detectedFormat, nil
+}
+
+// resizeSpec は基本設定（-scale/-width/-height/-fit）のリサイズ仕様を返します
+func (c *Converter) resizeSpec() types.ResizeSpec {
+	return types.ResizeSpec{
 		Scale:  c.config.Scale,
 		Width:  c.config.Width,
 		Height: c.config.Height,
+		Fit:    types.FitMode(c.config.Fit),
 	}
-	
-	resizedImg := c.resizer.ResizeImage(img, resizeSpec)
+}
 
-	// 3. 出力フォーマットの決定
-	var outputFormat types.ImageFormat
-	if c.config.Format != "" {
-		// ユーザーが指定したフォーマットを使用
-		outputFormat = c.formatDetector.NormalizeFormat(c.config.Format)
+// variantResizeSpec はバリエーションのリサイズ仕様を返します（未指定の場合は基本設定）
+func (c *Converter) variantResizeSpec(variant types.Variant) types.ResizeSpec {
+	spec := variant.Resize
+	if spec == (types.ResizeSpec{}) {
+		return c.resizeSpec()
+	}
+	if spec.Fit == "" {
+		spec.Fit = types.FitMode(c.config.Fit)
+	}
+	return spec
+}
+
+// quality は品質を決定します（0の場合は設定値、設定もない場合はデフォルト品質）
+func (c *Converter) quality(override int) int {
+	if override > 0 {
+		return override
+	}
+	if c.config.JPEGQuality > 0 {
+		return c.config.JPEGQuality
+	}
+	return 85 // デフォルト品質
+}
+
+// writeOutput は画像をリサイズして保存し、結果をresultに記録します
+func (c *Converter) writeOutput(result *types.ConversionResult, out outputImage, resizeSpec types.ResizeSpec, outputFormat types.ImageFormat, quality int) {
+	// 失敗した場合に空や途中までの出力を残さないよう、一時ファイルに書き込んでから置き換える
+	err := WriteFileAtomic(result.OutputPath, func(w io.Writer) error {
+		return c.encodeOutput(w, result, out, resizeSpec, outputFormat, quality)
+	})
+	if err != nil {
+		result.Error = err
+		return
+	}
+
+	// 成功
+	result.Success = true
+}
+
+// encodeOutput は画像をリサイズしてwに書き込み、出力の情報をresultに記録します
+func (c *Converter) encodeOutput(w io.Writer, result *types.ConversionResult, out outputImage, resizeSpec types.ResizeSpec, outputFormat types.ImageFormat, quality int) error {
+	// リサイズ仕様の適用
+	if out.anim != nil {
+		out.anim = c.resizer.ResizeAnimation(out.anim, resizeSpec)
 	} else {
-		// 元画像と同じフォーマットを使用
-		detectedFormat, err := c.formatDetector.DetectFormat(sourcePath)
+		out.still = c.resizer.ResizeImage(out.still, resizeSpec)
+	}
+
+	bounds := out.bounds()
+	counter := &countingWriter{w: w}
+
+	// 画像の保存
+	if c.config.TargetSSIM > 0 && out.anim != nil {
+		// 探索は静止画のみに対応するため、アニメーションのまま出力する場合は黙って通常の保存に切り替えずエラーにする
+		return ErrTargetSSIMAnimation
+	} else if c.config.TargetSSIM > 0 {
+		// 知覚品質目標モード: 候補をデコードしてSSIMが目標を満たす最小の出力を選ぶ
+		perceptual, err := encodeForTargetSSIM(c.saver, out.still, outputFormat, c.config.TargetSSIM)
 		if err != nil {
-			result.Error = fmt.Errorf("failed to detect format: %w", err)
-			return result
+			return fmt.Errorf("failed to encode for target SSIM: %w", err)
+		}
+		if _, err := counter.Write(perceptual.Data); err != nil {
+			return fmt.Errorf("failed to save image: %w", err)
+		}
+		result.Quality = perceptual.Quality
+		result.SSIM = perceptual.SSIM
+	} else if c.config.MaxBytes > 0 {
+		// 容量制限モード: メモリ上で品質を探索してから書き込む
+		budget, err := encodeWithinBudget(c.saver, c.resizer, out, outputFormat, quality, c.config.MaxBytes, c.config.AllowDownscale)
+		if err != nil {
+			return fmt.Errorf("failed to encode within budget: %w", err)
+		}
+		if _, err := counter.Write(budget.Data); err != nil {
+			return fmt.Errorf("failed to save image: %w", err)
+		}
+		result.Quality = budget.Quality
+		// 追加縮小された場合に備えて実際の寸法を記録
+		bounds = image.Rect(0, 0, budget.Width, budget.Height)
+	} else {
+		if err := out.encode(c.saver, counter, outputFormat, quality); err != nil {
+			return fmt.Errorf("failed to save image: %w", err)
 		}
-		outputFormat = detectedFormat
 	}
 
-	// 4. 出力パスの生成
-	outputPath := c.formatDetector.GenerateOutputPath(sourcePath, outputDir, outputFormat)
-	result.OutputPath = outputPath
+	// 出力の情報を記録
+	result.Format = outputFormat
+	result.Width = bounds.Dx()
+	result.Height = bounds.Dy()
+	result.Bytes = counter.n
+	return nil
+}
 
-	// 5. 画像の保存
-	quality := c.config.JPEGQuality
-	if quality == 0 {
-		quality = 85 // デフォルト品質
+// countingWriter は書き込まれたバイト数を数えるio.Writerです
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// loadOutputImage は画像を読み込みます
+// preserveAnimationが有効な場合は全フレームを読み込み、1フレームのみなら静止画として扱います
+func (c *Converter) loadOutputImage(sourcePath string, preserveAnimation bool) (outputImage, error) {
+	file, err := os.Open(sourcePath)
+	if err != nil {
+		return outputImage{}, fmt.Errorf("failed to open file: %w", err)
 	}
+	defer file.Close()
 
-	err = c.saver.Save(resizedImg, outputPath, outputFormat, quality)
+	return c.decodeOutputImage(file, preserveAnimation)
+}
+
+// shouldPreserveAnimation はアニメーションを保持して変換すべきかを判定します
+func (c *Converter) shouldPreserveAnimation(sourcePath string, outputFormat types.ImageFormat) bool {
+	if c.config.FirstFrameOnly || !SupportsAnimation(outputFormat) {
+		return false
+	}
+
+	sourceFormat, err := c.formatDetector.DetectFormat(sourcePath)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to save image: %w", err)
-		return result
+		return false
 	}
 
-	// 成功
-	result.Success = true
-	return result
+	return sourceFormat == types.FormatGIF
 }
 
 // GetStats は現在の統計情報を返します
@@ -111,6 +476,12 @@ func (c *Converter) UpdateStats(result types.ConversionResult) {
 	} else {
 		c.stats.Failed++
 	}
+	switch result.Cache {
+	case types.CacheHit:
+		c.stats.CacheHits++
+	case types.CacheMiss:
+		c.stats.CacheMisses++
+	}
 }
 
 // IncrementSkipped はスキップされたファイル数を増やします（スレッドセーフ）
@@ -148,6 +519,32 @@ func (c *Converter) ProcessDirectory(inputDir, outputDir string, fsManager FileS
 		}
 	}
 
+	return c.processFiles(imageFiles, outputDir)
+}
+
+// ProcessFiles は指定された画像ファイルを並行処理し、outputDirに出力します
+// サポートされていない拡張子のファイルはスキップとして数えます
+func (c *Converter) ProcessFiles(files []string, outputDir string) error {
+	imageFiles := []string{}
+	for _, file := range files {
+		if _, err := c.formatDetector.DetectFormat(file); err != nil {
+			c.IncrementSkipped()
+			continue
+		}
+		imageFiles = append(imageFiles, file)
+	}
+
+	return c.processFiles(imageFiles, outputDir)
+}
+
+// processFiles は画像ファイルを並行処理し、要約を表示してマニフェストを書き込みます
+// Config.DryRunが有効な場合は変換せずに変換計画を表示します
+func (c *Converter) processFiles(imageFiles []string, outputDir string) error {
+	if c.config.DryRun {
+		// 変換せずに計画のみを表示
+		return c.printPlan(imageFiles, outputDir)
+	}
+
 	// 要件6.1: 処理開始時の総ファイル数表示
 	fmt.Printf("Processing %d images...\n", len(imageFiles))
 
@@ -158,6 +555,10 @@ func (c *Converter) ProcessDirectory(inputDir, outputDir string, fsManager FileS
 	var wg sync.WaitGroup
 	var progressMutex sync.Mutex // 進行状況表示の保護
 	processedCount := 0
+	var allResults []types.ConversionResult // マニフェスト用に全結果を保持（progressMutexで保護）
+
+	// 出力パスが衝突する入力は、後から書き込んだ方で上書きされないよう変換せずに失敗とする
+	collisions := c.outputCollisions(imageFiles, outputDir)
 
 	// 各画像ファイルを並行処理
 	for _, file := range imageFiles {
@@ -177,15 +578,31 @@ func (c *Converter) ProcessDirectory(inputDir, outputDir string, fsManager FileS
 			progressMutex.Unlock()
 
 			// 画像の変換
-			result := c.ConvertImage(f, outputDir)
-			c.UpdateStats(result)
+			var results []types.ConversionResult
+			if err, ok := collisions[f]; ok {
+				results = []types.ConversionResult{{SourcePath: f, Error: err}}
+			} else {
+				results = c.ConvertImageVariants(f, outputDir)
+			}
+			var firstErr error
+			for _, result := range results {
+				c.UpdateStats(result)
+				if !result.Success && firstErr == nil {
+					firstErr = result.Error
+				}
+			}
 
 			// 結果の表示（スレッドセーフ）
 			progressMutex.Lock()
-			if result.Success {
-				fmt.Printf("OK\n")
+			allResults = append(allResults, results...)
+			if firstErr == nil {
+				if len(results) > 1 {
+					fmt.Printf("OK (%d variants)\n", len(results))
+				} else {
+					fmt.Printf("OK\n")
+				}
 			} else {
-				fmt.Printf("FAILED (%v)\n", result.Error)
+				fmt.Printf("FAILED (%v)\n", firstErr)
 			}
 			progressMutex.Unlock()
 		}(file)
@@ -193,6 +610,7 @@ func (c *Converter) ProcessDirectory(inputDir, outputDir string, fsManager FileS
 
 	// すべてのゴルーチンの完了を待機
 	wg.Wait()
+	c.recordOutputs(allResults)
 
 	// 要件6.5: 処理完了時の要約表示
 	fmt.Printf("\nSummary:\n")
@@ -200,6 +618,21 @@ func (c *Converter) ProcessDirectory(inputDir, outputDir string, fsManager FileS
 	fmt.Printf("  Success: %d\n", c.stats.Success)
 	fmt.Printf("  Failed: %d\n", c.stats.Failed)
 	fmt.Printf("  Skipped: %d\n", c.stats.Skipped)
+	if c.cache != nil {
+		fmt.Printf("  Cache: %d hits, %d misses\n", c.stats.CacheHits, c.stats.CacheMisses)
+	}
+
+	// マニフェストの出力
+	if err := c.WriteManifests(allResults); err != nil {
+		return err
+	}
+
+	// 孤立した出力の削除
+	if c.config.Sync {
+		if err := c.SyncOutputs(imageFiles, allResults, outputDir); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
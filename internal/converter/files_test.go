@@ -0,0 +1,126 @@
+package converter
+
+import (
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestConverter_ConvertImageTo(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "photo.png")
+	saveTestImage(t, inputPath, createTestImage(100, 50))
+
+	tests := []struct {
+		name       string
+		format     string
+		outputName string
+		expected   string
+	}{
+		{"拡張子から決定", "", "out.webp", "webp"},
+		{"拡張子なしは-formatに従う", "jpeg", "out", "jpeg"},
+		{"拡張子なしで-formatもない場合は元のフォーマット", "", "same", "png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath := filepath.Join(tempDir, tt.outputName)
+			config := types.Config{Format: tt.format, Width: 40, JPEGQuality: 85}
+
+			result := NewConverter(config).ConvertImageTo(inputPath, outputPath)
+			if !result.Success {
+				t.Fatalf("ConvertImageTo failed: %v", result.Error)
+			}
+			if result.OutputPath != outputPath || result.Width != 40 {
+				t.Errorf("Unexpected result: %+v", result)
+			}
+
+			file, err := os.Open(outputPath)
+			if err != nil {
+				t.Fatalf("Failed to open output: %v", err)
+			}
+			defer file.Close()
+			if _, format, err := image.DecodeConfig(file); err != nil || format != tt.expected {
+				t.Errorf("format = %s (err=%v), want %s", format, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConverter_ProcessFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	first := filepath.Join(tempDir, "first.png")
+	second := filepath.Join(tempDir, "nested", "second.png")
+	if err := os.MkdirAll(filepath.Dir(second), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	saveTestImage(t, first, createTestImage(20, 20))
+	saveTestImage(t, second, createTestImage(20, 20))
+
+	files := []string{first, second, filepath.Join(tempDir, "notes.txt"), filepath.Join(tempDir, "missing.png")}
+
+	converter := NewConverter(types.Config{Format: "jpeg", JPEGQuality: 85})
+	if err := converter.ProcessFiles(files, outputDir); err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+
+	stats := converter.GetStats()
+	if stats.Total != 4 || stats.Success != 2 || stats.Failed != 1 || stats.Skipped != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	for _, name := range []string{"first.jpg", "second.jpg"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Output %s not created: %v", name, err)
+		}
+	}
+}
+
+func TestConverter_ProcessFiles_OutputCollision(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	// 異なるディレクトリの同じ名前の入力は、どちらも output/x.jpg に書き込む予定になる
+	var files []string
+	for _, path := range []string{filepath.Join("d1", "x.png"), filepath.Join("d2", "x.png"), "unique.png"} {
+		path = filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		saveTestImage(t, path, createTestImage(20, 20))
+		files = append(files, path)
+	}
+
+	converter := NewConverter(types.Config{Format: "jpeg", JPEGQuality: 85})
+	if err := converter.ProcessFiles(files, outputDir); err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+
+	stats := converter.GetStats()
+	if stats.Success != 1 || stats.Failed != 2 {
+		t.Errorf("Expected both colliding inputs to fail, got %+v", stats)
+	}
+	assertExists(t, filepath.Join(outputDir, "x.jpg"), false)
+	assertExists(t, filepath.Join(outputDir, "unique.jpg"), true)
+
+	collisions := converter.outputCollisions(files, outputDir)
+	for _, file := range files[:2] {
+		if !errors.Is(collisions[file], ErrOutputCollision) {
+			t.Errorf("Expected ErrOutputCollision for %s, got %v", file, collisions[file])
+		}
+	}
+	if _, ok := collisions[files[2]]; ok {
+		t.Errorf("Unexpected collision for %s", files[2])
+	}
+}
@@ -1,6 +1,8 @@
 package converter
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -36,6 +38,31 @@ func (fd *FormatDetector) DetectFormat(path string) (types.ImageFormat, error) {
 	}
 }
 
+// ErrUnrecognizedFormat は内容から画像フォーマットを判定できなかったことを表します
+var ErrUnrecognizedFormat = errors.New("unrecognized image data")
+
+// SniffHeaderSize はSniffFormatでフォーマットの判定に必要な先頭のバイト数です
+const SniffHeaderSize = 12
+
+// SniffFormat はファイル先頭のマジックナンバーから画像フォーマットを判定します
+// headerには先頭のSniffHeaderSizeバイト（短いデータの場合はその全体）を渡します
+func SniffFormat(header []byte) (types.ImageFormat, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte{0xFF, 0xD8, 0xFF}):
+		return types.FormatJPEG, nil
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return types.FormatPNG, nil
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return types.FormatGIF, nil
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return types.FormatWebP, nil
+	case bytes.HasPrefix(header, []byte("BM")):
+		return types.FormatBMP, nil
+	default:
+		return "", ErrUnrecognizedFormat
+	}
+}
+
 // IsFormatSupported はフォーマットがサポートされているかチェックします
 func (fd *FormatDetector) IsFormatSupported(format string) bool {
 	normalizedFormat := strings.ToLower(format)
@@ -67,29 +94,36 @@ func (fd *FormatDetector) NormalizeFormat(format string) types.ImageFormat {
 // GenerateOutputFilename は出力ファイル名を生成します
 // ベース名を保持し、拡張子を出力フォーマットに変更します
 func (fd *FormatDetector) GenerateOutputFilename(inputPath string, outputFormat types.ImageFormat) string {
+	return fd.GenerateVariantFilename(inputPath, "", outputFormat)
+}
+
+// GenerateVariantFilename はバリエーション用の出力ファイル名を生成します
+// ベース名の後に接尾辞を付加し、拡張子を出力フォーマットに変更します（例: photo-sm.webp）
+func (fd *FormatDetector) GenerateVariantFilename(inputPath, suffix string, outputFormat types.ImageFormat) string {
 	// ファイル名からベース名（拡張子なし）を取得
 	baseName := filepath.Base(inputPath)
 	ext := filepath.Ext(baseName)
 	baseNameWithoutExt := strings.TrimSuffix(baseName, ext)
 	
-	// 新しい拡張子を追加
-	var newExt string
+	return baseNameWithoutExt + suffix + fd.Extension(outputFormat)
+}
+
+// Extension は出力フォーマットに対応する拡張子を返します
+func (fd *FormatDetector) Extension(outputFormat types.ImageFormat) string {
 	switch outputFormat {
 	case types.FormatJPEG:
-		newExt = ".jpg"
+		return ".jpg"
 	case types.FormatPNG:
-		newExt = ".png"
+		return ".png"
 	case types.FormatWebP:
-		newExt = ".webp"
+		return ".webp"
 	case types.FormatGIF:
-		newExt = ".gif"
+		return ".gif"
 	case types.FormatBMP:
-		newExt = ".bmp"
+		return ".bmp"
 	default:
-		newExt = ".jpg" // デフォルト
+		return ".jpg" // デフォルト
 	}
-	
-	return baseNameWithoutExt + newExt
 }
 
 // GenerateOutputPath は完全な出力パスを生成します
@@ -97,3 +131,8 @@ func (fd *FormatDetector) GenerateOutputPath(inputPath, outputDir string, output
 	outputFilename := fd.GenerateOutputFilename(inputPath, outputFormat)
 	return filepath.Join(outputDir, outputFilename)
 }
+
+// GenerateVariantOutputPath はバリエーション用の完全な出力パスを生成します
+func (fd *FormatDetector) GenerateVariantOutputPath(inputPath, outputDir, suffix string, outputFormat types.ImageFormat) string {
+	return filepath.Join(outputDir, fd.GenerateVariantFilename(inputPath, suffix, outputFormat))
+}
@@ -245,3 +245,57 @@ func TestGenerateOutputPath(t *testing.T) {
 		})
 	}
 }
+
+// ユニットテスト: バリエーションのファイル名生成
+func TestGenerateVariantFilename(t *testing.T) {
+	fd := NewFormatDetector()
+
+	tests := []struct {
+		inputPath string
+		suffix    string
+		format    types.ImageFormat
+		expected  string
+	}{
+		{"/input/image.jpg", "-sm", types.FormatWebP, "image-sm.webp"},
+		{"/input/image.png", "@2x", types.FormatPNG, "image@2x.png"},
+		{"/input/image.png", "", types.FormatJPEG, "image.jpg"},
+	}
+
+	for _, tt := range tests {
+		result := fd.GenerateVariantFilename(tt.inputPath, tt.suffix, tt.format)
+		if result != tt.expected {
+			t.Errorf("GenerateVariantFilename(%q, %q) = %v, want %v", tt.inputPath, tt.suffix, result, tt.expected)
+		}
+	}
+}
+
+// ユニットテスト: マジックナンバーによるフォーマット判定
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   []byte
+		expected types.ImageFormat
+		wantErr  bool
+	}{
+		{"JPEG", []byte{0xFF, 0xD8, 0xFF, 0xE0}, types.FormatJPEG, false},
+		{"PNG", []byte("\x89PNG\r\n\x1a\n\x00\x00"), types.FormatPNG, false},
+		{"GIF", []byte("GIF89a"), types.FormatGIF, false},
+		{"WebP", []byte("RIFF\x00\x00\x00\x00WEBP"), types.FormatWebP, false},
+		{"BMP", []byte("BM\x00\x00"), types.FormatBMP, false},
+		{"RIFFだがWebPではない", []byte("RIFF\x00\x00\x00\x00WAVE"), "", true},
+		{"短すぎる", []byte{0xFF}, "", true},
+		{"空", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := SniffFormat(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SniffFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if format != tt.expected {
+				t.Errorf("SniffFormat() = %v, want %v", format, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// fuzzMaxPixels はファジングで全体をデコードする画像の画素数の上限です
+// 細工されたヘッダーの巨大なサイズによるメモリ不足はpanicではないため、対象外とします
+const fuzzMaxPixels = 1 << 20
+
+// addImageSeeds はすべての対応フォーマットのエンコード済み画像と、その切り詰めたものをシードとして追加します
+func addImageSeeds(f *testing.F) {
+	f.Helper()
+	img := createTestImage(16, 12)
+	saver := NewImageSaver()
+	for _, format := range []types.ImageFormat{types.FormatJPEG, types.FormatPNG, types.FormatWebP, types.FormatGIF, types.FormatBMP} {
+		var buf bytes.Buffer
+		if err := saver.Encode(&buf, img, format, 80); err != nil {
+			f.Fatalf("Failed to encode %s seed: %v", format, err)
+		}
+		data := buf.Bytes()
+		f.Add(data)
+		f.Add(data[:len(data)/2])
+		f.Add(data[:SniffHeaderSize])
+	}
+
+	var anim bytes.Buffer
+	animated := &AnimatedImage{Frames: []image.Image{img, img}, Delays: []int{10, 10}}
+	if err := saver.EncodeAnimation(&anim, animated, types.FormatGIF, 80); err != nil {
+		f.Fatalf("Failed to encode animated GIF seed: %v", err)
+	}
+	f.Add(anim.Bytes())
+	anim.Reset()
+	if err := encodeAnimatedWebP(&anim, animated, 80); err != nil {
+		f.Fatalf("Failed to encode animated WebP seed: %v", err)
+	}
+	f.Add(anim.Bytes())
+
+	f.Add([]byte{})
+	f.Add([]byte("not an image"))
+}
+
+// decodableSize はヘッダーのサイズが全体をデコードできる範囲に収まるかを返します
+// テスト用に登録したpanicするフォーマット（recover_test.go）の入力も対象外とします
+func decodableSize(data []byte) bool {
+	if bytes.HasPrefix(data, []byte(panicMagic)) {
+		return false
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	return err != nil || config.Width*config.Height <= fuzzMaxPixels
+}
+
+func FuzzImageLoader_Load(f *testing.F) {
+	addImageSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !decodableSize(data) {
+			t.Skip()
+		}
+		path := filepath.Join(t.TempDir(), "input")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write input: %v", err)
+		}
+
+		loader := NewImageLoader()
+		img, err := loader.Load(path)
+		if err == nil {
+			// 全体をデコードできた画像はヘッダーも読み込める
+			if _, _, err := loader.LoadConfig(path); err != nil {
+				t.Errorf("Load succeeded but LoadConfig failed: %v", err)
+			}
+			if img == nil {
+				t.Error("Load returned a nil image without an error")
+			}
+		}
+		if anim, err := loader.LoadAnimation(path); err == nil && anim.FrameCount() == 0 {
+			t.Error("LoadAnimation returned no frames without an error")
+		}
+	})
+}
+
+func FuzzSniffFormat(f *testing.F) {
+	addImageSeeds(f)
+	detector := NewFormatDetector()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		format, err := SniffFormat(data)
+		if err != nil {
+			if format != "" {
+				t.Errorf("Expected an empty format with an error, got %q", format)
+			}
+			return
+		}
+		if !detector.IsFormatSupported(string(format)) {
+			t.Errorf("SniffFormat returned an unsupported format %q", format)
+		}
+		// 判定には先頭のSniffHeaderSizeバイトのみを使用する
+		if len(data) > SniffHeaderSize {
+			if again, _ := SniffFormat(data[:SniffHeaderSize]); again != format {
+				t.Errorf("SniffFormat of the header = %q, want %q", again, format)
+			}
+		}
+	})
+}
+
+func FuzzFormatDetector_DetectFormat(f *testing.F) {
+	for _, path := range []string{"photo.jpg", "photo.JPEG", "a/b.png", "anim.gif", "x.webp", "y.bmp", "noext", ".png", "dir.png/file", "", "a.", "a.png.txt", "\x00.jpg"} {
+		f.Add(path)
+	}
+	detector := NewFormatDetector()
+	f.Fuzz(func(t *testing.T, path string) {
+		format, err := detector.DetectFormat(path)
+		if err == nil && !detector.IsFormatSupported(string(format)) {
+			t.Errorf("DetectFormat(%q) returned an unsupported format %q", path, format)
+		}
+	})
+}
+
+func FuzzInspectBytes(f *testing.F) {
+	addImageSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !decodableSize(data) {
+			t.Skip()
+		}
+		info, err := NewImageLoader().InspectBytes(data)
+		if err != nil {
+			return
+		}
+		if info.Format == "" || info.Frames < 1 || info.Orientation < 0 || info.Orientation > 8 {
+			t.Errorf("Unexpected info: %+v", info)
+		}
+	})
+}
+
+func FuzzExifOrientation(f *testing.F) {
+	f.Add(exifWithOrientation(binary.LittleEndian, 6))
+	f.Add(exifWithOrientation(binary.BigEndian, 3))
+	f.Add([]byte("MM\x00\x2a\xff\xff\xff\xff"))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if orientation := exifOrientation(data); orientation < 0 || orientation > 8 {
+			t.Errorf("exifOrientation = %d, want 0-8", orientation)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package converter
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// updateGolden はゴールデン画像を現在の出力で再生成します
+// go test ./internal/converter -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "ゴールデン画像を再生成する")
+
+const goldenDir = "testdata/golden"
+
+// goldenFormats は入力・出力の両方で検証するフォーマットです
+var goldenFormats = []types.ImageFormat{types.FormatJPEG, types.FormatPNG, types.FormatWebP, types.FormatGIF, types.FormatBMP}
+
+// goldenResizeModes は検証するリサイズ指定です（フィクスチャは64x40）
+var goldenResizeModes = []struct {
+	name   string
+	config types.Config
+}{
+	{"none", types.Config{}},
+	{"scale", types.Config{Scale: 0.5}},
+	{"width", types.Config{Width: 48}},
+	{"height", types.Config{Height: 24}},
+	{"contain", types.Config{Width: 30, Height: 30, Fit: string(types.FitContain)}},
+	{"cover", types.Config{Width: 30, Height: 30, Fit: string(types.FitCover)}},
+}
+
+// goldenTolerance は出力フォーマットごとのゴールデン画像との許容差です
+// ロスレスの出力はほぼ一致し、非可逆の出力もエンコーダーの版の違いを吸収できる程度の差に収まる必要があります
+var goldenTolerance = map[types.ImageFormat]struct {
+	minPSNR float64
+	minSSIM float64
+}{
+	types.FormatJPEG: {40, 0.99},
+	types.FormatPNG:  {50, 0.999},
+	types.FormatWebP: {40, 0.99},
+	types.FormatGIF:  {40, 0.99},
+	types.FormatBMP:  {50, 0.999},
+}
+
+// createGoldenFixture はゴールデンテスト用の決定的なフィクスチャ画像を生成します
+// グラデーション、斜めの縞、円、細かい市松模様を含み、リサンプラーとエンコーダーの差が出やすい画像です
+func createGoldenFixture() image.Image {
+	const width, height = 64, 40
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: uint8(x * 255 / (width - 1)), G: uint8(y * 255 / (height - 1)), B: 96, A: 255}
+			if (x+y)/6%2 == 0 {
+				c.B = 200
+			}
+			if dx, dy := x-40, y-20; dx*dx+dy*dy <= 12*12 {
+				c = color.RGBA{R: 240, G: 200, B: 40, A: 255}
+			}
+			if x < 16 && y < 16 && (x/2+y/2)%2 == 0 {
+				c = color.RGBA{R: 20, G: 20, B: 20, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// TestGolden は入力フォーマット×出力フォーマット×リサイズ指定のすべての組み合わせの変換結果を
+// testdata/golden のゴールデン画像（デコード後の画素をPNGで保存したもの）と比較します
+func TestGolden(t *testing.T) {
+	fixtureDir := t.TempDir()
+	fixture := createGoldenFixture()
+	saver := NewImageSaver()
+	for _, format := range goldenFormats {
+		path := filepath.Join(fixtureDir, "fixture"+getExtension(format))
+		if err := saver.Save(fixture, path, format, 90); err != nil {
+			t.Fatalf("Failed to save %s fixture: %v", format, err)
+		}
+	}
+
+	if *updateGolden {
+		if err := os.MkdirAll(goldenDir, 0755); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+	}
+
+	for _, input := range goldenFormats {
+		for _, output := range goldenFormats {
+			for _, mode := range goldenResizeModes {
+				name := fmt.Sprintf("%s-to-%s-%s", input, output, mode.name)
+				t.Run(name, func(t *testing.T) {
+					source := filepath.Join(fixtureDir, "fixture"+getExtension(input))
+					outputPath := filepath.Join(t.TempDir(), "output"+getExtension(output))
+
+					result := NewConverter(mode.config).ConvertImageTo(source, outputPath)
+					if result.Error != nil {
+						t.Fatalf("Conversion failed: %v", result.Error)
+					}
+					data, err := os.ReadFile(outputPath)
+					if err != nil {
+						t.Fatalf("Failed to read output: %v", err)
+					}
+					if sniffed, err := SniffFormat(data); err != nil || sniffed != output {
+						t.Fatalf("Expected %s output, got %q (%v)", output, sniffed, err)
+					}
+					actual, err := NewImageLoader().Load(outputPath)
+					if err != nil {
+						t.Fatalf("Failed to load output: %v", err)
+					}
+
+					goldenPath := filepath.Join(goldenDir, name+".png")
+					if *updateGolden {
+						writeGolden(t, goldenPath, actual)
+						return
+					}
+					assertMatchesGolden(t, goldenPath, actual, output)
+				})
+			}
+		}
+	}
+}
+
+// writeGolden はデコード後の画素をPNGでゴールデン画像として保存します
+func writeGolden(t *testing.T, path string, img image.Image) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create golden image: %v", err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		t.Fatalf("Failed to write golden image: %v", err)
+	}
+}
+
+// assertMatchesGolden は画像がゴールデン画像と同じサイズで、許容差の範囲内であることを確認します
+func assertMatchesGolden(t *testing.T, path string, actual image.Image, format types.ImageFormat) {
+	t.Helper()
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Golden image %s is missing (run go test ./internal/converter -run TestGolden -update)", path)
+	}
+	if err != nil {
+		t.Fatalf("Failed to open golden image: %v", err)
+	}
+	defer file.Close()
+	golden, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode golden image: %v", err)
+	}
+
+	if golden.Bounds().Size() != actual.Bounds().Size() {
+		t.Fatalf("Size = %v, golden %v", actual.Bounds().Size(), golden.Bounds().Size())
+	}
+	comparison, err := CompareImages(golden, actual)
+	if err != nil {
+		t.Fatalf("CompareImages failed: %v", err)
+	}
+	tolerance := goldenTolerance[format]
+	if comparison.PSNR < tolerance.minPSNR || comparison.SSIM < tolerance.minSSIM {
+		t.Errorf("Output differs from %s: PSNR %.2f dB (min %.0f), SSIM %.5f (min %.3f), max error %d",
+			path, comparison.PSNR, tolerance.minPSNR, comparison.SSIM, tolerance.minSSIM, comparison.MaxError)
+	}
+}
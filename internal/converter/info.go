@@ -0,0 +1,408 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"math/bits"
+	"os"
+
+	"image-converter/internal/types"
+)
+
+// ImageInfo は画像ファイルの性質を表します（infoサブコマンド）
+type ImageInfo struct {
+	Path            string            `json:"path"`
+	Format          types.ImageFormat `json:"format"`                     // 内容から判定したフォーマット
+	ExtensionFormat types.ImageFormat `json:"extension_format,omitempty"` // 拡張子から判定したフォーマット（判定できない場合は空）
+	Width           int               `json:"width"`
+	Height          int               `json:"height"`
+	ColorModel      string            `json:"color_model"`
+	BitDepth        int               `json:"bit_depth"` // チャネルあたりのビット数（パレット形式はインデックスのビット数）
+	Alpha           bool              `json:"alpha"`
+	Frames          int               `json:"frames"`
+	Orientation     int               `json:"orientation,omitempty"` // EXIFの向き（1-8、記録がない場合は0）
+	ICCProfile      bool              `json:"icc_profile"`           // ICCプロファイルが埋め込まれているか
+	Error           string            `json:"error,omitempty"`
+}
+
+// Inspect は画像ファイルの性質を読み込みます
+// フォーマットは拡張子ではなく内容から判定し、GIFのフレーム数の計数以外では画素データをデコードしません
+func (il *ImageLoader) Inspect(path string) (ImageInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageInfo{Path: path}, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	info, err := il.InspectBytes(data)
+	info.Path = path
+	if err != nil {
+		return info, err
+	}
+	if format, err := NewFormatDetector().DetectFormat(path); err == nil {
+		info.ExtensionFormat = format
+	}
+	return info, nil
+}
+
+// InspectBytes はメモリ上の画像データの性質を読み込みます（Pathは設定しません）
+func (il *ImageLoader) InspectBytes(data []byte) (ImageInfo, error) {
+	var info ImageInfo
+
+	header := data
+	if len(header) > SniffHeaderSize {
+		header = header[:SniffHeaderSize]
+	}
+	format, err := SniffFormat(header)
+	if err != nil {
+		return info, err
+	}
+	info.Format = format
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return info, fmt.Errorf("failed to decode image header: %w", err)
+	}
+	info.Width = config.Width
+	info.Height = config.Height
+	info.ColorModel = colorModelName(config.ColorModel)
+	info.BitDepth = colorModelDepth(config.ColorModel)
+	info.Alpha = colorModelHasAlpha(config.ColorModel)
+	info.Frames = 1
+
+	// フォーマット固有の情報（コンテナのチャンク・マーカーから読み込む）
+	switch format {
+	case types.FormatJPEG:
+		inspectJPEG(data, &info)
+	case types.FormatPNG:
+		inspectPNG(data, &info)
+	case types.FormatWebP:
+		inspectWebP(data, &info)
+	case types.FormatGIF:
+		err = inspectGIF(data, &info)
+	case types.FormatBMP:
+		inspectBMP(data, &info)
+	}
+	return info, err
+}
+
+// inspectJPEG はJPEGのマーカーからビット深度・EXIFの向き・ICCプロファイルを読み込みます
+func inspectJPEG(data []byte, info *ImageInfo) {
+	pos := 2 // SOI
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return
+		}
+		marker := data[pos+1]
+		if marker == 0xFF {
+			// 埋め草
+			pos++
+			continue
+		}
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			// 長さを持たないマーカー
+			pos += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// SOS以降は画像データ
+			return
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			return
+		}
+		segment := data[pos+4 : pos+2+length]
+
+		switch {
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC:
+			// SOF: 先頭が標本の精度
+			if len(segment) > 0 {
+				info.BitDepth = int(segment[0])
+			}
+		case marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			info.Orientation = exifOrientation(segment[6:])
+		case marker == 0xE2 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")):
+			info.ICCProfile = true
+		}
+		pos += 2 + length
+	}
+}
+
+// inspectPNG はPNGのチャンクからビット深度・透過・EXIFの向き・ICCプロファイル・APNGのフレーム数を読み込みます
+func inspectPNG(data []byte, info *ImageInfo) {
+	pos := 8 // シグネチャ
+	for pos+8 <= len(data) {
+		length := binary.BigEndian.Uint32(data[pos : pos+4])
+		if uint64(length) > uint64(len(data)-pos-8) {
+			return
+		}
+		id := string(data[pos+4 : pos+8])
+		chunk := data[pos+8 : pos+8+int(length)]
+
+		switch id {
+		case "IHDR":
+			if len(chunk) >= 10 {
+				info.BitDepth = int(chunk[8])
+				// カラータイプ4（グレースケール+α）と6（RGBA）
+				info.Alpha = chunk[9] == 4 || chunk[9] == 6
+			}
+		case "tRNS":
+			info.Alpha = true
+		case "iCCP":
+			info.ICCProfile = true
+		case "eXIf":
+			info.Orientation = exifOrientation(chunk)
+		case "acTL":
+			if len(chunk) >= 4 {
+				info.Frames = int(binary.BigEndian.Uint32(chunk[0:4]))
+			}
+		case "IEND":
+			return
+		}
+		pos += 12 + int(length) // 長さ・種類・データ・CRC
+	}
+}
+
+// inspectWebP はWebPのチャンクから透過・EXIFの向き・ICCプロファイル・フレーム数を読み込みます
+func inspectWebP(data []byte, info *ImageInfo) {
+	chunks, err := parseWebPChunks(data)
+	if err != nil {
+		return
+	}
+
+	// カラーモデルではなくヘッダーの記録から透過を判定する（ロスレス形式は常にNRGBA）
+	frames := 0
+	info.Alpha = false
+	for _, chunk := range chunks {
+		switch chunk.ID {
+		case chunkVP8X:
+			if len(chunk.Data) > 0 && chunk.Data[0]&vp8xFlagAlpha != 0 {
+				info.Alpha = true
+			}
+		case chunkALPH:
+			info.Alpha = true
+		case chunkVP8L:
+			// ロスレス形式のヘッダー: 署名（1バイト）、幅-1（14ビット）、高さ-1（14ビット）、αの使用（1ビット）
+			if len(chunk.Data) >= 5 && chunk.Data[4]&0x10 != 0 {
+				info.Alpha = true
+			}
+		case "ICCP":
+			info.ICCProfile = true
+		case "EXIF":
+			info.Orientation = exifOrientation(bytes.TrimPrefix(chunk.Data, []byte("Exif\x00\x00")))
+		case chunkANMF:
+			frames++
+		}
+	}
+	if frames > 0 {
+		info.Frames = frames
+	}
+}
+
+// inspectGIF はGIFのフレーム数と、透明色の有無を読み込みます
+func inspectGIF(data []byte, info *ImageInfo) error {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode GIF: %w", err)
+	}
+	info.Frames = len(g.Image)
+
+	// 透明色はフレームごとのパレットに設定される
+	for _, frame := range g.Image {
+		if colorModelHasAlpha(frame.Palette) {
+			info.Alpha = true
+			break
+		}
+	}
+	return nil
+}
+
+// inspectBMP はBMPのヘッダーからビット深度と透過を読み込みます
+func inspectBMP(data []byte, info *ImageInfo) {
+	if len(data) < 30 {
+		return
+	}
+	// 1画素あたりのビット数（情報ヘッダーのオフセット14 + 14）
+	bpp := int(binary.LittleEndian.Uint16(data[28:30]))
+	if bpp <= 8 {
+		info.BitDepth = bpp
+	} else {
+		info.BitDepth = 8
+	}
+	// 透過を持つのは32ビットのNRGBAとしてデコードされる画像のみ
+	info.Alpha = bpp == 32 && info.ColorModel == colorModelName(color.NRGBAModel)
+}
+
+// exifOrientation はEXIF（TIFF形式）の0番目のIFDから向き（1-8）を読み込みます（記録がない場合は0）
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := uint64(order.Uint32(tiff[4:8]))
+	if offset+2 > uint64(len(tiff)) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		// 向きはSHORT型で、値はエントリ内に格納される
+		orientation := int(order.Uint16(tiff[entry+8:]))
+		if orientation < 1 || orientation > 8 {
+			return 0
+		}
+		return orientation
+	}
+	return 0
+}
+
+// colorModelName はカラーモデルの名前を返します
+func colorModelName(model color.Model) string {
+	// パレットはスライスのため、カラーモデルの比較より先に判定する
+	if palette, ok := model.(color.Palette); ok {
+		return fmt.Sprintf("Paletted (%d colors)", len(palette))
+	}
+	switch model {
+	case color.RGBAModel:
+		return "RGBA"
+	case color.RGBA64Model:
+		return "RGBA64"
+	case color.NRGBAModel:
+		return "NRGBA"
+	case color.NRGBA64Model:
+		return "NRGBA64"
+	case color.AlphaModel:
+		return "Alpha"
+	case color.Alpha16Model:
+		return "Alpha16"
+	case color.GrayModel:
+		return "Gray"
+	case color.Gray16Model:
+		return "Gray16"
+	case color.YCbCrModel:
+		return "YCbCr"
+	case color.NYCbCrAModel:
+		return "NYCbCrA"
+	case color.CMYKModel:
+		return "CMYK"
+	}
+	return fmt.Sprintf("%T", model)
+}
+
+// colorModelDepth はカラーモデルのチャネルあたりのビット数を返します（パレット形式はインデックスのビット数）
+func colorModelDepth(model color.Model) int {
+	if palette, ok := model.(color.Palette); ok {
+		if len(palette) > 1 {
+			return bits.Len(uint(len(palette) - 1))
+		}
+		return 1
+	}
+	switch model {
+	case color.RGBA64Model, color.NRGBA64Model, color.Alpha16Model, color.Gray16Model:
+		return 16
+	}
+	return 8
+}
+
+// colorModelHasAlpha はカラーモデルが透過を表現できるか（パレット形式は透明色を含むか）を判定します
+func colorModelHasAlpha(model color.Model) bool {
+	if palette, ok := model.(color.Palette); ok {
+		for _, c := range palette {
+			if _, _, _, a := c.RGBA(); a != 0xFFFF {
+				return true
+			}
+		}
+		return false
+	}
+	switch model {
+	case color.RGBAModel, color.RGBA64Model, color.NRGBAModel, color.NRGBA64Model,
+		color.AlphaModel, color.Alpha16Model, color.NYCbCrAModel:
+		return true
+	}
+	return false
+}
+
+// WriteImageInfoJSON は画像の性質の一覧をJSON形式でwに書き込みます
+func WriteImageInfoJSON(w io.Writer, infos []ImageInfo) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(infos)
+}
+
+// WriteImageInfoText は画像の性質をテキスト形式でwに書き込みます（読み込めなかった画像は省略します）
+func WriteImageInfoText(w io.Writer, infos []ImageInfo) error {
+	first := true
+	for _, info := range infos {
+		if info.Error != "" {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+
+		format := string(info.Format)
+		if info.ExtensionFormat != "" && info.ExtensionFormat != info.Format {
+			format += fmt.Sprintf(" (extension says %s)", info.ExtensionFormat)
+		}
+		orientation := "-"
+		if info.Orientation > 0 {
+			orientation = fmt.Sprintf("%d (%s)", info.Orientation, orientationNames[info.Orientation])
+		}
+
+		fmt.Fprintf(w, "%s\n", info.Path)
+		fmt.Fprintf(w, "  Format:      %s\n", format)
+		fmt.Fprintf(w, "  Dimensions:  %dx%d\n", info.Width, info.Height)
+		fmt.Fprintf(w, "  Color model: %s\n", info.ColorModel)
+		fmt.Fprintf(w, "  Bit depth:   %d\n", info.BitDepth)
+		fmt.Fprintf(w, "  Alpha:       %s\n", yesNo(info.Alpha))
+		fmt.Fprintf(w, "  Frames:      %d\n", info.Frames)
+		fmt.Fprintf(w, "  Orientation: %s\n", orientation)
+		if _, err := fmt.Fprintf(w, "  ICC profile: %s\n", yesNo(info.ICCProfile)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orientationNames はEXIFの向きの説明です
+var orientationNames = map[int]string{
+	1: "normal",
+	2: "mirrored horizontally",
+	3: "rotated 180",
+	4: "mirrored vertically",
+	5: "mirrored horizontally, rotated 270 CW",
+	6: "rotated 90 CW",
+	7: "mirrored horizontally, rotated 90 CW",
+	8: "rotated 270 CW",
+}
+
+// yesNo は真偽値をyes/noで返します
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,217 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// exifWithOrientation は向きのみを記録したEXIF（TIFF形式）を作成します
+func exifWithOrientation(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112) // Orientation
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	return tiff
+}
+
+// insertPNGChunk はIHDRチャンクの直後にチャンクを挿入します
+func insertPNGChunk(data []byte, id string, payload []byte) []byte {
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk[0:4], uint32(len(payload)))
+	copy(chunk[4:8], id)
+	chunk = append(chunk, payload...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	const ihdrEnd = 8 + 12 + 13
+	return append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+}
+
+// insertJPEGSegment はSOIの直後にセグメントを挿入します
+func insertJPEGSegment(data []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func inspect(t *testing.T, data []byte) ImageInfo {
+	t.Helper()
+	info, err := NewImageLoader().InspectBytes(data)
+	if err != nil {
+		t.Fatalf("InspectBytes failed: %v", err)
+	}
+	return info
+}
+
+func TestInspect_JPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, createTestImage(40, 30), nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	data := insertJPEGSegment(buf.Bytes(), 0xE1, append([]byte("Exif\x00\x00"), exifWithOrientation(binary.BigEndian, 6)...))
+	data = insertJPEGSegment(data, 0xE2, append([]byte("ICC_PROFILE\x00\x01\x01"), make([]byte, 16)...))
+
+	info := inspect(t, data)
+	expected := ImageInfo{Format: types.FormatJPEG, Width: 40, Height: 30, ColorModel: "YCbCr", BitDepth: 8, Frames: 1, Orientation: 6, ICCProfile: true}
+	if info != expected {
+		t.Errorf("info = %+v, want %+v", info, expected)
+	}
+}
+
+func TestInspect_PNG(t *testing.T) {
+	// 16ビットグレースケール
+	gray := image.NewGray16(image.Rect(0, 0, 8, 4))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, gray); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	data := insertPNGChunk(buf.Bytes(), "eXIf", exifWithOrientation(binary.LittleEndian, 3))
+	data = insertPNGChunk(data, "iCCP", []byte("icc\x00\x00"))
+
+	info := inspect(t, data)
+	expected := ImageInfo{Format: types.FormatPNG, Width: 8, Height: 4, ColorModel: "Gray16", BitDepth: 16, Frames: 1, Orientation: 3, ICCProfile: true}
+	if info != expected {
+		t.Errorf("info = %+v, want %+v", info, expected)
+	}
+
+	// 透明色を含むパレット
+	paletted := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Transparent, color.Black, color.White})
+	buf.Reset()
+	if err := png.Encode(&buf, paletted); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	info = inspect(t, buf.Bytes())
+	if !info.Alpha || info.BitDepth != 2 || !strings.HasPrefix(info.ColorModel, "Paletted") {
+		t.Errorf("Unexpected info for a paletted PNG: %+v", info)
+	}
+
+	// 不透明なRGB
+	buf.Reset()
+	if err := png.Encode(&buf, createTestImage(4, 4)); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	if info := inspect(t, buf.Bytes()); info.Alpha {
+		t.Errorf("Expected an opaque PNG to have no alpha: %+v", info)
+	}
+}
+
+func TestInspect_AnimatedGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White, color.Transparent}
+	g := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 10, 6), palette))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+
+	info := inspect(t, buf.Bytes())
+	if info.Format != types.FormatGIF || info.Frames != 3 || !info.Alpha || info.Width != 10 || info.Height != 6 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestInspect_AnimatedWebP(t *testing.T) {
+	anim := &AnimatedImage{
+		Frames: []image.Image{createTestImage(16, 16), createTestImage(16, 16)},
+		Delays: []int{10, 10},
+	}
+	var buf bytes.Buffer
+	if err := encodeAnimatedWebP(&buf, anim, 80); err != nil {
+		t.Fatalf("Failed to encode WebP: %v", err)
+	}
+
+	info := inspect(t, buf.Bytes())
+	if info.Format != types.FormatWebP || info.Frames != 2 || info.Width != 16 || info.Height != 16 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestInspect_FileAndExtension(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "disguised.jpg")
+	saveTestImage(t, path, createTestImage(12, 8))
+
+	info, err := NewImageLoader().Inspect(path)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if info.Path != path || info.Format != types.FormatPNG || info.ExtensionFormat != types.FormatJPEG {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "text.png"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewImageLoader().Inspect(filepath.Join(tempDir, "text.png")); err == nil {
+		t.Error("Expected an error for a non-image file")
+	}
+	if _, err := NewImageLoader().Inspect(filepath.Join(tempDir, "missing.png")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestExifOrientation(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected int
+	}{
+		{"little endian", exifWithOrientation(binary.LittleEndian, 8), 8},
+		{"big endian", exifWithOrientation(binary.BigEndian, 2), 2},
+		{"out of range", exifWithOrientation(binary.BigEndian, 9), 0},
+		{"empty", nil, 0},
+		{"bad byte order", []byte("XX\x00\x2a\x00\x00\x00\x08"), 0},
+		{"offset past end", []byte("MM\x00\x2a\xff\xff\xff\xff"), 0},
+		{"truncated entries", exifWithOrientation(binary.BigEndian, 1)[:14], 0},
+	}
+	for _, tt := range tests {
+		if got := exifOrientation(tt.data); got != tt.expected {
+			t.Errorf("%s: exifOrientation = %d, want %d", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteImageInfoText(t *testing.T) {
+	infos := []ImageInfo{
+		{Path: "a.jpg", Format: types.FormatPNG, ExtensionFormat: types.FormatJPEG, Width: 4, Height: 3, ColorModel: "NRGBA", BitDepth: 8, Alpha: true, Frames: 1, Orientation: 6},
+		{Path: "broken.png", Error: "unrecognized image data"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteImageInfoText(&buf, infos); err != nil {
+		t.Fatalf("WriteImageInfoText failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{"a.jpg\n", "png (extension says jpeg)", "4x3", "Alpha:       yes", "6 (rotated 90 CW)", "ICC profile: no"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "broken.png") {
+		t.Errorf("Expected failed entries to be omitted, got:\n%s", output)
+	}
+}
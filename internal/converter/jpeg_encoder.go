@@ -0,0 +1,583 @@
+package converter
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"image-converter/internal/types"
+)
+
+// JPEGエンコーダー
+// 標準ライブラリの image/jpeg はベースライン・4:2:0 のみを出力するため、
+// プログレッシブ（スペクトル選択）と色差サブサンプリングの選択に対応した独自実装を提供します
+
+// JPEGマーカー
+const (
+	markerSOI  = 0xd8
+	markerEOI  = 0xd9
+	markerSOF0 = 0xc0
+	markerSOF2 = 0xc2
+	markerDHT  = 0xc4
+	markerDQT  = 0xdb
+	markerSOS  = 0xda
+)
+
+// jpegZigzag はジグザグ順のインデックスから自然順（行優先）のインデックスへの対応表です
+var jpegZigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegUnscaledQuant はJPEG仕様（Annex K）の量子化テーブルです（ジグザグ順）
+var jpegUnscaledQuant = [2][64]int{
+	// 輝度
+	{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	},
+	// 色差
+	{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+// huffmanSpec はハフマンテーブルの定義（符号長ごとの個数とシンボル）です
+type huffmanSpec struct {
+	counts [16]byte
+	values []byte
+}
+
+// jpegHuffmanSpecs はJPEG仕様（Annex K）の標準ハフマンテーブルです
+// 0: 輝度DC, 1: 輝度AC, 2: 色差DC, 3: 色差AC
+var jpegHuffmanSpecs = [4]huffmanSpec{
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]byte{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]byte{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// dctCos はDCTの余弦テーブル（dctCos[x][u] = C(u)/2 * cos((2x+1)uπ/16)）です
+var dctCos = func() [8][8]float64 {
+	var t [8][8]float64
+	for x := 0; x < 8; x++ {
+		for u := 0; u < 8; u++ {
+			c := 1.0
+			if u == 0 {
+				c = 1 / math.Sqrt2
+			}
+			t[x][u] = c / 2 * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+	return t
+}()
+
+// huffmanCode はシンボルに対応する符号です
+type huffmanCode struct {
+	code   uint32
+	length uint8
+}
+
+// buildHuffmanCodes はハフマンテーブルの定義からシンボルごとの符号を生成します
+func buildHuffmanCodes(spec huffmanSpec) [256]huffmanCode {
+	var codes [256]huffmanCode
+	code := uint32(0)
+	k := 0
+	for length := 1; length <= 16; length++ {
+		for i := 0; i < int(spec.counts[length-1]); i++ {
+			codes[spec.values[k]] = huffmanCode{code: code, length: uint8(length)}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+// jpegComponent はエンコード中の1つの色成分を表します
+type jpegComponent struct {
+	id         byte
+	h, v       int // サンプリング係数
+	quant      int // 量子化テーブル番号
+	huff       int // ハフマンテーブル番号（0: 輝度, 1: 色差）
+	width      int // 成分の実サイズ（ピクセル）
+	height     int
+	blocksWide int // MCU境界まで拡張したブロック数
+	blocksHigh int
+	blocks     [][64]int32 // 量子化済みDCT係数（ジグザグ順）
+}
+
+// jpegScan は1つのスキャンを表します
+type jpegScan struct {
+	components []int // スキャンに含まれる成分のインデックス
+	ss, se     int   // スペクトル選択の開始・終了
+}
+
+// jpegBitWriter はバイトスタッフィング付きのビット単位の書き込みを提供します
+type jpegBitWriter struct {
+	w     *bufio.Writer
+	bits  uint32
+	nBits uint
+	err   error
+}
+
+// writeBits は下位nビットを書き込みます
+func (bw *jpegBitWriter) writeBits(bits uint32, n uint) {
+	if bw.err != nil {
+		return
+	}
+	bw.bits = bw.bits<<n | bits&(1<<n-1)
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		b := byte(bw.bits >> (bw.nBits - 8))
+		bw.nBits -= 8
+		bw.bits &= 1<<bw.nBits - 1
+		if bw.err = bw.w.WriteByte(b); bw.err != nil {
+			return
+		}
+		if b == 0xff {
+			bw.err = bw.w.WriteByte(0)
+		}
+	}
+}
+
+// flush は残りのビットを1で埋めて書き出します
+func (bw *jpegBitWriter) flush() {
+	if bw.nBits > 0 {
+		bw.writeBits(1<<(8-bw.nBits)-1, 8-bw.nBits)
+	}
+}
+
+// jpegEncoder はJPEGエンコードの状態を保持します
+type jpegEncoder struct {
+	w          *bufio.Writer
+	bw         *jpegBitWriter
+	quant      [2][64]int
+	huffCodes  [4][256]huffmanCode
+	components []*jpegComponent
+	mcuWide    int
+	mcuHigh    int
+	hMax, vMax int
+}
+
+// EncodeJPEG はサブサンプリングとプログレッシブを指定してJPEGをエンコードします
+func EncodeJPEG(w io.Writer, img image.Image, quality int, progressive bool, subsampling types.ChromaSubsampling) error {
+	bounds := img.Bounds()
+	if bounds.Dx() <= 0 || bounds.Dy() <= 0 || bounds.Dx() >= 1<<16 || bounds.Dy() >= 1<<16 {
+		return fmt.Errorf("invalid image size: %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	e := &jpegEncoder{w: bufio.NewWriter(w)}
+	e.bw = &jpegBitWriter{w: e.w}
+	e.initQuant(quality)
+	for i, spec := range jpegHuffmanSpecs {
+		e.huffCodes[i] = buildHuffmanCodes(spec)
+	}
+
+	lumaH, lumaV := subsamplingFactors(subsampling)
+	e.hMax, e.vMax = lumaH, lumaV
+	e.components = []*jpegComponent{
+		{id: 1, h: lumaH, v: lumaV, quant: 0, huff: 0},
+		{id: 2, h: 1, v: 1, quant: 1, huff: 1},
+		{id: 3, h: 1, v: 1, quant: 1, huff: 1},
+	}
+	e.computeBlocks(img)
+
+	e.writeMarker(markerSOI)
+	e.writeDQT()
+	if progressive {
+		e.writeSOF(markerSOF2, bounds.Dx(), bounds.Dy())
+	} else {
+		e.writeSOF(markerSOF0, bounds.Dx(), bounds.Dy())
+	}
+	e.writeDHT()
+
+	if progressive {
+		// DCを先に送り、その後に低周波・高周波のACを順に送る
+		scans := []jpegScan{
+			{components: []int{0, 1, 2}, ss: 0, se: 0},
+			{components: []int{0}, ss: 1, se: 5},
+			{components: []int{1}, ss: 1, se: 63},
+			{components: []int{2}, ss: 1, se: 63},
+			{components: []int{0}, ss: 6, se: 63},
+		}
+		for _, scan := range scans {
+			e.writeScan(scan)
+		}
+	} else {
+		e.writeScan(jpegScan{components: []int{0, 1, 2}, ss: 0, se: 63})
+	}
+
+	e.writeMarker(markerEOI)
+
+	if e.bw.err != nil {
+		return e.bw.err
+	}
+	return e.w.Flush()
+}
+
+// subsamplingFactors は輝度成分のサンプリング係数を返します
+func subsamplingFactors(subsampling types.ChromaSubsampling) (h, v int) {
+	switch subsampling {
+	case types.Subsampling444:
+		return 1, 1
+	case types.Subsampling422:
+		return 2, 1
+	default:
+		return 2, 2
+	}
+}
+
+// initQuant は品質に応じて量子化テーブルをスケーリングします（libjpegと同じ計算式）
+func (e *jpegEncoder) initQuant(quality int) {
+	if quality < 1 {
+		quality = 1
+	} else if quality > 100 {
+		quality = 100
+	}
+	var scale int
+	if quality < 50 {
+		scale = 5000 / quality
+	} else {
+		scale = 200 - quality*2
+	}
+	for i := range e.quant {
+		for j := range e.quant[i] {
+			x := (jpegUnscaledQuant[i][j]*scale + 50) / 100
+			if x < 1 {
+				x = 1
+			} else if x > 255 {
+				x = 255
+			}
+			e.quant[i][j] = x
+		}
+	}
+}
+
+// computeBlocks は画像をYCbCrに変換し、各成分のブロックをDCT・量子化します
+func (e *jpegEncoder) computeBlocks(img image.Image) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	mcuW, mcuH := 8*e.hMax, 8*e.vMax
+	e.mcuWide = (width + mcuW - 1) / mcuW
+	e.mcuHigh = (height + mcuH - 1) / mcuH
+	paddedW, paddedH := e.mcuWide*mcuW, e.mcuHigh*mcuH
+
+	// フル解像度のYCbCrプレーン（MCU境界まで端のピクセルを複製して拡張）
+	planes := [3][]float64{
+		make([]float64, paddedW*paddedH),
+		make([]float64, paddedW*paddedH),
+		make([]float64, paddedW*paddedH),
+	}
+	for y := 0; y < paddedH; y++ {
+		sy := y
+		if sy >= height {
+			sy = height - 1
+		}
+		for x := 0; x < paddedW; x++ {
+			sx := x
+			if sx >= width {
+				sx = width - 1
+			}
+			r16, g16, b16, _ := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+			r, g, b := float64(r16>>8), float64(g16>>8), float64(b16>>8)
+			i := y*paddedW + x
+			planes[0][i] = 0.299*r + 0.587*g + 0.114*b
+			planes[1][i] = -0.168736*r - 0.331264*g + 0.5*b + 128
+			planes[2][i] = 0.5*r - 0.418688*g - 0.081312*b + 128
+		}
+	}
+
+	for ci, comp := range e.components {
+		// 成分ごとの縮小率（4:2:0の色差成分は縦横1/2）
+		sx, sy := e.hMax/comp.h, e.vMax/comp.v
+		compW, compH := paddedW/sx, paddedH/sy
+		comp.width = (width*comp.h + e.hMax - 1) / e.hMax
+		comp.height = (height*comp.v + e.vMax - 1) / e.vMax
+		comp.blocksWide = compW / 8
+		comp.blocksHigh = compH / 8
+		comp.blocks = make([][64]int32, comp.blocksWide*comp.blocksHigh)
+
+		var block [64]float64
+		for by := 0; by < comp.blocksHigh; by++ {
+			for bx := 0; bx < comp.blocksWide; bx++ {
+				for y := 0; y < 8; y++ {
+					for x := 0; x < 8; x++ {
+						// 縮小する場合は対応するピクセルの平均を取る
+						px, py := (bx*8+x)*sx, (by*8+y)*sy
+						sum := 0.0
+						for dy := 0; dy < sy; dy++ {
+							for dx := 0; dx < sx; dx++ {
+								sum += planes[ci][(py+dy)*paddedW+px+dx]
+							}
+						}
+						block[y*8+x] = sum/float64(sx*sy) - 128
+					}
+				}
+				comp.blocks[by*comp.blocksWide+bx] = e.fdctQuantize(&block, comp.quant)
+			}
+		}
+	}
+}
+
+// fdctQuantize は8x8ブロックに順方向DCTを適用して量子化し、ジグザグ順で返します
+func (e *jpegEncoder) fdctQuantize(block *[64]float64, quant int) [64]int32 {
+	var tmp [64]float64
+	// 行方向
+	for y := 0; y < 8; y++ {
+		for u := 0; u < 8; u++ {
+			sum := 0.0
+			for x := 0; x < 8; x++ {
+				sum += block[y*8+x] * dctCos[x][u]
+			}
+			tmp[y*8+u] = sum
+		}
+	}
+
+	var out [64]int32
+	// 列方向と量子化
+	for k := 0; k < 64; k++ {
+		n := jpegZigzag[k]
+		u, v := n%8, n/8
+		sum := 0.0
+		for y := 0; y < 8; y++ {
+			sum += tmp[y*8+u] * dctCos[y][v]
+		}
+		out[k] = int32(math.Round(sum / float64(e.quant[quant][k])))
+	}
+	return out
+}
+
+// writeMarker はマーカーを書き込みます
+func (e *jpegEncoder) writeMarker(marker byte) {
+	e.writeBytes(0xff, marker)
+}
+
+// writeBytes はバイト列をそのまま書き込みます
+func (e *jpegEncoder) writeBytes(b ...byte) {
+	if e.bw.err != nil {
+		return
+	}
+	_, e.bw.err = e.w.Write(b)
+}
+
+// writeSegment はマーカーと長さ付きのセグメントを書き込みます
+func (e *jpegEncoder) writeSegment(marker byte, payload []byte) {
+	length := len(payload) + 2
+	e.writeBytes(0xff, marker, byte(length>>8), byte(length))
+	e.writeBytes(payload...)
+}
+
+// writeDQT は量子化テーブルを書き込みます
+func (e *jpegEncoder) writeDQT() {
+	payload := make([]byte, 0, 2*65)
+	for i := range e.quant {
+		payload = append(payload, byte(i))
+		for _, q := range e.quant[i] {
+			payload = append(payload, byte(q))
+		}
+	}
+	e.writeSegment(markerDQT, payload)
+}
+
+// writeSOF はフレームヘッダーを書き込みます
+func (e *jpegEncoder) writeSOF(marker byte, width, height int) {
+	payload := []byte{8, byte(height >> 8), byte(height), byte(width >> 8), byte(width), byte(len(e.components))}
+	for _, comp := range e.components {
+		payload = append(payload, comp.id, byte(comp.h<<4|comp.v), byte(comp.quant))
+	}
+	e.writeSegment(marker, payload)
+}
+
+// writeDHT はハフマンテーブルを書き込みます
+func (e *jpegEncoder) writeDHT() {
+	var payload []byte
+	// クラス（0: DC, 1: AC）と番号の組み合わせ
+	classes := [4]byte{0x00, 0x10, 0x01, 0x11}
+	for i, spec := range jpegHuffmanSpecs {
+		payload = append(payload, classes[i])
+		payload = append(payload, spec.counts[:]...)
+		payload = append(payload, spec.values...)
+	}
+	e.writeSegment(markerDHT, payload)
+}
+
+// writeScan はスキャンヘッダーとエントロピー符号化データを書き込みます
+func (e *jpegEncoder) writeScan(scan jpegScan) {
+	payload := []byte{byte(len(scan.components))}
+	for _, ci := range scan.components {
+		comp := e.components[ci]
+		payload = append(payload, comp.id, byte(comp.huff<<4|comp.huff))
+	}
+	payload = append(payload, byte(scan.ss), byte(scan.se), 0)
+	e.writeSegment(markerSOS, payload)
+
+	prevDC := make([]int32, len(e.components))
+
+	if len(scan.components) > 1 {
+		// インターリーブスキャン: MCU単位で各成分のブロックを順に符号化
+		for my := 0; my < e.mcuHigh; my++ {
+			for mx := 0; mx < e.mcuWide; mx++ {
+				for _, ci := range scan.components {
+					comp := e.components[ci]
+					for v := 0; v < comp.v; v++ {
+						for h := 0; h < comp.h; h++ {
+							bx, by := mx*comp.h+h, my*comp.v+v
+							e.encodeBlock(comp, &comp.blocks[by*comp.blocksWide+bx], scan, &prevDC[ci])
+						}
+					}
+				}
+			}
+		}
+	} else {
+		// 非インターリーブスキャン: 成分の実サイズを覆うブロックのみを符号化
+		ci := scan.components[0]
+		comp := e.components[ci]
+		blocksWide := (comp.width + 7) / 8
+		blocksHigh := (comp.height + 7) / 8
+		for by := 0; by < blocksHigh; by++ {
+			for bx := 0; bx < blocksWide; bx++ {
+				e.encodeBlock(comp, &comp.blocks[by*comp.blocksWide+bx], scan, &prevDC[ci])
+			}
+		}
+	}
+
+	e.bw.flush()
+}
+
+// encodeBlock はブロックのうちスキャンのスペクトル範囲に含まれる係数を符号化します
+func (e *jpegEncoder) encodeBlock(comp *jpegComponent, block *[64]int32, scan jpegScan, prevDC *int32) {
+	dcCodes := &e.huffCodes[comp.huff*2]
+	acCodes := &e.huffCodes[comp.huff*2+1]
+
+	k := scan.ss
+	if k == 0 {
+		diff := block[0] - *prevDC
+		*prevDC = block[0]
+		e.emitValue(dcCodes, 0, diff)
+		k = 1
+	}
+	if scan.se == 0 {
+		return
+	}
+
+	run := 0
+	for ; k <= scan.se; k++ {
+		v := block[k]
+		if v == 0 {
+			run++
+			continue
+		}
+		for run > 15 {
+			e.emitSymbol(acCodes, 0xf0) // ZRL
+			run -= 16
+		}
+		e.emitValue(acCodes, run, v)
+		run = 0
+	}
+	if run > 0 {
+		e.emitSymbol(acCodes, 0x00) // EOB
+	}
+}
+
+// emitSymbol はハフマン符号を書き込みます
+func (e *jpegEncoder) emitSymbol(codes *[256]huffmanCode, symbol byte) {
+	c := codes[symbol]
+	e.bw.writeBits(c.code, uint(c.length))
+}
+
+// emitValue はランレングスと値の大きさを符号化し、続けて値の下位ビットを書き込みます
+func (e *jpegEncoder) emitValue(codes *[256]huffmanCode, run int, value int32) {
+	a, b := value, value
+	if a < 0 {
+		a = -value
+		b = value - 1
+	}
+	size := uint(0)
+	for a > 0 {
+		size++
+		a >>= 1
+	}
+	e.emitSymbol(codes, byte(run<<4)|byte(size))
+	if size > 0 {
+		e.bw.writeBits(uint32(b), size)
+	}
+}
@@ -0,0 +1,156 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// meanAbsError は2つの画像のRGBチャンネルの平均絶対誤差（0-255）を返します
+func meanAbsError(a, b image.Image) float64 {
+	bounds := a.Bounds()
+	sum := 0.0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, _ := a.At(x, y).RGBA()
+			r2, g2, b2, _ := b.At(x, y).RGBA()
+			sum += math.Abs(float64(r1>>8) - float64(r2>>8))
+			sum += math.Abs(float64(g1>>8) - float64(g2>>8))
+			sum += math.Abs(float64(b1>>8) - float64(b2>>8))
+		}
+	}
+	return sum / float64(bounds.Dx()*bounds.Dy()*3)
+}
+
+// findSOF はJPEGデータからフレームヘッダーのマーカーと成分のサンプリング係数を取得します
+func findSOF(data []byte) (marker byte, sampling []byte) {
+	for i := 2; i+4 < len(data); {
+		if data[i] != 0xff {
+			return 0, nil
+		}
+		m := data[i+1]
+		length := int(data[i+2])<<8 | int(data[i+3])
+		if m == markerSOF0 || m == markerSOF2 {
+			seg := data[i+4 : i+2+length]
+			n := int(seg[5])
+			for c := 0; c < n; c++ {
+				sampling = append(sampling, seg[6+c*3+1])
+			}
+			return m, sampling
+		}
+		i += 2 + length
+	}
+	return 0, nil
+}
+
+func TestEncodeJPEG_RoundTrip(t *testing.T) {
+	subsamplings := []types.ChromaSubsampling{types.Subsampling444, types.Subsampling422, types.Subsampling420}
+	expectedLuma := map[types.ChromaSubsampling]byte{
+		types.Subsampling444: 0x11,
+		types.Subsampling422: 0x21,
+		types.Subsampling420: 0x22,
+	}
+
+	// MCU境界に揃わないサイズも含める
+	sizes := [][2]int{{64, 48}, {37, 23}, {1, 1}, {17, 9}}
+
+	for _, progressive := range []bool{false, true} {
+		for _, subsampling := range subsamplings {
+			for _, size := range sizes {
+				img := createTestImage(size[0], size[1])
+
+				var buf bytes.Buffer
+				if err := EncodeJPEG(&buf, img, 90, progressive, subsampling); err != nil {
+					t.Fatalf("progressive=%v %s %v: encode failed: %v", progressive, subsampling, size, err)
+				}
+
+				marker, sampling := findSOF(buf.Bytes())
+				expectedMarker := byte(markerSOF0)
+				if progressive {
+					expectedMarker = markerSOF2
+				}
+				if marker != expectedMarker {
+					t.Errorf("progressive=%v %s: expected SOF marker %#x, got %#x", progressive, subsampling, expectedMarker, marker)
+				}
+				if len(sampling) != 3 || sampling[0] != expectedLuma[subsampling] || sampling[1] != 0x11 {
+					t.Errorf("progressive=%v %s: unexpected sampling factors %x", progressive, subsampling, sampling)
+				}
+
+				decoded, err := jpeg.Decode(bytes.NewReader(buf.Bytes()))
+				if err != nil {
+					t.Fatalf("progressive=%v %s %v: decode failed: %v", progressive, subsampling, size, err)
+				}
+				if decoded.Bounds().Dx() != size[0] || decoded.Bounds().Dy() != size[1] {
+					t.Errorf("progressive=%v %s: expected %v, got %v", progressive, subsampling, size, decoded.Bounds())
+				}
+				// 小さい画像の4:2:0は色差の平均化による誤差が大きくなる（標準ライブラリでも同程度）
+				if e := meanAbsError(img, decoded); e > 8 {
+					t.Errorf("progressive=%v %s %v: mean error too large: %f", progressive, subsampling, size, e)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeJPEG_ProgressiveMatchesBaseline(t *testing.T) {
+	// スペクトル選択のみのプログレッシブは係数がベースラインと同一のため、デコード結果も一致する
+	img := createTestImage(40, 40)
+
+	var baseline, progressive bytes.Buffer
+	if err := EncodeJPEG(&baseline, img, 75, false, types.Subsampling420); err != nil {
+		t.Fatalf("Failed to encode baseline: %v", err)
+	}
+	if err := EncodeJPEG(&progressive, img, 75, true, types.Subsampling420); err != nil {
+		t.Fatalf("Failed to encode progressive: %v", err)
+	}
+
+	a, err := jpeg.Decode(&baseline)
+	if err != nil {
+		t.Fatalf("Failed to decode baseline: %v", err)
+	}
+	b, err := jpeg.Decode(&progressive)
+	if err != nil {
+		t.Fatalf("Failed to decode progressive: %v", err)
+	}
+
+	if e := meanAbsError(a, b); e != 0 {
+		t.Errorf("Expected identical decoded images, got mean error %f", e)
+	}
+}
+
+func TestEncodeJPEG_444PreservesChromaEdges(t *testing.T) {
+	// 1ピクセル幅の赤と白の縦縞（赤い文字の輪郭に相当）
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			if x%2 == 0 {
+				img.SetRGBA(x, y, color.RGBA{R: 220, A: 255})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+			}
+		}
+	}
+
+	errorFor := func(subsampling types.ChromaSubsampling) float64 {
+		var buf bytes.Buffer
+		if err := EncodeJPEG(&buf, img, 95, false, subsampling); err != nil {
+			t.Fatalf("Failed to encode %s: %v", subsampling, err)
+		}
+		decoded, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v", subsampling, err)
+		}
+		return meanAbsError(img, decoded)
+	}
+
+	e444 := errorFor(types.Subsampling444)
+	e420 := errorFor(types.Subsampling420)
+	if e444 >= e420 {
+		t.Errorf("Expected 4:4:4 error (%f) to be lower than 4:2:0 error (%f)", e444, e420)
+	}
+}
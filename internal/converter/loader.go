@@ -3,13 +3,16 @@ package converter
 import (
 	"fmt"
 	"image"
-	_ "image/gif"  // GIFデコーダーを登録
+	"image/gif"
 	_ "image/jpeg" // JPEGデコーダーを登録
 	_ "image/png"  // PNGデコーダーを登録
+	"io"
 	"os"
 
 	_ "golang.org/x/image/bmp"  // BMPデコーダーを登録
 	_ "golang.org/x/image/webp" // WebPデコーダーを登録
+
+	"image-converter/internal/types"
 )
 
 // ImageLoader は画像ファイルの読み込みを提供します
@@ -30,14 +33,57 @@ func (il *ImageLoader) Load(path string) (image.Image, error) {
 	}
 	defer file.Close()
 
-	// 画像をデコード
-	img, format, err := image.Decode(file)
+	img, _, err := il.Decode(file)
+	return img, err
+}
+
+// Decode はrから画像をデコードし、内容から判定したフォーマットとともに返します
+func (il *ImageLoader) Decode(r io.Reader) (image.Image, types.ImageFormat, error) {
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return img, types.ImageFormat(format), nil
+}
+
+// LoadConfig は画像ファイルのヘッダーのみを読み込み、サイズとカラーモデルを内容から判定したフォーマットとともに返します
+func (il *ImageLoader) LoadConfig(path string) (image.Config, types.ImageFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	config, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return image.Config{}, "", fmt.Errorf("failed to decode image header: %w", err)
+	}
+	return config, types.ImageFormat(format), nil
+}
+
+// LoadAnimation はGIFファイルの全フレームを読み込みます
+// 各フレームは廃棄方法に従って合成され、表示時間とループ回数が保持されます
+func (il *ImageLoader) LoadAnimation(path string) (*AnimatedImage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return il.DecodeAnimation(file)
+}
+
+// DecodeAnimation はrからGIFの全フレームをデコードします
+func (il *ImageLoader) DecodeAnimation(r io.Reader) (*AnimatedImage, error) {
+	g, err := gif.DecodeAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+		return nil, fmt.Errorf("failed to decode GIF: %w", err)
 	}
 
-	// デコード成功（フォーマット情報はログ用に保持可能）
-	_ = format
+	if len(g.Image) == 0 {
+		return nil, fmt.Errorf("GIF contains no frames")
+	}
 
-	return img, nil
+	return compositeGIF(g), nil
 }
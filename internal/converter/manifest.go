@@ -0,0 +1,230 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"image-converter/internal/types"
+)
+
+// DefaultPictureSizes は<picture>タグのsizes属性の既定値です
+const DefaultPictureSizes = "100vw"
+
+// ManifestEntry は1つの出力ファイルの情報を表します
+type ManifestEntry struct {
+	Path    string `json:"path"` // マニフェストファイルからの相対パス（/区切り）
+	Variant string `json:"variant,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Format  string `json:"format"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+}
+
+// Manifest は入力ファイル（入力ディレクトリからの相対パス）から出力ファイル一覧への対応表です
+type Manifest map[string][]ManifestEntry
+
+// BuildManifest は成功した変換結果からマニフェストを作成します
+// 出力パスはbaseDirからの相対パスで記録し、内容のSHA-256ハッシュを計算します
+func BuildManifest(results []types.ConversionResult, inputDir, baseDir string) (Manifest, error) {
+	manifest := Manifest{}
+
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+
+		hash, err := hashFile(result.OutputPath)
+		if err != nil {
+			return nil, err
+		}
+
+		source := relativeSlashPath(inputDir, result.SourcePath)
+		manifest[source] = append(manifest[source], ManifestEntry{
+			Path:    relativeSlashPath(baseDir, result.OutputPath),
+			Variant: result.Variant,
+			Rule:    result.Rule,
+			Width:   result.Width,
+			Height:  result.Height,
+			Format:  string(result.Format),
+			Bytes:   result.Bytes,
+			SHA256:  hash,
+		})
+	}
+
+	return manifest, nil
+}
+
+// WriteManifestJSON はマニフェストをJSON形式でwに書き込みます
+func WriteManifestJSON(w io.Writer, manifest Manifest) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
+
+// WritePictureHTML は入力ファイルごとの<picture>タグをwに書き込みます
+// フォールバックのフォーマット（JPEG, PNG, GIFの順で優先）は<img>に、
+// それ以外のフォーマットは<source>にまとめ、幅の昇順でsrcsetを生成します
+func WritePictureHTML(w io.Writer, manifest Manifest, sizes string) error {
+	sources := make([]string, 0, len(manifest))
+	for source := range manifest {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		if _, err := io.WriteString(w, pictureHTML(source, manifest[source], sizes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pictureHTML は1つの入力ファイルの<picture>タグを生成します
+func pictureHTML(source string, entries []ManifestEntry, sizes string) string {
+	byFormat := make(map[string][]ManifestEntry)
+	var formats []string
+	for _, entry := range entries {
+		if _, ok := byFormat[entry.Format]; !ok {
+			formats = append(formats, entry.Format)
+		}
+		byFormat[entry.Format] = append(byFormat[entry.Format], entry)
+	}
+	for _, group := range byFormat {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].Width < group[j].Width })
+	}
+
+	fallback := formats[0]
+	for _, format := range []string{"jpeg", "png", "gif"} {
+		if _, ok := byFormat[format]; ok {
+			fallback = format
+			break
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!-- %s -->\n<picture>\n", html.EscapeString(source))
+	for _, format := range formats {
+		if format == fallback {
+			continue
+		}
+		fmt.Fprintf(&b, "  <source type=\"%s\" srcset=\"%s\" sizes=\"%s\">\n",
+			MIMEType(types.ImageFormat(format)), srcset(byFormat[format]), html.EscapeString(sizes))
+	}
+
+	// 最大の出力をsrcとして使用
+	group := byFormat[fallback]
+	largest := group[len(group)-1]
+	fmt.Fprintf(&b, "  <img src=\"%s\" srcset=\"%s\" sizes=\"%s\" width=\"%d\" height=\"%d\" alt=\"\">\n",
+		html.EscapeString(largest.Path), srcset(group), html.EscapeString(sizes), largest.Width, largest.Height)
+	b.WriteString("</picture>\n")
+
+	return b.String()
+}
+
+// srcset は出力ファイル一覧から幅記述子付きのsrcset属性値を生成します
+func srcset(entries []ManifestEntry) string {
+	candidates := make([]string, len(entries))
+	for i, entry := range entries {
+		candidates[i] = fmt.Sprintf("%s %dw", html.EscapeString(entry.Path), entry.Width)
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// MIMEType は画像フォーマットに対応するMIMEタイプを返します
+func MIMEType(format types.ImageFormat) string {
+	switch format {
+	case types.FormatJPEG:
+		return "image/jpeg"
+	case types.FormatPNG:
+		return "image/png"
+	case types.FormatWebP:
+		return "image/webp"
+	case types.FormatGIF:
+		return "image/gif"
+	case types.FormatBMP:
+		return "image/bmp"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// WriteManifests は設定に応じてJSONマニフェストと<picture>タグのHTMLを書き込みます
+func (c *Converter) WriteManifests(results []types.ConversionResult) error {
+	if c.config.ManifestPath != "" {
+		if err := c.writeManifestFile(results, c.config.ManifestPath, func(w io.Writer, m Manifest) error {
+			return WriteManifestJSON(w, m)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if c.config.ManifestHTMLPath != "" {
+		if err := c.writeManifestFile(results, c.config.ManifestHTMLPath, func(w io.Writer, m Manifest) error {
+			return WritePictureHTML(w, m, DefaultPictureSizes)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeManifestFile はマニフェストを作成し、pathにwriteで書き込みます
+// 出力パスは書き込み先ファイルのディレクトリからの相対パスになります
+func (c *Converter) writeManifestFile(results []types.ConversionResult, path string, write func(io.Writer, Manifest) error) error {
+	manifest, err := BuildManifest(results, c.config.InputDir, filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to build manifest: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	defer file.Close()
+
+	if err := write(file, manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return file.Close()
+}
+
+// hashFile はファイル内容のSHA-256ハッシュを16進文字列で返します
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open output: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash output: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// relativeSlashPath はbaseからの相対パスを/区切りで返します
+// 相対パスにできない場合は/区切りの元のパスを返します
+func relativeSlashPath(base, path string) string {
+	if base != "" {
+		absBase, errBase := filepath.Abs(base)
+		absPath, errPath := filepath.Abs(path)
+		if errBase == nil && errPath == nil {
+			if rel, err := filepath.Rel(absBase, absPath); err == nil {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,146 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"image-converter/internal/filesystem"
+	"image-converter/internal/types"
+)
+
+func TestBuildManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	for _, dir := range []string{inputDir, outputDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	inputPath := filepath.Join(inputDir, "photo.png")
+	saveTestImage(t, inputPath, createTestImage(200, 100))
+
+	config := types.Config{
+		InputDir:  inputDir,
+		OutputDir: outputDir,
+		Format:    "jpeg",
+		Variants: []types.Variant{
+			{Suffix: "-lg", Resize: types.ResizeSpec{Width: 160}},
+			{Suffix: "-sm", Resize: types.ResizeSpec{Width: 80}},
+		},
+	}
+	results := NewConverter(config).ConvertImageVariants(inputPath, outputDir)
+
+	manifest, err := BuildManifest(results, inputDir, outputDir)
+	if err != nil {
+		t.Fatalf("BuildManifest failed: %v", err)
+	}
+
+	entries := manifest["photo.png"]
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	small := entries[1]
+	if small.Path != "photo-sm.jpg" || small.Width != 80 || small.Height != 40 || small.Format != "jpeg" {
+		t.Errorf("Unexpected entry: %+v", small)
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "photo-sm.jpg"))
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if small.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 = %s, want %s", small.SHA256, hex.EncodeToString(sum[:]))
+	}
+	if small.Bytes != int64(len(data)) {
+		t.Errorf("Bytes = %d, want %d", small.Bytes, len(data))
+	}
+}
+
+func TestBuildManifest_SkipsFailures(t *testing.T) {
+	results := []types.ConversionResult{{SourcePath: "/input/broken.png", OutputPath: "/output/broken.png"}}
+	manifest, err := BuildManifest(results, "/input", "/output")
+	if err != nil {
+		t.Fatalf("BuildManifest failed: %v", err)
+	}
+	if len(manifest) != 0 {
+		t.Errorf("Expected empty manifest, got %v", manifest)
+	}
+}
+
+func TestWritePictureHTML(t *testing.T) {
+	manifest := Manifest{
+		"photo.png": {
+			{Path: "photo-lg.jpg", Width: 1280, Height: 720, Format: "jpeg"},
+			{Path: "photo-sm.jpg", Width: 320, Height: 180, Format: "jpeg"},
+			{Path: "photo-lg.webp", Width: 1280, Height: 720, Format: "webp"},
+			{Path: "photo-sm.webp", Width: 320, Height: 180, Format: "webp"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePictureHTML(&buf, manifest, DefaultPictureSizes); err != nil {
+		t.Fatalf("WritePictureHTML failed: %v", err)
+	}
+	html := buf.String()
+
+	expected := []string{
+		`<source type="image/webp" srcset="photo-sm.webp 320w, photo-lg.webp 1280w" sizes="100vw">`,
+		`<img src="photo-lg.jpg" srcset="photo-sm.jpg 320w, photo-lg.jpg 1280w" sizes="100vw" width="1280" height="720" alt="">`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML does not contain %q:\n%s", want, html)
+		}
+	}
+}
+
+func TestProcessDirectory_WritesManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	for _, dir := range []string{inputDir, outputDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	saveTestImage(t, filepath.Join(inputDir, "a.png"), createTestImage(40, 20))
+	saveTestImage(t, filepath.Join(inputDir, "b.png"), createTestImage(20, 40))
+
+	config := types.Config{
+		InputDir:         inputDir,
+		OutputDir:        outputDir,
+		Format:           "png",
+		ManifestPath:     filepath.Join(outputDir, "manifest.json"),
+		ManifestHTMLPath: filepath.Join(outputDir, "picture.html"),
+	}
+	converter := NewConverter(config)
+	if err := converter.ProcessDirectory(inputDir, outputDir, filesystem.NewFileSystemManager()); err != nil {
+		t.Fatalf("ProcessDirectory failed: %v", err)
+	}
+
+	data, err := os.ReadFile(config.ManifestPath)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Invalid manifest JSON: %v", err)
+	}
+	if len(manifest) != 2 || manifest["b.png"][0].Width != 20 || manifest["b.png"][0].Height != 40 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	if _, err := os.Stat(config.ManifestHTMLPath); err != nil {
+		t.Errorf("HTML snippet was not written: %v", err)
+	}
+}
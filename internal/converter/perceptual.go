@@ -0,0 +1,141 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+
+	"image-converter/internal/types"
+)
+
+// ErrTargetSSIMUnreachable は最高設定でもSSIMが目標に届かないことを表します
+var ErrTargetSSIMUnreachable = errors.New("target SSIM is unreachable")
+
+// ErrTargetSSIMAnimation はアニメーションの出力に目標SSIMが指定されたことを表します
+var ErrTargetSSIMAnimation = errors.New("target SSIM is not supported for animated output")
+
+// PerceptualResult は知覚品質目標モードでのエンコード結果を表します
+type PerceptualResult struct {
+	Data    []byte
+	Quality int     // 採用した品質（JPEG/WebP）または色数（GIF/パレットPNG）、0はフルカラー
+	SSIM    float64 // 元画像との構造的類似度
+}
+
+// perceptualCandidate は候補を1つエンコードし、デコードしてSSIMを計算します
+type perceptualCandidate func(k int) (*PerceptualResult, error)
+
+// encodeForTargetSSIM は元画像とのSSIMがtarget以上となる最小の出力を探索します
+// JPEG/WebPは品質、GIF/PNGは色数を調整し、最高設定でも目標に届かない場合はErrTargetSSIMUnreachableを返します
+func encodeForTargetSSIM(saver *ImageSaver, img image.Image, format types.ImageFormat, target float64) (*PerceptualResult, error) {
+	result, err := searchTargetSSIM(saver, img, format, target)
+	if err != nil {
+		return nil, err
+	}
+	if result.SSIM < target {
+		return nil, fmt.Errorf("%w: best %s output has SSIM %.5f, target %.5f", ErrTargetSSIMUnreachable, format, result.SSIM, target)
+	}
+	return result, nil
+}
+
+// searchTargetSSIM はフォーマットに応じて候補を探索し、目標を満たす最小の出力（満たせない場合は最高設定の結果）を返します
+func searchTargetSSIM(saver *ImageSaver, img image.Image, format types.ImageFormat, target float64) (*PerceptualResult, error) {
+	candidate := func(s *ImageSaver) perceptualCandidate {
+		return func(k int) (*PerceptualResult, error) {
+			var buf bytes.Buffer
+			if err := s.Encode(&buf, img, format, k); err != nil {
+				return nil, err
+			}
+			return evaluateCandidate(img, buf.Bytes(), k)
+		}
+	}
+
+	// 色数を調整する候補（qualityは使用されない）
+	paletteCandidate := func(pngPalette bool) perceptualCandidate {
+		return func(colors int) (*PerceptualResult, error) {
+			options := saver.options
+			options.Colors = colors
+			if pngPalette {
+				options.PNGPalette = true
+				options.PNGMaxError = 0
+			}
+			var buf bytes.Buffer
+			if err := NewImageSaverWithOptions(options).Encode(&buf, img, format, 0); err != nil {
+				return nil, err
+			}
+			return evaluateCandidate(img, buf.Bytes(), colors)
+		}
+	}
+
+	switch format {
+	case types.FormatJPEG, types.FormatWebP:
+		return searchLowestMeeting(MinBudgetQuality, 100, target, candidate(saver))
+
+	case types.FormatGIF:
+		return searchLowestMeeting(minBudgetColors, 256, target, paletteCandidate(false))
+
+	case types.FormatPNG:
+		// フルカラー（可逆）と目標を満たす最小のパレットのうち小さい方を採用
+		truecolorOptions := saver.options
+		truecolorOptions.PNGPalette = false
+		truecolor, err := candidate(NewImageSaverWithOptions(truecolorOptions))(0)
+		if err != nil {
+			return nil, err
+		}
+		paletted, err := searchLowestMeeting(minBudgetColors, 256, target, paletteCandidate(true))
+		if err != nil {
+			return nil, err
+		}
+		if paletted.SSIM >= target && len(paletted.Data) < len(truecolor.Data) {
+			return paletted, nil
+		}
+		return truecolor, nil
+
+	default:
+		return candidate(saver)(0)
+	}
+}
+
+// searchLowestMeeting はSSIMがtarget以上となる最小のkを[lo, hi]の範囲で二分探索します
+// SSIMがkに対して単調増加すると仮定しています
+func searchLowestMeeting(lo, hi int, target float64, evaluate perceptualCandidate) (*PerceptualResult, error) {
+	best, err := evaluate(hi)
+	if err != nil {
+		return nil, err
+	}
+	if best.SSIM < target {
+		// 最高設定でも目標に届かない
+		return best, nil
+	}
+
+	for lo < hi {
+		mid := (lo + hi) / 2
+		result, err := evaluate(mid)
+		if err != nil {
+			return nil, err
+		}
+		if result.SSIM >= target {
+			best = result
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	return best, nil
+}
+
+// evaluateCandidate はエンコード結果をデコードし、元画像とのSSIMを計算します
+func evaluateCandidate(src image.Image, data []byte, k int) (*PerceptualResult, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode candidate: %w", err)
+	}
+
+	ssim, err := SSIM(src, decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PerceptualResult{Data: data, Quality: k, SSIM: ssim}, nil
+}
@@ -0,0 +1,108 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestEncodeForTargetSSIM_JPEG(t *testing.T) {
+	img := createNoisyImage(96, 96)
+	saver := NewImageSaver()
+
+	strict, err := encodeForTargetSSIM(saver, img, types.FormatJPEG, 0.99)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	loose, err := encodeForTargetSSIM(saver, img, types.FormatJPEG, 0.8)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	if loose.SSIM < 0.8 {
+		t.Errorf("Expected SSIM >= 0.8, got %f", loose.SSIM)
+	}
+	if loose.Quality > strict.Quality || len(loose.Data) > len(strict.Data) {
+		t.Errorf("Expected lower target to give lower quality/size: loose q=%d %dB, strict q=%d %dB",
+			loose.Quality, len(loose.Data), strict.Quality, len(strict.Data))
+	}
+
+	// 報告されたSSIMがデコード結果と一致することを確認
+	decoded, _, err := image.Decode(bytes.NewReader(loose.Data))
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	ssim, _ := SSIM(img, decoded)
+	if ssim != loose.SSIM {
+		t.Errorf("Reported SSIM %f does not match measured %f", loose.SSIM, ssim)
+	}
+}
+
+func TestEncodeForTargetSSIM_FlatGraphicPNG(t *testing.T) {
+	// 平坦なグラフィックは少ない色数のパレットで目標を満たせる
+	img := createTestImage(64, 64)
+	result, err := encodeForTargetSSIM(NewImageSaver(), img, types.FormatPNG, 0.95)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if result.SSIM < 0.95 {
+		t.Errorf("Expected SSIM >= 0.95, got %f", result.SSIM)
+	}
+}
+
+func TestEncodeForTargetSSIM_Unreachable(t *testing.T) {
+	// 非可逆のJPEGは品質100でも完全には一致しない
+	_, err := encodeForTargetSSIM(NewImageSaver(), createNoisyImage(64, 64), types.FormatJPEG, 1)
+	if !errors.Is(err, ErrTargetSSIMUnreachable) {
+		t.Errorf("Expected ErrTargetSSIMUnreachable, got %v", err)
+	}
+}
+
+func TestConverter_ConvertImage_TargetSSIM(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "photo.png")
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+	saveTestImage(t, inputPath, createNoisyImage(80, 60))
+
+	converter := NewConverter(types.Config{Format: "webp", JPEGQuality: 85, TargetSSIM: 0.9})
+	result := converter.ConvertImage(inputPath, outputDir)
+	if !result.Success {
+		t.Fatalf("Expected conversion to succeed, got error: %v", result.Error)
+	}
+	if result.SSIM < 0.9 {
+		t.Errorf("Expected SSIM >= 0.9, got %f", result.SSIM)
+	}
+	if result.Quality < MinBudgetQuality || result.Quality > 100 {
+		t.Errorf("Unexpected quality: %d", result.Quality)
+	}
+}
+
+func TestConverter_ConvertImage_TargetSSIMAnimation(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "anim.gif")
+	colors := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}}
+	saveTestGIF(t, inputPath, createTestGIF(20, 20, colors, gif.DisposalNone))
+
+	// アニメーションのまま出力する場合はエラーにする
+	result := NewConverter(types.Config{Format: "gif", TargetSSIM: 0.9}).ConvertImageTo(inputPath, filepath.Join(tempDir, "out.gif"))
+	if result.Success || !errors.Is(result.Error, ErrTargetSSIMAnimation) {
+		t.Errorf("Expected a failed result with ErrTargetSSIMAnimation, got %+v", result)
+	}
+	assertExists(t, filepath.Join(tempDir, "out.gif"), false)
+
+	// 最初のフレームのみを出力する場合は静止画として探索する
+	result = NewConverter(types.Config{Format: "gif", TargetSSIM: 0.9, FirstFrameOnly: true}).ConvertImageTo(inputPath, filepath.Join(tempDir, "first.gif"))
+	if !result.Success || result.SSIM < 0.9 {
+		t.Errorf("Expected first-frame conversion to meet the target, got %+v", result)
+	}
+}
@@ -0,0 +1,306 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"image-converter/internal/types"
+)
+
+// 変換計画の表示形式
+const (
+	PlanFormatTable = "table" // 表形式
+	PlanFormatJSON  = "json"  // JSON形式
+)
+
+// PlanEntry は-dry-runで表示する1つの出力の予定です
+// 入力のサイズはヘッダーのみから読み込み、出力のサイズはリサイズ仕様から計算します
+type PlanEntry struct {
+	Source       string   `json:"source"`
+	SourceFormat string   `json:"source_format,omitempty"` // 内容から判定した入力フォーマット
+	SourceWidth  int      `json:"source_width,omitempty"`
+	SourceHeight int      `json:"source_height,omitempty"`
+	Output       string   `json:"output,omitempty"`
+	Variant      string   `json:"variant,omitempty"`
+	Rule         string   `json:"rule,omitempty"`
+	Format       string   `json:"format,omitempty"`
+	Width        int      `json:"width,omitempty"`
+	Height       int      `json:"height,omitempty"`
+	Exists       bool     `json:"exists,omitempty"`        // 出力パスに既にファイルがある（上書きする）
+	CollidesWith []string `json:"collides_with,omitempty"` // 同じ出力パスに書き込む他の入力、または出力パスにある入力
+	Error        string   `json:"error,omitempty"`
+}
+
+// Plan は-dry-runで表示する変換計画です
+type Plan struct {
+	Outputs []PlanEntry `json:"outputs"`
+	Remove  []string    `json:"remove,omitempty"` // -syncで削除する出力
+	Keep    []string    `json:"keep,omitempty"`   // -syncの対象だが、変換後に書き換えられたため残す出力
+}
+
+// Collisions は出力パスが衝突する予定の数を返します
+func (p Plan) Collisions() int {
+	count := 0
+	for _, entry := range p.Outputs {
+		if len(entry.CollidesWith) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// PlanFiles は画像ファイルを変換した場合の出力を、画像を保存せずに計算します
+// 規則とバリエーションを考慮し、出力パスの衝突（複数の出力が同じパスに書き込む、または入力を上書きする）を検出します
+func (c *Converter) PlanFiles(imageFiles []string, outputDir string) Plan {
+	plan := Plan{Outputs: []PlanEntry{}}
+	for _, file := range imageFiles {
+		plan.Outputs = append(plan.Outputs, c.planFile(file, outputDir)...)
+	}
+	markCollisions(plan.Outputs, imageFiles)
+	return plan
+}
+
+// planFile は規則を評価して1つの入力の出力の予定を返します
+func (c *Converter) planFile(sourcePath, outputDir string) []PlanEntry {
+	rule, err := c.MatchRule(sourcePath)
+	if err != nil {
+		return []PlanEntry{{Source: sourcePath, Error: err.Error()}}
+	}
+	if rule == nil {
+		return c.planOutputs(sourcePath, outputDir)
+	}
+
+	entries := c.forRule(rule).planOutputs(sourcePath, outputDir)
+	for i := range entries {
+		entries[i].Rule = rule.Name
+	}
+	return entries
+}
+
+// planOutputs は規則を評価せずに1つの入力の出力（バリエーションごと）の予定を返します
+func (c *Converter) planOutputs(sourcePath, outputDir string) []PlanEntry {
+	base := PlanEntry{Source: sourcePath}
+	header, sourceFormat, err := c.loader.LoadConfig(sourcePath)
+	if err != nil {
+		base.Error = fmt.Errorf("%w: %w", ErrDecode, err).Error()
+		return []PlanEntry{base}
+	}
+	base.SourceFormat = string(sourceFormat)
+	base.SourceWidth = header.Width
+	base.SourceHeight = header.Height
+
+	// バリエーションがない場合は、基本設定のみの（接尾辞のない）バリエーションとして計算
+	variants := c.config.Variants
+	if len(variants) == 0 {
+		variants = []types.Variant{{}}
+	}
+
+	entries := make([]PlanEntry, 0, len(variants))
+	for _, variant := range variants {
+		entry := base
+		entry.Variant = variant.Suffix
+
+		format := variant.Format
+		if format == "" {
+			format = c.config.Format
+		}
+		outputFormat, err := c.resolveOutputFormat(sourcePath, format)
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+
+		entry.Output = c.formatDetector.GenerateVariantOutputPath(sourcePath, outputDir, variant.Suffix, outputFormat)
+		entry.Format = string(outputFormat)
+		entry.Width, entry.Height = c.resizer.CalculateOutputSize(header.Width, header.Height, c.variantResizeSpec(variant))
+		if _, err := os.Stat(entry.Output); err == nil {
+			entry.Exists = true
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// markCollisions は同じ出力パスに書き込む予定と、入力を上書きする予定にCollidesWithを設定します
+func markCollisions(entries []PlanEntry, imageFiles []string) {
+	writers := map[string][]int{} // 出力パス → 書き込む予定のインデックス
+	for i, entry := range entries {
+		if entry.Output != "" {
+			key := filepath.Clean(entry.Output)
+			writers[key] = append(writers[key], i)
+		}
+	}
+	inputs := map[string]string{} // パス → 入力
+	for _, file := range imageFiles {
+		inputs[filepath.Clean(file)] = file
+	}
+
+	for key, indexes := range writers {
+		input, overwritesInput := inputs[key]
+		if len(indexes) < 2 && !overwritesInput {
+			continue
+		}
+		for _, i := range indexes {
+			var others []string
+			for _, j := range indexes {
+				if j != i {
+					others = append(others, entries[j].Source)
+				}
+			}
+			if overwritesInput {
+				others = append(others, input)
+			}
+			entries[i].CollidesWith = others
+		}
+	}
+}
+
+// outputCollisions は複数の入力が同じ出力パスに書き込む予定を検出し、衝突する入力ごとのエラーを返します
+// 出力パスは規則とバリエーションを考慮して、画像をデコードせずに計算します
+func (c *Converter) outputCollisions(imageFiles []string, outputDir string) map[string]error {
+	writers := map[string][]string{} // 出力パス → 書き込む予定の入力
+	var outputs []string
+	for _, file := range imageFiles {
+		seen := map[string]bool{}
+		for _, output := range c.expectedOutputs(file, outputDir) {
+			key := filepath.Clean(output)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if writers[key] == nil {
+				outputs = append(outputs, key)
+			}
+			writers[key] = append(writers[key], file)
+		}
+	}
+
+	collisions := map[string]error{}
+	for _, output := range outputs {
+		sources := writers[output]
+		if len(sources) < 2 {
+			continue
+		}
+		for i, source := range sources {
+			if _, ok := collisions[source]; ok {
+				continue
+			}
+			others := append(append([]string{}, sources[:i]...), sources[i+1:]...)
+			collisions[source] = fmt.Errorf("%w: %s is also written by %s", ErrOutputCollision, output, strings.Join(others, ", "))
+		}
+	}
+	return collisions
+}
+
+// WritePlanJSON は変換計画をJSON形式でwに書き込みます
+func WritePlanJSON(w io.Writer, plan Plan) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+// WritePlanTable は変換計画を表形式でwに書き込みます
+// 表の後に-syncで削除する出力と、予定の要約を書き込みます
+func WritePlanTable(w io.Writer, plan Plan) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tFORMAT\tSIZE\tOUTPUT\tFORMAT\tSIZE\tNOTE")
+
+	errorCount := 0
+	for _, entry := range plan.Outputs {
+		var notes []string
+		if entry.Error != "" {
+			errorCount++
+			notes = append(notes, "error: "+entry.Error)
+		}
+		if entry.Rule != "" {
+			notes = append(notes, "rule: "+entry.Rule)
+		}
+		if len(entry.CollidesWith) > 0 {
+			notes = append(notes, "COLLISION with "+strings.Join(entry.CollidesWith, ", "))
+		} else if entry.Exists {
+			notes = append(notes, "overwrite")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Source,
+			orDash(entry.SourceFormat),
+			formatSize(entry.SourceWidth, entry.SourceHeight),
+			orDash(entry.Output),
+			orDash(entry.Format),
+			formatSize(entry.Width, entry.Height),
+			strings.Join(notes, "; "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, path := range plan.Keep {
+		fmt.Fprintf(w, "Keeping %s (modified since conversion)\n", path)
+	}
+	for _, path := range plan.Remove {
+		fmt.Fprintf(w, "Would remove %s\n", path)
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %d outputs, %d collisions, %d errors, %d removals\n",
+		len(plan.Outputs), plan.Collisions(), errorCount, len(plan.Remove))
+	return err
+}
+
+// formatSize は幅と高さを「幅x高さ」の形式で返します（不明な場合は-）
+func formatSize(width, height int) string {
+	if width == 0 && height == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
+// orDash は空文字列を-に置き換えます
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// printPlan は画像を保存せずに変換計画を表示します（-dry-run）
+// 読み込めない入力は失敗として数え、出力パスが衝突する場合はエラーを返します
+func (c *Converter) printPlan(imageFiles []string, outputDir string) error {
+	plan := c.PlanFiles(imageFiles, outputDir)
+	if c.config.Sync {
+		remove, modified, err := c.PlanRemovals(imageFiles, outputDir)
+		if err != nil {
+			return err
+		}
+		plan.Remove = remove
+		plan.Keep = modified
+	}
+
+	for _, entry := range plan.Outputs {
+		result := types.ConversionResult{SourcePath: entry.Source, OutputPath: entry.Output, Success: entry.Error == ""}
+		if entry.Error != "" {
+			result.Error = fmt.Errorf("%s", entry.Error)
+		}
+		c.UpdateStats(result)
+	}
+
+	var err error
+	if c.config.PlanFormat == PlanFormatJSON {
+		err = WritePlanJSON(os.Stdout, plan)
+	} else {
+		err = WritePlanTable(os.Stdout, plan)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	if collisions := plan.Collisions(); collisions > 0 {
+		return fmt.Errorf("%d outputs would collide with another output or input", collisions)
+	}
+	return nil
+}
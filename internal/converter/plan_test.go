@@ -0,0 +1,173 @@
+package converter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"image-converter/internal/filesystem"
+	"image-converter/internal/types"
+)
+
+func TestPlanFiles_VariantsAndRules(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := filepath.Join(tempDir, "output")
+	photo := filepath.Join(tempDir, "photo.png")
+	icon := filepath.Join(tempDir, "icon.png")
+	saveTestImage(t, photo, createTestImage(400, 200))
+	saveTestImage(t, icon, createTestImage(64, 64))
+
+	conv := NewConverter(types.Config{
+		InputDir: tempDir,
+		Format:   "webp",
+		Variants: []types.Variant{
+			{Suffix: "-sm", Resize: types.ResizeSpec{Width: 100}},
+			{Suffix: "-lg", Resize: types.ResizeSpec{Width: 200}, Format: "jpeg"},
+		},
+		Rules: []types.Rule{{Name: "icons", Match: types.RuleMatch{Glob: "icon.*"}, Format: "png"}},
+	})
+
+	plan := conv.PlanFiles([]string{icon, photo}, outputDir)
+	expected := []PlanEntry{
+		{Source: icon, SourceFormat: "png", SourceWidth: 64, SourceHeight: 64, Output: filepath.Join(outputDir, "icon-sm.png"), Variant: "-sm", Rule: "icons", Format: "png", Width: 100, Height: 100},
+		{Source: icon, SourceFormat: "png", SourceWidth: 64, SourceHeight: 64, Output: filepath.Join(outputDir, "icon-lg.jpg"), Variant: "-lg", Rule: "icons", Format: "jpeg", Width: 200, Height: 200},
+		{Source: photo, SourceFormat: "png", SourceWidth: 400, SourceHeight: 200, Output: filepath.Join(outputDir, "photo-sm.webp"), Variant: "-sm", Format: "webp", Width: 100, Height: 50},
+		{Source: photo, SourceFormat: "png", SourceWidth: 400, SourceHeight: 200, Output: filepath.Join(outputDir, "photo-lg.jpg"), Variant: "-lg", Format: "jpeg", Width: 200, Height: 100},
+	}
+	if !reflect.DeepEqual(plan.Outputs, expected) {
+		t.Errorf("Unexpected plan:\n got  %+v\n want %+v", plan.Outputs, expected)
+	}
+	if plan.Collisions() != 0 {
+		t.Errorf("Expected no collisions, got %d", plan.Collisions())
+	}
+	assertExists(t, outputDir, false)
+}
+
+func TestPlanFiles_Collisions(t *testing.T) {
+	tempDir := t.TempDir()
+	a := filepath.Join(tempDir, "a.png")
+	aJPEG := filepath.Join(tempDir, "a.jpg")
+	b := filepath.Join(tempDir, "b.png")
+	saveTestImage(t, a, createTestImage(20, 10))
+	if err := saveImageWithFormat(createTestImage(20, 10), aJPEG, types.FormatJPEG); err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+	saveTestImage(t, b, createTestImage(20, 10))
+
+	// 異なる入力が同じ出力パスに書き込む
+	plan := NewConverter(types.Config{Format: "webp"}).PlanFiles([]string{a, aJPEG, b}, filepath.Join(tempDir, "out"))
+	if plan.Collisions() != 2 {
+		t.Fatalf("Expected 2 collisions, got %d", plan.Collisions())
+	}
+	if !reflect.DeepEqual(plan.Outputs[0].CollidesWith, []string{aJPEG}) || !reflect.DeepEqual(plan.Outputs[1].CollidesWith, []string{a}) {
+		t.Errorf("Unexpected collisions: %v, %v", plan.Outputs[0].CollidesWith, plan.Outputs[1].CollidesWith)
+	}
+	if len(plan.Outputs[2].CollidesWith) != 0 {
+		t.Errorf("Expected b.png not to collide, got %v", plan.Outputs[2].CollidesWith)
+	}
+
+	// 入力と同じディレクトリに同じフォーマットで出力すると入力を上書きする
+	plan = NewConverter(types.Config{}).PlanFiles([]string{b}, tempDir)
+	if !reflect.DeepEqual(plan.Outputs[0].CollidesWith, []string{b}) {
+		t.Errorf("Expected the output to collide with its input, got %v", plan.Outputs[0].CollidesWith)
+	}
+	if !plan.Outputs[0].Exists {
+		t.Error("Expected the output to be reported as existing")
+	}
+}
+
+func TestPlanFiles_InvalidImage(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "broken.png")
+	if err := os.WriteFile(path, []byte("not an image"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	plan := NewConverter(types.Config{Format: "jpeg"}).PlanFiles([]string{path}, tempDir)
+	if len(plan.Outputs) != 1 || plan.Outputs[0].Error == "" || plan.Outputs[0].Output != "" {
+		t.Errorf("Expected a single failed entry, got %+v", plan.Outputs)
+	}
+}
+
+func TestProcessDirectory_DryRun(t *testing.T) {
+	inputDir, _ := newSyncDirs(t)
+	outputDir := filepath.Join(filepath.Dir(inputDir), "new-output")
+	if err := os.WriteFile(filepath.Join(inputDir, "broken.png"), []byte("not an image"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	conv := NewConverter(types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "jpeg", ManifestPath: filepath.Join(outputDir, "manifest.json"), DryRun: true})
+	if err := conv.ProcessDirectory(inputDir, outputDir, filesystem.NewFileSystemManager()); err != nil {
+		t.Fatalf("ProcessDirectory failed: %v", err)
+	}
+
+	// 出力ディレクトリもマニフェストも作成しない
+	assertExists(t, outputDir, false)
+
+	stats := conv.GetStats()
+	if stats.Success != 2 || stats.Failed != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// 出力パスが衝突する場合はエラー
+	if err := saveImageWithFormat(createTestImage(10, 10), filepath.Join(inputDir, "a.jpg"), types.FormatJPEG); err != nil {
+		t.Fatalf("Failed to save image: %v", err)
+	}
+	conv = NewConverter(types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "jpeg", DryRun: true})
+	if err := conv.ProcessDirectory(inputDir, outputDir, filesystem.NewFileSystemManager()); err == nil {
+		t.Error("Expected an error for colliding outputs")
+	}
+}
+
+func TestWritePlanTable(t *testing.T) {
+	plan := Plan{
+		Outputs: []PlanEntry{
+			{Source: "in/a.png", SourceFormat: "png", SourceWidth: 400, SourceHeight: 200, Output: "out/a.webp", Format: "webp", Width: 200, Height: 100, Exists: true},
+			{Source: "in/b.png", Output: "out/b.webp", Format: "webp", CollidesWith: []string{"in/b.jpg"}},
+			{Source: "in/c.png", Error: "failed to load image"},
+		},
+		Remove: []string{"out/old.webp"},
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlanTable(&buf, plan); err != nil {
+		t.Fatalf("WritePlanTable failed: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"SOURCE",
+		"400x200",
+		"200x100",
+		"overwrite",
+		"COLLISION with in/b.jpg",
+		"error: failed to load image",
+		"Would remove out/old.webp",
+		"Plan: 3 outputs, 1 collisions, 1 errors, 1 removals",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestWritePlanJSON(t *testing.T) {
+	plan := Plan{Outputs: []PlanEntry{{Source: "in/a.png", Output: "out/a.webp", Format: "webp", Width: 10, Height: 5}}}
+
+	var buf bytes.Buffer
+	if err := WritePlanJSON(&buf, plan); err != nil {
+		t.Fatalf("WritePlanJSON failed: %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{`"source": "in/a.png"`, `"output": "out/a.webp"`, `"width": 10`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "remove") || strings.Contains(output, "collides_with") {
+		t.Errorf("Expected empty fields to be omitted, got:\n%s", output)
+	}
+}
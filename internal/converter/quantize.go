@@ -0,0 +1,497 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"image-converter/internal/types"
+)
+
+// DefaultColors はパレットのデフォルト色数です
+const DefaultColors = 256
+
+// alphaThreshold はこの値未満のアルファを透明ピクセルとして扱う閾値です（16ビット）
+const alphaThreshold = 0x8000
+
+// bayer8x8 は組織的ディザリング（Bayer）の閾値行列です
+var bayer8x8 = [8][8]float64{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// Quantizer はメディアンカット法により画像固有のパレットを生成し、減色を行います
+type Quantizer struct {
+	NumColors int              // 最大色数（透過色を含む、2-256）
+	Dither    types.DitherMode // ディザリング方式
+	// AlphaAware が true の場合、半透明を含むRGBA空間でパレットを生成します（PNGのtRNS用）
+	// false の場合は不透明色と1つの透過色のみを使用します（GIF用）
+	AlphaAware bool
+}
+
+// NewQuantizer は新しいQuantizerを作成します
+// numColorsが範囲外の場合は256色、ditherが空の場合はFloyd-Steinbergを使用します
+func NewQuantizer(numColors int, dither types.DitherMode) *Quantizer {
+	if numColors < 2 || numColors > 256 {
+		numColors = DefaultColors
+	}
+	if dither == "" {
+		dither = types.DitherFloydSteinberg
+	}
+	return &Quantizer{NumColors: numColors, Dither: dither}
+}
+
+// rgba は0-255の浮動小数点で表した色です
+// AlphaAwareモードでは乗算済み（premultiplied）の値を保持します
+type rgba [4]float64
+
+// colorBin はヒストグラムの1つのビンを表します
+type colorBin struct {
+	c     rgba // ビン内の平均色
+	count float64
+}
+
+// colorBox はメディアンカットの分割単位です
+type colorBox struct {
+	bins []colorBin
+}
+
+// Quantize は画像をパレット画像に変換します
+func (q *Quantizer) Quantize(img image.Image) *image.Paletted {
+	pal := q.Palette(img)
+	return q.Apply(img, pal)
+}
+
+// Palette は画像に適したパレットを生成します
+// 不透明モードで透明ピクセルが含まれる場合、パレットの最後に透過色を1つ確保します
+func (q *Quantizer) Palette(img image.Image) color.Palette {
+	bins, hasTransparent := q.buildHistogram(img)
+
+	numColors := q.NumColors
+	if hasTransparent {
+		numColors--
+	}
+
+	boxes := medianCut(bins, numColors)
+
+	pal := make(color.Palette, 0, len(boxes)+1)
+	for _, box := range boxes {
+		c := box.average()
+		pal = append(pal, color.RGBA{
+			R: clamp255(c[0]),
+			G: clamp255(c[1]),
+			B: clamp255(c[2]),
+			A: clamp255(c[3]),
+		})
+	}
+	if len(pal) == 0 {
+		// 完全に透明な画像でも最低1色は持たせる
+		pal = append(pal, color.RGBA{A: 255})
+	}
+	if hasTransparent {
+		pal = append(pal, color.RGBA{})
+	}
+
+	return pal
+}
+
+// pixel は画像の1ピクセルをQuantizerの色空間に変換します
+// 不透明モードでは透明ピクセルに対して ok=false を返します
+func (q *Quantizer) pixel(img image.Image, x, y int) (rgba, bool) {
+	r, g, b, a := img.At(x, y).RGBA()
+	if q.AlphaAware {
+		return rgba{float64(r >> 8), float64(g >> 8), float64(b >> 8), float64(a >> 8)}, true
+	}
+	if a < alphaThreshold {
+		return rgba{}, false
+	}
+	ur, ug, ub := unpremultiply(r, g, b, a)
+	return rgba{ur, ug, ub, 255}, true
+}
+
+// Apply は指定されたパレットで画像を減色します
+func (q *Quantizer) Apply(img image.Image, pal color.Palette) *image.Paletted {
+	bounds := img.Bounds()
+	dst := image.NewPaletted(bounds, pal)
+	width := bounds.Dx()
+
+	transparentIndex := -1
+	if !q.AlphaAware {
+		for i, c := range pal {
+			if _, _, _, a := c.RGBA(); a == 0 {
+				transparentIndex = i
+				break
+			}
+		}
+	}
+
+	mapper := newPaletteMapper(pal, q.AlphaAware)
+
+	// Floyd-Steinberg用の誤差バッファ（現在行と次行）
+	var curErr, nextErr []rgba
+	if q.Dither == types.DitherFloydSteinberg {
+		curErr = make([]rgba, width+2)
+		nextErr = make([]rgba, width+2)
+	}
+
+	spread := 255.0 / math.Cbrt(float64(len(pal)))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, ok := q.pixel(img, x, y)
+			if !ok {
+				if transparentIndex >= 0 {
+					dst.SetColorIndex(x, y, uint8(transparentIndex))
+				} else {
+					dst.SetColorIndex(x, y, uint8(mapper.nearest(rgba{})))
+				}
+				continue
+			}
+
+			switch q.Dither {
+			case types.DitherFloydSteinberg:
+				e := curErr[x-bounds.Min.X+1]
+				for ch := range c {
+					c[ch] += e[ch]
+				}
+			case types.DitherOrdered:
+				offset := ((bayer8x8[(y-bounds.Min.Y)&7][(x-bounds.Min.X)&7]+0.5)/64 - 0.5) * spread
+				for ch := 0; ch < 3; ch++ {
+					c[ch] += offset
+				}
+			}
+
+			c = clampColor(c, q.AlphaAware)
+			idx := mapper.nearest(c)
+			dst.SetColorIndex(x, y, uint8(idx))
+
+			if q.Dither == types.DitherFloydSteinberg {
+				p := mapper.colors[idx]
+				var e rgba
+				for ch := range e {
+					e[ch] = c[ch] - p[ch]
+				}
+				i := x - bounds.Min.X + 1
+				diffuse(&curErr[i+1], e, 7.0/16)
+				diffuse(&nextErr[i-1], e, 3.0/16)
+				diffuse(&nextErr[i], e, 5.0/16)
+				diffuse(&nextErr[i+1], e, 1.0/16)
+			}
+		}
+
+		if q.Dither == types.DitherFloydSteinberg {
+			curErr, nextErr = nextErr, curErr
+			for i := range nextErr {
+				nextErr[i] = rgba{}
+			}
+		}
+	}
+
+	return dst
+}
+
+// QuantizationError は元画像と減色後の画像の誤差（RGBAチャンネルのRMSE、0-255）を返します
+func QuantizationError(src image.Image, dst image.Image) float64 {
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return 0
+	}
+
+	sum := 0.0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := src.At(x, y).RGBA()
+			r2, g2, b2, a2 := dst.At(x, y).RGBA()
+			for _, d := range [4]float64{
+				float64(r1>>8) - float64(r2>>8),
+				float64(g1>>8) - float64(g2>>8),
+				float64(b1>>8) - float64(b2>>8),
+				float64(a1>>8) - float64(a2>>8),
+			} {
+				sum += d * d
+			}
+		}
+	}
+
+	return math.Sqrt(sum / float64(bounds.Dx()*bounds.Dy()*4))
+}
+
+// diffuse は誤差を重み付きで加算します
+func diffuse(e *rgba, err rgba, weight float64) {
+	for ch := range e {
+		e[ch] += err[ch] * weight
+	}
+}
+
+// clampColor は色を有効な範囲に収めます
+// 乗算済みの場合はRGBがアルファを超えないようにします
+func clampColor(c rgba, premultiplied bool) rgba {
+	for ch := range c {
+		c[ch] = math.Max(0, math.Min(255, c[ch]))
+	}
+	if premultiplied {
+		for ch := 0; ch < 3; ch++ {
+			c[ch] = math.Min(c[ch], c[3])
+		}
+	}
+	return c
+}
+
+// unpremultiply は乗算済みの16ビット色を0-255のストレートアルファ色に変換します
+func unpremultiply(r, g, b, a uint32) (float64, float64, float64) {
+	if a == 0 {
+		return 0, 0, 0
+	}
+	if a == 0xffff {
+		return float64(r >> 8), float64(g >> 8), float64(b >> 8)
+	}
+	scale := 255.0 / float64(a)
+	return float64(r) * scale, float64(g) * scale, float64(b) * scale
+}
+
+// clamp255 は値を0-255の整数に丸めます
+func clamp255(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// buildHistogram は色ヒストグラムを作成します
+// RGBは各5ビット、アルファは4ビットに量子化したキーでビンを分けます
+func (q *Quantizer) buildHistogram(img image.Image) ([]colorBin, bool) {
+	type accumulator struct {
+		sum   rgba
+		count float64
+	}
+	hist := make(map[uint32]*accumulator)
+	hasTransparent := false
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c, ok := q.pixel(img, x, y)
+			if !ok {
+				hasTransparent = true
+				continue
+			}
+			key := uint32(clamp255(c[0]))>>3<<14 |
+				uint32(clamp255(c[1]))>>3<<9 |
+				uint32(clamp255(c[2]))>>3<<4 |
+				uint32(clamp255(c[3]))>>4
+			acc, exists := hist[key]
+			if !exists {
+				acc = &accumulator{}
+				hist[key] = acc
+			}
+			for ch := range c {
+				acc.sum[ch] += c[ch]
+			}
+			acc.count++
+		}
+	}
+
+	// マップの反復順序に依存しないようキー順に並べる
+	keys := make([]uint32, 0, len(hist))
+	for key := range hist {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	bins := make([]colorBin, 0, len(keys))
+	for _, key := range keys {
+		acc := hist[key]
+		var avg rgba
+		for ch := range avg {
+			avg[ch] = acc.sum[ch] / acc.count
+		}
+		bins = append(bins, colorBin{c: avg, count: acc.count})
+	}
+
+	return bins, hasTransparent
+}
+
+// medianCut はビンの集合を最大numColors個のボックスに分割します
+func medianCut(bins []colorBin, numColors int) []colorBox {
+	if len(bins) == 0 || numColors <= 0 {
+		return nil
+	}
+
+	boxes := []colorBox{{bins: bins}}
+	for len(boxes) < numColors {
+		// 「色の広がり×ピクセル数」が最大の分割可能なボックスを選ぶ
+		best := -1
+		bestScore := 0.0
+		for i, box := range boxes {
+			if len(box.bins) < 2 {
+				continue
+			}
+			_, spread := box.widestChannel()
+			score := spread * box.population()
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		left, right := boxes[best].split()
+		boxes[best] = left
+		boxes = append(boxes, right)
+	}
+
+	return boxes
+}
+
+// population はボックス内のピクセル数を返します
+func (b colorBox) population() float64 {
+	total := 0.0
+	for _, bin := range b.bins {
+		total += bin.count
+	}
+	return total
+}
+
+// widestChannel は最も値の範囲が広いチャンネル（0=R, 1=G, 2=B, 3=A）とその範囲を返します
+func (b colorBox) widestChannel() (int, float64) {
+	minC := rgba{255, 255, 255, 255}
+	maxC := rgba{0, 0, 0, 0}
+	for _, bin := range b.bins {
+		for ch, v := range bin.c {
+			minC[ch] = math.Min(minC[ch], v)
+			maxC[ch] = math.Max(maxC[ch], v)
+		}
+	}
+
+	channel := 0
+	spread := maxC[0] - minC[0]
+	for ch := 1; ch < 4; ch++ {
+		if s := maxC[ch] - minC[ch]; s > spread {
+			channel, spread = ch, s
+		}
+	}
+	return channel, spread
+}
+
+// split はボックスを最も広いチャンネルの重み付き中央値で2分割します
+func (b colorBox) split() (colorBox, colorBox) {
+	channel, _ := b.widestChannel()
+
+	sort.SliceStable(b.bins, func(i, j int) bool {
+		return b.bins[i].c[channel] < b.bins[j].c[channel]
+	})
+
+	half := b.population() / 2
+	acc := 0.0
+	cut := 1
+	for i, bin := range b.bins {
+		acc += bin.count
+		if acc >= half {
+			cut = i + 1
+			break
+		}
+	}
+	// 両側に最低1つのビンが残るようにする
+	if cut >= len(b.bins) {
+		cut = len(b.bins) - 1
+	}
+
+	return colorBox{bins: b.bins[:cut]}, colorBox{bins: b.bins[cut:]}
+}
+
+// average はボックスの重み付き平均色を返します
+func (b colorBox) average() rgba {
+	var sum rgba
+	total := 0.0
+	for _, bin := range b.bins {
+		for ch := range sum {
+			sum[ch] += bin.c[ch] * bin.count
+		}
+		total += bin.count
+	}
+	for ch := range sum {
+		sum[ch] /= total
+	}
+	return sum
+}
+
+// paletteCacheBits は最近傍インデックスのキャッシュの大きさ（2の累乗の指数）です
+// 固定サイズの表とし、色数の多い写真でもメモリ使用量が増え続けないようにします
+const paletteCacheBits = 14
+
+// paletteCacheEntry は最近傍インデックスのキャッシュの1要素です
+// indexは最近傍のインデックス+1を保持し、0は空を表します
+type paletteCacheEntry struct {
+	key   uint32
+	index uint16
+}
+
+// paletteMapper は色からパレットの最近傍インデックスへの変換をキャッシュします
+type paletteMapper struct {
+	colors     []rgba
+	candidates []bool // 最近傍探索の候補とするか
+	cache      []paletteCacheEntry
+}
+
+// newPaletteMapper はpaletteMapperを作成します
+// alphaAwareがfalseの場合、透過色は最近傍の候補から除外されます
+func newPaletteMapper(pal color.Palette, alphaAware bool) *paletteMapper {
+	m := &paletteMapper{
+		colors:     make([]rgba, len(pal)),
+		candidates: make([]bool, len(pal)),
+		cache:      make([]paletteCacheEntry, 1<<paletteCacheBits),
+	}
+	for i, c := range pal {
+		r, g, b, a := c.RGBA()
+		if alphaAware {
+			m.colors[i] = rgba{float64(r >> 8), float64(g >> 8), float64(b >> 8), float64(a >> 8)}
+			m.candidates[i] = true
+			continue
+		}
+		ur, ug, ub := unpremultiply(r, g, b, a)
+		m.colors[i] = rgba{ur, ug, ub, 255}
+		m.candidates[i] = a != 0
+	}
+	return m
+}
+
+// nearest は色に最も近いパレットのインデックスを返します
+// キャッシュは直接マップ方式で、衝突した場合は新しい色で上書きします
+func (m *paletteMapper) nearest(c rgba) int {
+	key := uint32(clamp255(c[0]))<<24 | uint32(clamp255(c[1]))<<16 |
+		uint32(clamp255(c[2]))<<8 | uint32(clamp255(c[3]))
+	slot := &m.cache[(key*2654435761)>>(32-paletteCacheBits)]
+	if slot.index != 0 && slot.key == key {
+		return int(slot.index) - 1
+	}
+
+	best := 0
+	bestDist := math.Inf(1)
+	for i, p := range m.colors {
+		if !m.candidates[i] {
+			continue
+		}
+		dist := 0.0
+		for ch := range p {
+			d := p[ch] - c[ch]
+			dist += d * d
+		}
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	*slot = paletteCacheEntry{key: key, index: uint16(best + 1)}
+	return best
+}
@@ -0,0 +1,181 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestQuantizer_PaletteSize(t *testing.T) {
+	img := createTestImage(64, 64)
+
+	for _, numColors := range []int{2, 16, 64, 256} {
+		paletted := NewQuantizer(numColors, types.DitherNone).Quantize(img)
+		if len(paletted.Palette) > numColors {
+			t.Errorf("Expected at most %d colors, got %d", numColors, len(paletted.Palette))
+		}
+	}
+}
+
+func TestQuantizer_ExactColorsPreserved(t *testing.T) {
+	// 色数が上限以下の場合、元の色がそのまま再現される
+	colors := []color.RGBA{
+		{R: 255, A: 255},
+		{G: 200, A: 255},
+		{B: 150, A: 255},
+		{R: 10, G: 20, B: 30, A: 255},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetRGBA(x, y, colors[(x/2+y/4)%len(colors)])
+		}
+	}
+
+	paletted := NewQuantizer(16, types.DitherFloydSteinberg).Quantize(img)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			expected := img.RGBAAt(x, y)
+			r, g, b, a := paletted.At(x, y).RGBA()
+			got := color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(a >> 8)}
+			if got != expected {
+				t.Fatalf("Pixel (%d,%d): expected %v, got %v", x, y, expected, got)
+			}
+		}
+	}
+}
+
+func TestQuantizer_TransparencyPreserved(t *testing.T) {
+	img := createTestImage(32, 32).(*image.RGBA)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetRGBA(x, y, color.RGBA{})
+		}
+	}
+
+	paletted := NewQuantizer(32, types.DitherFloydSteinberg).Quantize(img)
+
+	transparentCount := 0
+	for _, c := range paletted.Palette {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			transparentCount++
+		}
+	}
+	if transparentCount != 1 {
+		t.Fatalf("Expected exactly 1 transparent palette entry, got %d", transparentCount)
+	}
+
+	if _, _, _, a := paletted.At(5, 5).RGBA(); a != 0 {
+		t.Errorf("Expected transparent pixel at (5,5), got %v", paletted.At(5, 5))
+	}
+	if _, _, _, a := paletted.At(20, 20).RGBA(); a == 0 {
+		t.Errorf("Expected opaque pixel at (20,20)")
+	}
+}
+
+func TestQuantizer_DitherModes(t *testing.T) {
+	img := createTestImage(50, 40)
+	modes := []types.DitherMode{types.DitherNone, types.DitherFloydSteinberg, types.DitherOrdered}
+
+	for _, mode := range modes {
+		paletted := NewQuantizer(8, mode).Quantize(img)
+		if paletted.Bounds() != img.Bounds() {
+			t.Errorf("%s: expected bounds %v, got %v", mode, img.Bounds(), paletted.Bounds())
+		}
+		for _, idx := range paletted.Pix {
+			if int(idx) >= len(paletted.Palette) {
+				t.Fatalf("%s: palette index %d out of range", mode, idx)
+			}
+		}
+	}
+}
+
+func TestImageSaver_SaveGIF_Colors(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "out.gif")
+
+	saver := NewImageSaverWithOptions(types.EncodeOptions{Colors: 16, Dither: types.DitherOrdered})
+	if err := saver.Save(createTestImage(64, 64), path, types.FormatGIF, 85); err != nil {
+		t.Fatalf("Failed to save GIF: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open GIF: %v", err)
+	}
+	defer file.Close()
+
+	img, err := gif.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode GIF: %v", err)
+	}
+
+	paletted, ok := img.(*image.Paletted)
+	if !ok {
+		t.Fatalf("Expected paletted image, got %T", img)
+	}
+	if len(paletted.Palette) > 16 {
+		t.Errorf("Expected at most 16 colors, got %d", len(paletted.Palette))
+	}
+}
+
+func TestQuantizer_AlphaAware(t *testing.T) {
+	// 半透明のグラデーションを含む画像
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 32; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 200, G: 50, B: 50, A: uint8(x * 8)})
+		}
+	}
+
+	quantizer := NewQuantizer(64, types.DitherNone)
+	quantizer.AlphaAware = true
+	paletted := quantizer.Quantize(img)
+
+	translucent := 0
+	for _, c := range paletted.Palette {
+		if _, _, _, a := c.RGBA(); a > 0 && a < 0xffff {
+			translucent++
+		}
+	}
+	if translucent < 2 {
+		t.Errorf("Expected multiple translucent palette entries, got %d", translucent)
+	}
+
+	if e := QuantizationError(img, paletted); e > 4 {
+		t.Errorf("Expected small quantization error, got %f", e)
+	}
+}
+
+func TestPaletteMapper_BoundedCache(t *testing.T) {
+	pal := color.Palette{}
+	for i := 0; i < 64; i++ {
+		pal = append(pal, color.RGBA{R: uint8(i * 4), G: uint8(255 - i*4), B: uint8(i * 37), A: 255})
+	}
+	cached := newPaletteMapper(pal, true)
+
+	// キャッシュより多くの色を2周して、衝突で上書きされた後も最近傍が正しいことを確認
+	for pass := 0; pass < 2; pass++ {
+		for i := 0; i < 4<<paletteCacheBits; i++ {
+			c := rgba{float64(i * 7 % 256), float64(i * 13 % 256), float64(i * 31 % 256), 255}
+			want, wantDist := 0, math.Inf(1)
+			for j, p := range cached.colors {
+				if dist := (p[0]-c[0])*(p[0]-c[0]) + (p[1]-c[1])*(p[1]-c[1]) + (p[2]-c[2])*(p[2]-c[2]); dist < wantDist {
+					want, wantDist = j, dist
+				}
+			}
+			if got := cached.nearest(c); got != want {
+				t.Fatalf("nearest(%v) = %d, want %d", c, got, want)
+			}
+		}
+	}
+	if len(cached.cache) != 1<<paletteCacheBits {
+		t.Errorf("Cache grew to %d entries, want %d", len(cached.cache), 1<<paletteCacheBits)
+	}
+}
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// panicMagic はデコード時にpanicするテスト用フォーマットのマジックナンバーです
+const panicMagic = "PANIC!"
+
+func init() {
+	// 細工された入力でpanicするデコーダーを再現
+	decode := func(io.Reader) (image.Image, error) { panic("decoder bug") }
+	decodeConfig := func(io.Reader) (image.Config, error) { panic("decoder bug") }
+	image.RegisterFormat("panic", panicMagic, decode, decodeConfig)
+}
+
+// panicReader はdataを読み終えた後の読み込みでpanicするReaderです
+type panicReader struct {
+	data []byte
+}
+
+func (r *panicReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		panic("reader bug")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestConverter_RecoversDecoderPanic(t *testing.T) {
+	tempDir := t.TempDir()
+	bomb := filepath.Join(tempDir, "bomb.png")
+	if err := os.WriteFile(bomb, []byte(panicMagic+"payload"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	conv := NewConverter(types.Config{Format: "jpeg"})
+
+	result := conv.ConvertImage(bomb, tempDir)
+	if result.Success || !errors.Is(result.Error, ErrPanic) || result.SourcePath != bomb {
+		t.Errorf("ConvertImage: expected a failed result with ErrPanic, got %+v", result)
+	}
+
+	result = conv.ConvertImageTo(bomb, filepath.Join(tempDir, "out.jpg"))
+	if result.Success || !errors.Is(result.Error, ErrPanic) {
+		t.Errorf("ConvertImageTo: expected a failed result with ErrPanic, got %+v", result)
+	}
+
+	variants := NewConverter(types.Config{Variants: []types.Variant{{Suffix: "-a"}, {Suffix: "-b"}}}).ConvertImageVariants(bomb, tempDir)
+	if len(variants) != 1 || variants[0].Success || !errors.Is(variants[0].Error, ErrPanic) {
+		t.Errorf("ConvertImageVariants: expected a single failed result with ErrPanic, got %+v", variants)
+	}
+}
+
+func TestConverter_ConvertStream_RecoversPanic(t *testing.T) {
+	// フォーマットの判定後、デコード中にpanicする
+	var png bytes.Buffer
+	if err := NewImageSaver().Encode(&png, createTestImage(4, 4), types.FormatPNG, 0); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	r := &panicReader{data: png.Bytes()[:SniffHeaderSize]}
+
+	result := NewConverter(types.Config{}).ConvertStream(r, io.Discard, types.FormatJPEG)
+	if result.Success || !errors.Is(result.Error, ErrPanic) || result.SourcePath != "-" {
+		t.Errorf("Expected a failed result with ErrPanic, got %+v", result)
+	}
+}
+
+func TestProcessFiles_PanicDoesNotAbortBatch(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	var files []string
+	for _, name := range []string{"a.png", "b.png", "c.png"} {
+		path := filepath.Join(tempDir, name)
+		saveTestImage(t, path, createTestImage(10, 10))
+		files = append(files, path)
+	}
+	bomb := filepath.Join(tempDir, "bomb.png")
+	if err := os.WriteFile(bomb, []byte(panicMagic+"payload"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	files = append(files, bomb)
+
+	conv := NewConverter(types.Config{Format: "jpeg"})
+	if err := conv.ProcessFiles(files, outputDir); err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+
+	stats := conv.GetStats()
+	if stats.Success != 3 || stats.Failed != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		assertExists(t, filepath.Join(outputDir, name), true)
+	}
+}
+
+func TestWriteFileAtomic_PanicLeavesNoTempFile(t *testing.T) {
+	dir := t.TempDir()
+	func() {
+		defer func() {
+			if recovered := recover(); recovered == nil {
+				t.Error("Expected the panic to propagate")
+			}
+		}()
+		WriteFileAtomic(filepath.Join(dir, "out.png"), func(w io.Writer) error {
+			w.Write([]byte("partial"))
+			panic("encoder bug")
+		})
+	}()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Unexpected file left after panic: %s", entry.Name())
+	}
+}
@@ -27,6 +27,10 @@ func (rc *ResizeCalculator) CalculateOutputSize(srcWidth, srcHeight int, spec ty
 
 	// 幅と高さ両方指定の場合（範囲内に収める）
 	if spec.Width > 0 && spec.Height > 0 {
+		// coverの場合は指定サイズちょうど（はみ出した部分は切り取る）
+		if spec.Fit == types.FitCover {
+			return spec.Width, spec.Height
+		}
 		scaleW := float64(spec.Width) / float64(srcWidth)
 		scaleH := float64(spec.Height) / float64(srcHeight)
 		scale := math.Min(scaleW, scaleH)
@@ -81,11 +85,35 @@ func (rc *ResizeCalculator) ResizeImage(src image.Image, spec types.ResizeSpec)
 		return src
 	}
 
+	// coverの場合は出力と同じ縦横比になるように元画像の中央を切り取る
+	srcRect := bounds
+	if spec.Fit == types.FitCover && spec.Width > 0 && spec.Height > 0 {
+		srcRect = coverCrop(bounds, dstWidth, dstHeight)
+	}
+
 	// 新しい画像を作成
 	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
 
 	// CatmullRomスケーラーを使用して高品質リサイズ
-	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Over, nil)
 
 	return dst
 }
+
+// coverCrop は出力サイズと同じ縦横比になる元画像の中央の範囲を返します
+func coverCrop(bounds image.Rectangle, dstWidth, dstHeight int) image.Rectangle {
+	srcWidth := bounds.Dx()
+	srcHeight := bounds.Dy()
+
+	// 幅を維持して高さを切り取り、高さが足りない場合は高さを維持して幅を切り取る
+	cropWidth := srcWidth
+	cropHeight := int(math.Round(float64(srcWidth) * float64(dstHeight) / float64(dstWidth)))
+	if cropHeight > srcHeight {
+		cropWidth = int(math.Round(float64(srcHeight) * float64(dstWidth) / float64(dstHeight)))
+		cropHeight = srcHeight
+	}
+
+	x := bounds.Min.X + (srcWidth-cropWidth)/2
+	y := bounds.Min.Y + (srcHeight-cropHeight)/2
+	return image.Rect(x, y, x+cropWidth, y+cropHeight)
+}
@@ -2,6 +2,7 @@ package converter
 
 import (
 	"image"
+	"image/color"
 	"math"
 	"testing"
 	"testing/quick"
@@ -498,6 +499,43 @@ func TestResizeImage_BothDimensions(t *testing.T) {
 	}
 }
 
+// TestResizeImage_Cover はcover指定で指定サイズちょうどに中央を切り取ってリサイズすることをテストします
+func TestResizeImage_Cover(t *testing.T) {
+	rc := NewResizeCalculator()
+
+	// 左右の端が赤、中央が青の画像を作成（300x100）
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{0, 0, 255, 255}
+			if x < 100 || x >= 200 {
+				c = color.RGBA{255, 0, 0, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	spec := types.ResizeSpec{Width: 50, Height: 50, Fit: types.FitCover}
+
+	if w, h := rc.CalculateOutputSize(300, 100, spec); w != 50 || h != 50 {
+		t.Errorf("Expected 50x50, got %dx%d", w, h)
+	}
+
+	result := rc.ResizeImage(src, spec)
+	bounds := result.Bounds()
+	if bounds.Dx() != 50 || bounds.Dy() != 50 {
+		t.Fatalf("Expected 50x50, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	// 中央の正方形のみが使われるので、端も青になるはず
+	for _, x := range []int{0, 25, 49} {
+		r, _, b, _ := result.At(x, 25).RGBA()
+		if r > b {
+			t.Errorf("Expected blue at x=%d, got r=%d b=%d", x, r>>8, b>>8)
+		}
+	}
+}
+
 // TestResizeImage_SameSize は元のサイズと同じサイズにリサイズする場合に元の画像を返すことをテストします
 func TestResizeImage_SameSize(t *testing.T) {
 	rc := NewResizeCalculator()
@@ -0,0 +1,148 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"image-converter/internal/types"
+)
+
+// MatchRule は入力ファイルに一致する最初の規則を返します（一致しない場合はnil）
+// 寸法の条件を持つ規則がある場合のみ、画像のヘッダーを読み込みます
+func (c *Converter) MatchRule(sourcePath string) (*types.Rule, error) {
+	if len(c.config.Rules) == 0 {
+		return nil, nil
+	}
+
+	relPath := relativeSlashPath(c.config.InputDir, sourcePath)
+	var size *image.Point
+
+	for i := range c.config.Rules {
+		rule := &c.config.Rules[i]
+		match := rule.Match
+
+		if match.Glob != "" && !matchGlob(match.Glob, relPath) {
+			continue
+		}
+
+		if len(match.Formats) > 0 {
+			format, err := c.formatDetector.DetectFormat(sourcePath)
+			if err != nil || !containsFormat(c.formatDetector, match.Formats, format) {
+				continue
+			}
+		}
+
+		if hasSizeCondition(match) {
+			if size == nil {
+				bounds, err := decodeDimensions(sourcePath)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read image size for rule %q: %w", rule.Name, err)
+				}
+				size = &bounds
+			}
+			if !sizeMatches(match, *size) {
+				continue
+			}
+		}
+
+		return rule, nil
+	}
+
+	return nil, nil
+}
+
+// forRule は規則を基本設定に適用した設定で動作するConverterを返します
+func (c *Converter) forRule(rule *types.Rule) *Converter {
+	config := c.config
+	config.Rules = nil
+
+	if rule.Resize != (types.ResizeSpec{}) {
+		config.Scale = rule.Resize.Scale
+		config.Width = rule.Resize.Width
+		config.Height = rule.Resize.Height
+	}
+	if rule.Format != "" {
+		config.Format = rule.Format
+	}
+	if rule.Quality > 0 {
+		config.JPEGQuality = rule.Quality
+	}
+	if rule.Colors > 0 {
+		config.Colors = rule.Colors
+	}
+	if rule.Dither != "" {
+		config.Dither = rule.Dither
+	}
+	if rule.PNGPalette != nil {
+		config.PNGPalette = *rule.PNGPalette
+	}
+	if rule.JPEGProgressive != nil {
+		config.JPEGProgressive = *rule.JPEGProgressive
+	}
+	if rule.JPEGSubsampling != "" {
+		config.JPEGSubsampling = rule.JPEGSubsampling
+	}
+
+	return NewConverterWithCache(config, c.cache)
+}
+
+// matchGlob はパターンが/を含む場合は相対パス全体、含まない場合はファイル名と照合します
+func matchGlob(pattern, relPath string) bool {
+	target := relPath
+	if !strings.Contains(pattern, "/") {
+		target = path.Base(relPath)
+	}
+	matched, err := path.Match(pattern, target)
+	return err == nil && matched
+}
+
+// containsFormat はフォーマットの一覧に指定されたフォーマットが含まれるか判定します
+func containsFormat(fd *FormatDetector, formats []string, format types.ImageFormat) bool {
+	for _, f := range formats {
+		if fd.NormalizeFormat(f) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSizeCondition は寸法の条件が指定されているか判定します
+func hasSizeCondition(match types.RuleMatch) bool {
+	return match.MinWidth > 0 || match.MaxWidth > 0 || match.MinHeight > 0 || match.MaxHeight > 0
+}
+
+// sizeMatches は画像の寸法が条件を満たすか判定します
+func sizeMatches(match types.RuleMatch, size image.Point) bool {
+	if match.MinWidth > 0 && size.X < match.MinWidth {
+		return false
+	}
+	if match.MaxWidth > 0 && size.X > match.MaxWidth {
+		return false
+	}
+	if match.MinHeight > 0 && size.Y < match.MinHeight {
+		return false
+	}
+	if match.MaxHeight > 0 && size.Y > match.MaxHeight {
+		return false
+	}
+	return true
+}
+
+// decodeDimensions は画像全体をデコードせずに寸法を取得します
+func decodeDimensions(sourcePath string) (image.Point, error) {
+	file, err := os.Open(filepath.Clean(sourcePath))
+	if err != nil {
+		return image.Point{}, err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return image.Point{}, err
+	}
+	return image.Point{X: config.Width, Y: config.Height}, nil
+}
@@ -0,0 +1,132 @@
+package converter
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestConverter_MatchRule(t *testing.T) {
+	tempDir := t.TempDir()
+	iconPath := filepath.Join(tempDir, "icons", "logo.png")
+	photoPath := filepath.Join(tempDir, "photos", "beach.png")
+	for _, p := range []string{iconPath, photoPath} {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	saveTestImage(t, iconPath, createTestImage(32, 32))
+	saveTestImage(t, photoPath, createTestImage(400, 300))
+
+	config := types.Config{
+		InputDir: tempDir,
+		Rules: []types.Rule{
+			{Name: "large", Match: types.RuleMatch{MinWidth: 300}},
+			{Name: "icons", Match: types.RuleMatch{Glob: "icons/*.png", Formats: []string{"png"}}},
+			{Name: "jpeg", Match: types.RuleMatch{Glob: "*.jpg"}},
+		},
+	}
+	converter := NewConverter(config)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{iconPath, "icons"},
+		{photoPath, "large"},
+	}
+	for _, tt := range tests {
+		rule, err := converter.MatchRule(tt.path)
+		if err != nil {
+			t.Fatalf("MatchRule failed: %v", err)
+		}
+		if rule == nil || rule.Name != tt.expected {
+			t.Errorf("MatchRule(%s) = %v, want %s", tt.path, rule, tt.expected)
+		}
+	}
+
+	// 一致する規則がない場合はnil
+	other := filepath.Join(tempDir, "other.png")
+	saveTestImage(t, other, createTestImage(10, 10))
+	if rule, err := converter.MatchRule(other); err != nil || rule != nil {
+		t.Errorf("Expected no rule, got %v (err=%v)", rule, err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		relPath string
+		want    bool
+	}{
+		{"icons/*.png", "icons/logo.png", true},
+		{"icons/*.png", "photos/logo.png", false},
+		{"icons/*.png", "icons/sub/logo.png", false},
+		{"*.png", "icons/sub/logo.png", true},
+		{"logo.*", "icons/logo.gif", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.relPath); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.relPath, got, tt.want)
+		}
+	}
+}
+
+func TestConverter_ConvertImage_AppliesRule(t *testing.T) {
+	tempDir := t.TempDir()
+	inputDir := filepath.Join(tempDir, "input")
+	outputDir := filepath.Join(tempDir, "output")
+	for _, dir := range []string{filepath.Join(inputDir, "icons"), outputDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	iconPath := filepath.Join(inputDir, "icons", "logo.png")
+	photoPath := filepath.Join(inputDir, "photo.png")
+	saveTestImage(t, iconPath, createTestImage(256, 256))
+	saveTestImage(t, photoPath, createTestImage(200, 100))
+
+	config := types.Config{
+		InputDir:    inputDir,
+		OutputDir:   outputDir,
+		Format:      "jpeg",
+		Width:       100,
+		JPEGQuality: 85,
+		Rules: []types.Rule{
+			{Name: "icons", Match: types.RuleMatch{Glob: "icons/*"}, Resize: types.ResizeSpec{Width: 64}, Format: "png"},
+		},
+	}
+	converter := NewConverter(config)
+
+	icon := converter.ConvertImage(iconPath, outputDir)
+	if !icon.Success {
+		t.Fatalf("Icon conversion failed: %v", icon.Error)
+	}
+	if icon.Rule != "icons" || icon.Format != types.FormatPNG || icon.Width != 64 {
+		t.Errorf("Rule not applied: %+v", icon)
+	}
+	if filepath.Ext(icon.OutputPath) != ".png" {
+		t.Errorf("Unexpected output path: %s", icon.OutputPath)
+	}
+
+	photo := converter.ConvertImage(photoPath, outputDir)
+	if !photo.Success {
+		t.Fatalf("Photo conversion failed: %v", photo.Error)
+	}
+	if photo.Rule != "" || photo.Format != types.FormatJPEG || photo.Width != 100 {
+		t.Errorf("Base config not applied: %+v", photo)
+	}
+
+	// 規則で指定した形式で書き込まれていることを確認
+	file, err := os.Open(icon.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer file.Close()
+	if _, format, err := image.DecodeConfig(file); err != nil || format != "png" {
+		t.Errorf("Expected png output, got %s (err=%v)", format, err)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/chai2010/webp"
 	"golang.org/x/image/bmp"
@@ -15,48 +17,101 @@ import (
 )
 
 // ImageSaver は画像ファイルの保存を提供します
-type ImageSaver struct{}
+type ImageSaver struct {
+	options types.EncodeOptions
+}
 
-// NewImageSaver は新しいImageSaverを作成します
+// NewImageSaver はデフォルト設定の新しいImageSaverを作成します
 func NewImageSaver() *ImageSaver {
 	return &ImageSaver{}
 }
 
+// NewImageSaverWithOptions はエンコーダー設定を指定してImageSaverを作成します
+func NewImageSaverWithOptions(options types.EncodeOptions) *ImageSaver {
+	return &ImageSaver{options: options}
+}
+
+// quantizer はエンコーダー設定に基づくQuantizerを返します
+func (is *ImageSaver) quantizer() *Quantizer {
+	return NewQuantizer(is.options.Colors, is.options.Dither)
+}
+
 // Save は画像を指定されたパスとフォーマットで保存します
 // formatはImageFormat型の文字列（jpeg, png, webp, gif, bmp）
 // qualityはJPEG保存時の品質（1-100）、他のフォーマットでは無視されます
 func (is *ImageSaver) Save(img image.Image, path string, format types.ImageFormat, quality int) error {
-	// ファイルを作成
-	file, err := os.Create(path)
+	return WriteFileAtomic(path, func(w io.Writer) error {
+		return is.Encode(w, img, format, quality)
+	})
+}
+
+// WriteFileAtomic はpathと同じディレクトリの一時ファイルにwriteで書き込み、成功した場合のみpathに置き換えます
+// writeや書き込みの完了（Close）に失敗した場合やwriteがpanicした場合は一時ファイルを削除し、pathには空や途中までのファイルを残しません
+func WriteFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer file.Close()
+	renamed := false
+	defer func() {
+		// panicの場合もdeferは実行されるため、置き換えなかった一時ファイルは必ず削除する
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
+	if err := write(tmp); err != nil {
+		return err
+	}
+	// 一時ファイルは所有者のみ読み書きできるため、os.Createと同じ権限にする
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	renamed = true
+	return nil
+}
+
+// Encode は画像を指定されたフォーマットでwに書き込みます
+func (is *ImageSaver) Encode(w io.Writer, img image.Image, format types.ImageFormat, quality int) error {
 	// フォーマットに応じてエンコード
 	switch format {
 	case types.FormatJPEG:
-		return is.saveJPEG(file, img, quality)
+		return is.saveJPEG(w, img, quality)
 	case types.FormatPNG:
-		return is.savePNG(file, img)
+		return is.savePNG(w, img)
 	case types.FormatWebP:
-		return is.saveWebP(file, img, quality)
+		return is.saveWebP(w, img, quality)
 	case types.FormatGIF:
-		return is.saveGIF(file, img)
+		return is.saveGIF(w, img)
 	case types.FormatBMP:
-		return is.saveBMP(file, img)
+		return is.saveBMP(w, img)
 	default:
 		return fmt.Errorf("unsupported output format: %s", format)
 	}
 }
 
 // saveJPEG はJPEG形式で画像を保存します
-func (is *ImageSaver) saveJPEG(file *os.File, img image.Image, quality int) error {
+// プログレッシブまたは4:2:0以外のサブサンプリングが指定された場合は独自エンコーダーを使用します
+func (is *ImageSaver) saveJPEG(w io.Writer, img image.Image, quality int) error {
+	if is.options.JPEGProgressive || (is.options.JPEGSubsampling != "" && is.options.JPEGSubsampling != types.Subsampling420) {
+		if err := EncodeJPEG(w, img, quality, is.options.JPEGProgressive, is.options.JPEGSubsampling); err != nil {
+			return fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+		return nil
+	}
+
 	options := &jpeg.Options{
 		Quality: quality,
 	}
 	
-	if err := jpeg.Encode(file, img, options); err != nil {
+	if err := jpeg.Encode(w, img, options); err != nil {
 		return fmt.Errorf("failed to encode JPEG: %w", err)
 	}
 	
@@ -64,27 +119,46 @@ func (is *ImageSaver) saveJPEG(file *os.File, img image.Image, quality int) erro
 }
 
 // savePNG はPNG形式で画像を保存します
-func (is *ImageSaver) savePNG(file *os.File, img image.Image) error {
+// パレットモードでは減色誤差が許容範囲内の場合のみパレット形式で出力します
+func (is *ImageSaver) savePNG(w io.Writer, img image.Image) error {
 	encoder := &png.Encoder{
 		CompressionLevel: png.DefaultCompression,
 	}
+
+	if is.options.PNGPalette {
+		img = is.palettePNG(img)
+	}
 	
-	if err := encoder.Encode(file, img); err != nil {
+	if err := encoder.Encode(w, img); err != nil {
 		return fmt.Errorf("failed to encode PNG: %w", err)
 	}
 	
 	return nil
 }
 
+// palettePNG は画像を半透明対応のパレット画像に変換します
+// 減色誤差が上限を超えた場合は元の画像（フルカラー）を返します
+func (is *ImageSaver) palettePNG(img image.Image) image.Image {
+	quantizer := is.quantizer()
+	quantizer.AlphaAware = true
+	paletted := quantizer.Quantize(img)
+
+	if is.options.PNGMaxError > 0 && QuantizationError(img, paletted) > is.options.PNGMaxError {
+		return img
+	}
+
+	return paletted
+}
+
 // saveWebP はWebP形式で画像を保存します
-func (is *ImageSaver) saveWebP(file *os.File, img image.Image, quality int) error {
+func (is *ImageSaver) saveWebP(w io.Writer, img image.Image, quality int) error {
 	// WebPエンコーダーのオプション設定
 	options := &webp.Options{
 		Lossless: false,
 		Quality:  float32(quality),
 	}
 	
-	if err := webp.Encode(file, img, options); err != nil {
+	if err := webp.Encode(w, img, options); err != nil {
 		return fmt.Errorf("failed to encode WebP: %w", err)
 	}
 	
@@ -92,12 +166,14 @@ func (is *ImageSaver) saveWebP(file *os.File, img image.Image, quality int) erro
 }
 
 // saveGIF はGIF形式で画像を保存します
-func (is *ImageSaver) saveGIF(file *os.File, img image.Image) error {
+// 画像固有のパレットを生成し、設定されたディザリング方式で減色します
+func (is *ImageSaver) saveGIF(w io.Writer, img image.Image) error {
+	paletted := is.quantizer().Quantize(img)
 	options := &gif.Options{
-		NumColors: 256,
+		NumColors: len(paletted.Palette),
 	}
 	
-	if err := gif.Encode(file, img, options); err != nil {
+	if err := gif.Encode(w, paletted, options); err != nil {
 		return fmt.Errorf("failed to encode GIF: %w", err)
 	}
 	
@@ -105,10 +181,72 @@ func (is *ImageSaver) saveGIF(file *os.File, img image.Image) error {
 }
 
 // saveBMP はBMP形式で画像を保存します
-func (is *ImageSaver) saveBMP(file *os.File, img image.Image) error {
-	if err := bmp.Encode(file, img); err != nil {
+func (is *ImageSaver) saveBMP(w io.Writer, img image.Image) error {
+	if err := bmp.Encode(w, img); err != nil {
 		return fmt.Errorf("failed to encode BMP: %w", err)
 	}
 	
 	return nil
 }
+
+// SaveAnimation はアニメーション画像を指定されたパスとフォーマットで保存します
+// フレームの表示時間とループ回数を保持します
+func (is *ImageSaver) SaveAnimation(anim *AnimatedImage, path string, format types.ImageFormat, quality int) error {
+	return WriteFileAtomic(path, func(w io.Writer) error {
+		return is.EncodeAnimation(w, anim, format, quality)
+	})
+}
+
+// EncodeAnimation はアニメーション画像を指定されたフォーマットでwに書き込みます
+func (is *ImageSaver) EncodeAnimation(w io.Writer, anim *AnimatedImage, format types.ImageFormat, quality int) error {
+	switch format {
+	case types.FormatGIF:
+		return is.saveAnimatedGIF(w, anim)
+	case types.FormatWebP:
+		return is.saveAnimatedWebP(w, anim, quality)
+	default:
+		return fmt.Errorf("animation is not supported for output format: %s", format)
+	}
+}
+
+// saveAnimatedGIF はアニメーションGIF形式で画像を保存します
+func (is *ImageSaver) saveAnimatedGIF(w io.Writer, anim *AnimatedImage) error {
+	out := &gif.GIF{
+		Image:     make([]*image.Paletted, len(anim.Frames)),
+		Delay:     make([]int, len(anim.Frames)),
+		Disposal:  make([]byte, len(anim.Frames)),
+		LoopCount: anim.LoopCount,
+	}
+
+	quantizer := is.quantizer()
+	for i, frame := range anim.Frames {
+		out.Image[i] = quantizer.Quantize(frame)
+		if i < len(anim.Delays) {
+			out.Delay[i] = anim.Delays[i]
+		}
+		// 各フレームはキャンバス全体を含むため、次のフレームの前に背景へ戻す
+		out.Disposal[i] = gif.DisposalBackground
+	}
+
+	bounds := anim.Bounds()
+	out.Config = image.Config{
+		ColorModel: out.Image[0].Palette,
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+	}
+
+	if err := gif.EncodeAll(w, out); err != nil {
+		return fmt.Errorf("failed to encode GIF: %w", err)
+	}
+
+	return nil
+}
+
+// saveAnimatedWebP はアニメーションWebP形式で画像を保存します
+func (is *ImageSaver) saveAnimatedWebP(w io.Writer, anim *AnimatedImage, quality int) error {
+	if err := encodeAnimatedWebP(w, anim, quality); err != nil {
+		return fmt.Errorf("failed to encode WebP: %w", err)
+	}
+
+	return nil
+}
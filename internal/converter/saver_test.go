@@ -1,6 +1,11 @@
 package converter
 
 import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -183,3 +188,125 @@ func TestProperty_JPEGQualityImpact(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestImageSaver_SavePNG_Palette(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "icon.png")
+
+	// 少ない色数と半透明ピクセルを含むアイコン風の画像
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			switch {
+			case x < 4:
+				img.SetNRGBA(x, y, color.NRGBA{})
+			case x < 8:
+				img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 128})
+			default:
+				img.SetNRGBA(x, y, color.NRGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	saver := NewImageSaverWithOptions(types.EncodeOptions{Colors: 16, PNGPalette: true, PNGMaxError: 8})
+	if err := saver.Save(img, path, types.FormatPNG, 85); err != nil {
+		t.Fatalf("Failed to save PNG: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open PNG: %v", err)
+	}
+	defer file.Close()
+
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+
+	paletted, ok := decoded.(*image.Paletted)
+	if !ok {
+		t.Fatalf("Expected paletted PNG, got %T", decoded)
+	}
+	if len(paletted.Palette) > 16 {
+		t.Errorf("Expected at most 16 colors, got %d", len(paletted.Palette))
+	}
+
+	// tRNSにより半透明が保持されていることを確認
+	if _, _, _, a := paletted.At(5, 5).RGBA(); a>>8 < 120 || a>>8 > 136 {
+		t.Errorf("Expected semi-transparent pixel, got alpha %d", a>>8)
+	}
+	if _, _, _, a := paletted.At(1, 1).RGBA(); a != 0 {
+		t.Errorf("Expected transparent pixel, got alpha %d", a>>8)
+	}
+}
+
+func TestImageSaver_SavePNG_PaletteFallback(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "photo.png")
+
+	// 2色ではグラデーションを表現できず、誤差が上限を超える
+	saver := NewImageSaverWithOptions(types.EncodeOptions{Colors: 2, PNGPalette: true, PNGMaxError: 1})
+	if err := saver.Save(createTestImage(64, 64), path, types.FormatPNG, 85); err != nil {
+		t.Fatalf("Failed to save PNG: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open PNG: %v", err)
+	}
+	defer file.Close()
+
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+
+	if _, ok := decoded.(*image.Paletted); ok {
+		t.Error("Expected truecolor fallback, got paletted PNG")
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "out.jpg")
+	if err := os.WriteFile(path, []byte("previous"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// 失敗した場合は既存のファイルを変更せず、一時ファイルも残さない
+	writeErr := errors.New("encode failed")
+	err := WriteFileAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("partial"))
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "previous" {
+		t.Errorf("Expected the existing file to be kept, got %q", data)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 1 {
+		t.Errorf("Expected no temporary files, got %d entries", len(entries))
+	}
+
+	// 成功した場合は置き換える
+	if err := WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("new"))
+		return err
+	}); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new" || info.Mode().Perm() != 0644 {
+		t.Errorf("Unexpected file: %q, mode %v", data, info.Mode().Perm())
+	}
+
+	// 新しいファイルも失敗した場合は作成しない
+	missing := filepath.Join(tempDir, "missing.jpg")
+	WriteFileAtomic(missing, func(w io.Writer) error { return writeErr })
+	assertExists(t, missing, false)
+}
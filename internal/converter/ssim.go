@@ -0,0 +1,102 @@
+package converter
+
+import (
+	"fmt"
+	"image"
+)
+
+// SSIMの安定化定数（ダイナミックレンジ255に対する標準値）
+const (
+	ssimC1 = (0.01 * 255) * (0.01 * 255)
+	ssimC2 = (0.03 * 255) * (0.03 * 255)
+)
+
+// ssimWindow はSSIMを計算するウィンドウの大きさ、ssimStride はウィンドウの移動量です
+const (
+	ssimWindow = 8
+	ssimStride = 4
+)
+
+// SSIM は2つの画像の構造的類似度（SSIM）を返します
+// 輝度チャンネルに対して8x8のウィンドウを4ピクセルずつずらして計算した平均値で、
+// 1.0が完全一致を表します。2つの画像は同じサイズである必要があります
+func SSIM(a, b image.Image) (float64, error) {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return 0, fmt.Errorf("image size mismatch: %v vs %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+
+	width, height := a.Bounds().Dx(), a.Bounds().Dy()
+	if width == 0 || height == 0 {
+		return 0, fmt.Errorf("empty image")
+	}
+
+	la := luminancePlane(a)
+	lb := luminancePlane(b)
+
+	// 小さい画像ではウィンドウを画像サイズに合わせる
+	window := ssimWindow
+	if width < window {
+		window = width
+	}
+	if height < window {
+		window = height
+	}
+
+	sum := 0.0
+	count := 0
+	for y := 0; y+window <= height; y += ssimStride {
+		for x := 0; x+window <= width; x += ssimStride {
+			sum += windowSSIM(la, lb, width, x, y, window)
+			count++
+		}
+	}
+
+	return sum / float64(count), nil
+}
+
+// windowSSIM は1つのウィンドウのSSIMを計算します
+func windowSSIM(la, lb []float64, stride, x0, y0, window int) float64 {
+	n := float64(window * window)
+
+	var sumA, sumB float64
+	for y := y0; y < y0+window; y++ {
+		for x := x0; x < x0+window; x++ {
+			sumA += la[y*stride+x]
+			sumB += lb[y*stride+x]
+		}
+	}
+	meanA, meanB := sumA/n, sumB/n
+
+	var varA, varB, cov float64
+	for y := y0; y < y0+window; y++ {
+		for x := x0; x < x0+window; x++ {
+			da := la[y*stride+x] - meanA
+			db := lb[y*stride+x] - meanB
+			varA += da * da
+			varB += db * db
+			cov += da * db
+		}
+	}
+	varA /= n
+	varB /= n
+	cov /= n
+
+	return ((2*meanA*meanB + ssimC1) * (2*cov + ssimC2)) /
+		((meanA*meanA + meanB*meanB + ssimC1) * (varA + varB + ssimC2))
+}
+
+// luminancePlane は画像の輝度（BT.601、0-255）を行優先の配列で返します
+// 透過ピクセルは黒背景に合成した値になります
+func luminancePlane(img image.Image) []float64 {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	plane := make([]float64, width*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			plane[(y-bounds.Min.Y)*width+(x-bounds.Min.X)] =
+				(0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+		}
+	}
+	return plane
+}
@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// addNoise は画像に決定的なノイズを加えた複製を返します
+func addNoise(src image.Image, amplitude int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	seed := uint32(12345)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// 線形合同法による擬似乱数
+			seed = seed*1664525 + 1013904223
+			n := int(seed>>24)%(2*amplitude+1) - amplitude
+			r, g, b, a := src.At(x, y).RGBA()
+			dst.SetRGBA(x, y, color.RGBA{
+				R: clamp255(float64(int(r>>8) + n)),
+				G: clamp255(float64(int(g>>8) + n)),
+				B: clamp255(float64(int(b>>8) + n)),
+				A: uint8(a >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+func TestSSIM_Identical(t *testing.T) {
+	img := createTestImage(64, 48)
+	ssim, err := SSIM(img, img)
+	if err != nil {
+		t.Fatalf("SSIM failed: %v", err)
+	}
+	if math.Abs(ssim-1) > 1e-9 {
+		t.Errorf("Expected SSIM 1 for identical images, got %f", ssim)
+	}
+}
+
+func TestSSIM_DecreasesWithNoise(t *testing.T) {
+	img := createTestImage(64, 64)
+
+	light, err := SSIM(img, addNoise(img, 5))
+	if err != nil {
+		t.Fatalf("SSIM failed: %v", err)
+	}
+	heavy, err := SSIM(img, addNoise(img, 60))
+	if err != nil {
+		t.Fatalf("SSIM failed: %v", err)
+	}
+
+	if !(1 > light && light > heavy) {
+		t.Errorf("Expected 1 > SSIM(light)=%f > SSIM(heavy)=%f", light, heavy)
+	}
+}
+
+func TestSSIM_Symmetric(t *testing.T) {
+	a := createTestImage(40, 40)
+	b := addNoise(a, 20)
+
+	ab, _ := SSIM(a, b)
+	ba, _ := SSIM(b, a)
+	if math.Abs(ab-ba) > 1e-12 {
+		t.Errorf("Expected symmetric SSIM, got %f and %f", ab, ba)
+	}
+}
+
+func TestSSIM_SmallImage(t *testing.T) {
+	img := createTestImage(3, 5)
+	if _, err := SSIM(img, img); err != nil {
+		t.Errorf("Expected SSIM to handle images smaller than the window: %v", err)
+	}
+}
+
+func TestSSIM_SizeMismatch(t *testing.T) {
+	if _, err := SSIM(createTestImage(10, 10), createTestImage(10, 11)); err == nil {
+		t.Error("Expected error for size mismatch")
+	}
+}
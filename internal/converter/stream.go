@@ -0,0 +1,121 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"image-converter/internal/types"
+)
+
+// ConvertStream はrから読み込んだ画像を変換してwに書き込みます（標準入出力によるパイプライン用）
+// 入力フォーマットは内容から判定し、formatが空の場合は設定のフォーマット、それもなければ入力と同じフォーマットで出力します
+// ファイルパスがないため、規則（Config.Rules）とバリエーションは適用されません
+func (c *Converter) ConvertStream(r io.Reader, w io.Writer, format types.ImageFormat) (result types.ConversionResult) {
+	defer recoverConversion(&result, "-")
+	result = types.ConversionResult{SourcePath: "-", OutputPath: "-"}
+
+	// 先頭のバイト列から入力フォーマットを判定
+	br := bufio.NewReader(r)
+	header, err := br.Peek(SniffHeaderSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		result.Error = fmt.Errorf("failed to read input: %w", err)
+		return result
+	}
+	inputFormat, err := SniffFormat(header)
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.SourceFormat = inputFormat
+
+	// 出力フォーマットの決定
+	outputFormat := format
+	if outputFormat == "" {
+		if c.config.Format != "" {
+			outputFormat = c.formatDetector.NormalizeFormat(c.config.Format)
+		} else {
+			outputFormat = inputFormat
+		}
+	}
+
+	preserveAnimation := !c.config.FirstFrameOnly && SupportsAnimation(outputFormat) && inputFormat == types.FormatGIF
+	if c.cache != nil {
+		return c.convertStreamCached(br, w, result, outputFormat, preserveAnimation)
+	}
+
+	// 画像の読み込み
+	out, err := c.decodeOutputImage(br, preserveAnimation)
+	if err != nil {
+		result.Error = fmt.Errorf("%w: %w", ErrDecode, err)
+		return result
+	}
+
+	if err := c.encodeOutput(w, &result, out, c.resizeSpec(), outputFormat, c.quality(0)); err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Success = true
+	return result
+}
+
+// convertStreamCached はキャッシュを使用してストリームを変換します
+// キーの計算に入力全体のハッシュが必要なため、入力をメモリに読み込んでから変換します
+func (c *Converter) convertStreamCached(r io.Reader, w io.Writer, result types.ConversionResult, outputFormat types.ImageFormat, preserveAnimation bool) types.ConversionResult {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		result.Error = fmt.Errorf("%w: %w", ErrDecode, err)
+		return result
+	}
+	hash, _ := hashReader(bytes.NewReader(input))
+	key := c.cacheKey(hash, c.resizeSpec(), outputFormat, c.quality(0), preserveAnimation)
+
+	if data, entry, ok := c.cache.Get(key); ok {
+		if _, err := w.Write(data); err != nil {
+			result.Error = fmt.Errorf("failed to save image: %w", err)
+			return result
+		}
+		applyCacheEntry(&result, entry, int64(len(data)))
+		return result
+	}
+
+	out, err := c.decodeOutputImage(bytes.NewReader(input), preserveAnimation)
+	if err != nil {
+		result.Error = fmt.Errorf("%w: %w", ErrDecode, err)
+		return result
+	}
+
+	var output bytes.Buffer
+	if err := c.encodeOutput(io.MultiWriter(w, &output), &result, out, c.resizeSpec(), outputFormat, c.quality(0)); err != nil {
+		result.Error = err
+		return result
+	}
+
+	result.Success = true
+	result.Cache = types.CacheMiss
+	c.cache.Put(key, output.Bytes(), cacheEntryFromResult(result))
+	return result
+}
+
+// decodeOutputImage はrから画像をデコードします
+// preserveAnimationが有効な場合は全フレームを読み込み、1フレームのみなら静止画として扱います
+func (c *Converter) decodeOutputImage(r io.Reader, preserveAnimation bool) (outputImage, error) {
+	if preserveAnimation {
+		anim, err := c.loader.DecodeAnimation(r)
+		if err != nil {
+			return outputImage{}, err
+		}
+		if anim.IsAnimated() {
+			return outputImage{anim: anim}, nil
+		}
+		return outputImage{still: anim.Frames[0]}, nil
+	}
+
+	img, _, err := c.loader.Decode(r)
+	if err != nil {
+		return outputImage{}, err
+	}
+	return outputImage{still: img}, nil
+}
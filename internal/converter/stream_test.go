@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestConverter_ConvertStream(t *testing.T) {
+	var input bytes.Buffer
+	if err := png.Encode(&input, createTestImage(80, 40)); err != nil {
+		t.Fatalf("Failed to encode input: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   types.Config
+		format   types.ImageFormat
+		expected string
+	}{
+		{"入力と同じフォーマット", types.Config{Width: 40}, "", "png"},
+		{"設定のフォーマット", types.Config{Width: 40, Format: "jpeg", JPEGQuality: 85}, "", "jpeg"},
+		{"引数のフォーマットが優先", types.Config{Width: 40, Format: "jpeg", JPEGQuality: 85}, types.FormatWebP, "webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			result := NewConverter(tt.config).ConvertStream(bytes.NewReader(input.Bytes()), &output, tt.format)
+			if !result.Success {
+				t.Fatalf("ConvertStream failed: %v", result.Error)
+			}
+			if result.Bytes != int64(output.Len()) {
+				t.Errorf("Bytes = %d, want %d", result.Bytes, output.Len())
+			}
+
+			cfg, format, err := image.DecodeConfig(&output)
+			if err != nil {
+				t.Fatalf("Failed to decode output: %v", err)
+			}
+			if format != tt.expected || cfg.Width != 40 || cfg.Height != 20 {
+				t.Errorf("output = %s %dx%d, want %s 40x20", format, cfg.Width, cfg.Height, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConverter_ConvertStream_Animation(t *testing.T) {
+	var input bytes.Buffer
+	g := createTestGIF(16, 16, []color.RGBA{{255, 0, 0, 255}, {0, 0, 255, 255}}, gif.DisposalNone)
+	if err := gif.EncodeAll(&input, g); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+
+	var output bytes.Buffer
+	result := NewConverter(types.Config{}).ConvertStream(&input, &output, "")
+	if !result.Success {
+		t.Fatalf("ConvertStream failed: %v", result.Error)
+	}
+
+	decoded, err := gif.DecodeAll(&output)
+	if err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	if len(decoded.Image) != 2 {
+		t.Errorf("frames = %d, want 2", len(decoded.Image))
+	}
+}
+
+func TestConverter_ConvertStream_InvalidInput(t *testing.T) {
+	var output bytes.Buffer
+	result := NewConverter(types.Config{}).ConvertStream(strings.NewReader("not an image"), &output, "")
+	if result.Success || result.Error == nil {
+		t.Error("Expected failure for invalid input")
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected no output, got %d bytes", output.Len())
+	}
+}
+
+func TestImageLoader_Decode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, createTestImage(10, 5)); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	img, format, err := NewImageLoader().Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if format != types.FormatPNG || img.Bounds().Dx() != 10 {
+		t.Errorf("Decode() = %s %v", format, img.Bounds())
+	}
+}
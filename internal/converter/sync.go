@@ -0,0 +1,237 @@
+package converter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"image-converter/internal/types"
+)
+
+// SyncManifestName は-syncで出力ディレクトリに書き込む、このツールが生成した出力の記録ファイルの名前です
+const SyncManifestName = ".image-converter-sync.json"
+
+// ReadManifestJSON はJSON形式のマニフェストを読み込みます
+func ReadManifestJSON(r io.Reader) (Manifest, error) {
+	var manifest Manifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// SyncOutputs は入力が存在しなくなった出力（孤立した出力）を出力ディレクトリから削除し、出力の記録を更新します
+// 削除の対象は前回までの-syncの記録（SyncManifestName）にある出力のうち、記録時から内容が変わっていないものに限ります
+// imageFilesは現在の入力画像、resultsは今回の変換結果です
+func (c *Converter) SyncOutputs(imageFiles []string, results []types.ConversionResult, outputDir string) error {
+	manifestPath := filepath.Join(outputDir, SyncManifestName)
+	previous, err := readSyncManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	remove, modified, kept := c.pruneTargets(previous, imageFiles, results, outputDir)
+	for _, path := range modified {
+		fmt.Printf("Keeping %s (modified since conversion)\n", path)
+	}
+
+	removed := 0
+	for _, path := range remove {
+		if err := os.Remove(path); err != nil {
+			fmt.Printf("Failed to remove %s (%v)\n", path, err)
+			continue
+		}
+		fmt.Printf("Removed %s\n", path)
+		removed++
+	}
+	fmt.Printf("Sync: removed %d orphaned outputs\n", removed)
+
+	// 今回の変換結果と、変換に失敗した入力の前回の出力を記録する
+	manifest, err := BuildManifest(results, c.config.InputDir, outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to build sync manifest: %w", err)
+	}
+	for source, entries := range kept {
+		if _, ok := manifest[source]; !ok {
+			manifest[source] = entries
+		}
+	}
+	return writeSyncManifest(manifestPath, manifest)
+}
+
+// PlanRemovals は変換せずに、SyncOutputsで削除する予定の出力と、変換後に書き換えられたため残す出力を返します
+// 出力の記録は更新しません
+func (c *Converter) PlanRemovals(imageFiles []string, outputDir string) (remove, modified []string, err error) {
+	previous, err := readSyncManifest(filepath.Join(outputDir, SyncManifestName))
+	if err != nil {
+		return nil, nil, err
+	}
+	remove, modified, _ = c.pruneTargets(previous, imageFiles, nil, outputDir)
+	return remove, modified, nil
+}
+
+// pruneTargets は孤立した出力のうち削除する出力と、変換後に書き換えられたため残す出力のパスを返します
+// keptは引き続き記録しておく出力です
+func (c *Converter) pruneTargets(previous Manifest, imageFiles []string, results []types.ConversionResult, outputDir string) (remove, modified []string, kept Manifest) {
+	orphans, kept := c.findOrphans(previous, imageFiles, results, outputDir)
+	for _, orphan := range orphans {
+		path := filepath.Join(outputDir, filepath.FromSlash(orphan.Path))
+		if !withinDir(outputDir, path) {
+			// 記録が改ざんされていても出力ディレクトリの外は削除しない
+			continue
+		}
+		if hash, err := hashFile(path); err != nil {
+			// 既に削除されている
+			continue
+		} else if hash != orphan.SHA256 {
+			modified = append(modified, path)
+			continue
+		}
+		remove = append(remove, path)
+	}
+	return remove, modified, kept
+}
+
+// findOrphans は前回の記録のうち削除する出力と、引き続き記録しておく出力を返します
+// 入力が存在しない出力と、今回の変換で別のパスに出力された（フォーマットの変更など）出力を削除の対象とします
+// 変換に失敗した入力の出力は残し、resultsがない場合（DryRun）は変換せずに予定される出力パスと比較します
+// 今回の（DryRunでは予定される）いずれかの入力の出力と同じパスは、入力の名前が変わった場合などでも削除しません
+func (c *Converter) findOrphans(previous Manifest, imageFiles []string, results []types.ConversionResult, outputDir string) (orphans []ManifestEntry, kept Manifest) {
+	present := map[string]bool{} // 入力の相対パス
+	for _, file := range imageFiles {
+		present[relativeSlashPath(c.config.InputDir, file)] = true
+	}
+
+	converted := map[string]map[string]bool{} // 入力の相対パス → 出力の相対パス
+	current := map[string]bool{}              // 今回のすべての出力の相対パス
+	addOutput := func(sourcePath, outputPath string) {
+		source := relativeSlashPath(c.config.InputDir, sourcePath)
+		if converted[source] == nil {
+			converted[source] = map[string]bool{}
+		}
+		if outputPath != "" {
+			output := relativeSlashPath(outputDir, outputPath)
+			converted[source][output] = true
+			current[output] = true
+		}
+	}
+	if results == nil {
+		for _, file := range imageFiles {
+			addOutput(file, "")
+			for _, output := range c.expectedOutputs(file, outputDir) {
+				addOutput(file, output)
+			}
+		}
+	}
+	for _, result := range results {
+		if result.Success {
+			addOutput(result.SourcePath, result.OutputPath)
+		}
+	}
+
+	kept = Manifest{}
+	sources := make([]string, 0, len(previous))
+	for source := range previous {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		expected := converted[source]
+		for _, entry := range previous[source] {
+			switch {
+			case current[entry.Path]:
+				// 今回の出力として記録し直される
+			case !present[source]:
+				orphans = append(orphans, entry)
+			case expected == nil:
+				// 変換に失敗した入力の出力は残す
+				kept[source] = append(kept[source], entry)
+			case !expected[entry.Path]:
+				orphans = append(orphans, entry)
+			}
+		}
+	}
+	return orphans, kept
+}
+
+// expectedOutputs は入力を変換した場合の出力パスを返します（規則とバリエーションを考慮します）
+// 出力パスを決定できない場合は空を返します
+func (c *Converter) expectedOutputs(sourcePath, outputDir string) []string {
+	conv := c
+	rule, err := c.MatchRule(sourcePath)
+	if err != nil {
+		return nil
+	}
+	if rule != nil {
+		conv = c.forRule(rule)
+	}
+
+	if len(conv.config.Variants) == 0 {
+		format, err := conv.resolveOutputFormat(sourcePath, conv.config.Format)
+		if err != nil {
+			return nil
+		}
+		return []string{conv.formatDetector.GenerateOutputPath(sourcePath, outputDir, format)}
+	}
+
+	var outputs []string
+	for _, variant := range conv.config.Variants {
+		format := variant.Format
+		if format == "" {
+			format = conv.config.Format
+		}
+		outputFormat, err := conv.resolveOutputFormat(sourcePath, format)
+		if err != nil {
+			continue
+		}
+		outputs = append(outputs, conv.formatDetector.GenerateVariantOutputPath(sourcePath, outputDir, variant.Suffix, outputFormat))
+	}
+	return outputs
+}
+
+// readSyncManifest は出力の記録を読み込みます（存在しない場合は空）
+func readSyncManifest(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Manifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to open sync manifest: %w", err)
+	}
+	defer file.Close()
+
+	manifest, err := ReadManifestJSON(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// writeSyncManifest は出力の記録を書き込みます
+func writeSyncManifest(path string, manifest Manifest) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create sync manifest: %w", err)
+	}
+	defer file.Close()
+
+	if err := WriteManifestJSON(file, manifest); err != nil {
+		return fmt.Errorf("failed to write sync manifest: %w", err)
+	}
+	return file.Close()
+}
+
+// withinDir はpathがdirの中にあるか判定します
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"image-converter/internal/filesystem"
+	"image-converter/internal/types"
+)
+
+// newSyncDirs は入力画像a.png, b.pngを配置した入力ディレクトリと出力ディレクトリを作成します
+func newSyncDirs(t *testing.T) (inputDir, outputDir string) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	inputDir = filepath.Join(tempDir, "input")
+	outputDir = filepath.Join(tempDir, "output")
+	for _, dir := range []string{inputDir, outputDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	saveTestImage(t, filepath.Join(inputDir, "a.png"), createTestImage(40, 20))
+	saveTestImage(t, filepath.Join(inputDir, "b.png"), createTestImage(20, 40))
+	return inputDir, outputDir
+}
+
+// runSync は-syncを有効にして入力ディレクトリを変換します
+func runSync(t *testing.T, config types.Config) {
+	t.Helper()
+
+	config.Sync = true
+	if err := NewConverter(config).ProcessDirectory(config.InputDir, config.OutputDir, filesystem.NewFileSystemManager()); err != nil {
+		t.Fatalf("ProcessDirectory failed: %v", err)
+	}
+}
+
+// assertExists はファイルの有無を検証します
+func assertExists(t *testing.T, path string, expected bool) {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if exists := err == nil; exists != expected {
+		t.Errorf("%s: exists = %v, want %v", filepath.Base(path), exists, expected)
+	}
+}
+
+func TestSyncOutputs_RemovesOrphans(t *testing.T) {
+	inputDir, outputDir := newSyncDirs(t)
+	config := types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "jpeg", JPEGQuality: 85}
+
+	unrelated := filepath.Join(outputDir, "unrelated.jpg")
+	if err := os.WriteFile(unrelated, []byte("not ours"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	runSync(t, config)
+	assertExists(t, filepath.Join(outputDir, SyncManifestName), true)
+
+	// 入力を削除すると、その出力のみが削除される
+	if err := os.Remove(filepath.Join(inputDir, "a.png")); err != nil {
+		t.Fatalf("Failed to remove input: %v", err)
+	}
+	runSync(t, config)
+
+	assertExists(t, filepath.Join(outputDir, "a.jpg"), false)
+	assertExists(t, filepath.Join(outputDir, "b.jpg"), true)
+	assertExists(t, unrelated, true)
+
+	manifest, err := readSyncManifest(filepath.Join(outputDir, SyncManifestName))
+	if err != nil {
+		t.Fatalf("Failed to read sync manifest: %v", err)
+	}
+	if _, ok := manifest["a.png"]; ok || len(manifest["b.png"]) != 1 {
+		t.Errorf("Unexpected sync manifest: %+v", manifest)
+	}
+}
+
+func TestSyncOutputs_FormatChange(t *testing.T) {
+	inputDir, outputDir := newSyncDirs(t)
+	config := types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "jpeg", JPEGQuality: 85}
+	runSync(t, config)
+
+	// フォーマットを変更すると、以前のフォーマットの出力は不要になる
+	config.Format = "png"
+	runSync(t, config)
+
+	for _, name := range []string{"a", "b"} {
+		assertExists(t, filepath.Join(outputDir, name+".jpg"), false)
+		assertExists(t, filepath.Join(outputDir, name+".png"), true)
+	}
+}
+
+func TestSyncOutputs_RenamedSourceKeepsCurrentOutput(t *testing.T) {
+	inputDir, outputDir := newSyncDirs(t)
+	config := types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "webp", JPEGQuality: 85}
+	runSync(t, config)
+
+	// 拡張子の大文字小文字だけを変更すると、以前の入力と同じパスに出力される
+	if err := os.Rename(filepath.Join(inputDir, "a.png"), filepath.Join(inputDir, "a.PNG")); err != nil {
+		t.Fatalf("Failed to rename input: %v", err)
+	}
+
+	// 変換せずに予定を確認した場合も、今回の出力は削除の対象にならない
+	remove, _, err := NewConverter(config).PlanRemovals([]string{filepath.Join(inputDir, "a.PNG"), filepath.Join(inputDir, "b.png")}, outputDir)
+	if err != nil {
+		t.Fatalf("PlanRemovals failed: %v", err)
+	}
+	if len(remove) != 0 {
+		t.Errorf("Expected no removals, got %v", remove)
+	}
+
+	runSync(t, config)
+	assertExists(t, filepath.Join(outputDir, "a.webp"), true)
+
+	manifest, err := readSyncManifest(filepath.Join(outputDir, SyncManifestName))
+	if err != nil {
+		t.Fatalf("Failed to read sync manifest: %v", err)
+	}
+	if _, ok := manifest["a.png"]; ok || len(manifest["a.PNG"]) != 1 {
+		t.Errorf("Unexpected sync manifest: %+v", manifest)
+	}
+}
+
+func TestSyncOutputs_KeepsModifiedAndOutsideFiles(t *testing.T) {
+	inputDir, outputDir := newSyncDirs(t)
+	config := types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "jpeg", JPEGQuality: 85}
+	runSync(t, config)
+
+	// 変換後に書き換えられた出力は削除しない
+	if err := os.WriteFile(filepath.Join(outputDir, "a.jpg"), []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// 記録が出力ディレクトリの外を指していても削除しない
+	outside := filepath.Join(filepath.Dir(outputDir), "outside.jpg")
+	if err := os.WriteFile(outside, []byte("outside"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	hash, err := hashFile(outside)
+	if err != nil {
+		t.Fatalf("hashFile failed: %v", err)
+	}
+	manifestPath := filepath.Join(outputDir, SyncManifestName)
+	manifest, err := readSyncManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to read sync manifest: %v", err)
+	}
+	manifest["gone.png"] = []ManifestEntry{{Path: "../outside.jpg", SHA256: hash}}
+	if err := writeSyncManifest(manifestPath, manifest); err != nil {
+		t.Fatalf("Failed to write sync manifest: %v", err)
+	}
+
+	for _, name := range []string{"a.png", "b.png"} {
+		if err := os.Remove(filepath.Join(inputDir, name)); err != nil {
+			t.Fatalf("Failed to remove input: %v", err)
+		}
+	}
+	runSync(t, config)
+
+	assertExists(t, filepath.Join(outputDir, "a.jpg"), true)
+	assertExists(t, filepath.Join(outputDir, "b.jpg"), false)
+	assertExists(t, outside, true)
+}
+
+func TestSyncOutputs_DryRun(t *testing.T) {
+	inputDir, outputDir := newSyncDirs(t)
+	config := types.Config{InputDir: inputDir, OutputDir: outputDir, Format: "jpeg", JPEGQuality: 85}
+	runSync(t, config)
+
+	manifestPath := filepath.Join(outputDir, SyncManifestName)
+	before, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to read sync manifest: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(inputDir, "a.png")); err != nil {
+		t.Fatalf("Failed to remove input: %v", err)
+	}
+	// 新しい入力も変換されない
+	saveTestImage(t, filepath.Join(inputDir, "c.png"), createTestImage(10, 10))
+
+	config.DryRun = true
+	runSync(t, config)
+
+	assertExists(t, filepath.Join(outputDir, "a.jpg"), true)
+	assertExists(t, filepath.Join(outputDir, "c.jpg"), false)
+
+	after, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to read sync manifest: %v", err)
+	}
+	if string(before) != string(after) {
+		t.Error("Expected the sync manifest not to change in dry-run mode")
+	}
+}
+
+func TestFindOrphans_DryRunComparesExpectedPaths(t *testing.T) {
+	inputDir, outputDir := newSyncDirs(t)
+	conv := NewConverter(types.Config{InputDir: inputDir, Format: "png", DryRun: true})
+
+	previous := Manifest{
+		"a.png": {{Path: "a.jpg"}, {Path: "a.png"}},
+		"b.png": {{Path: "b.png"}},
+		"c.png": {{Path: "c.png"}},
+	}
+	files := []string{filepath.Join(inputDir, "a.png"), filepath.Join(inputDir, "b.png")}
+
+	orphans, _ := conv.findOrphans(previous, files, nil, outputDir)
+	var paths []string
+	for _, orphan := range orphans {
+		paths = append(paths, orphan.Path)
+	}
+	if got := strings.Join(paths, ","); got != "a.jpg,c.png" {
+		t.Errorf("orphans = %s, want a.jpg,c.png", got)
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	dir := filepath.Join("out", "dir")
+	tests := map[string]bool{
+		filepath.Join(dir, "a.jpg"):           true,
+		filepath.Join(dir, "sub", "a.jpg"):    true,
+		filepath.Join(dir, "..", "a.jpg"):     false,
+		filepath.Join(dir, "..", "dir2", "a"): false,
+		filepath.Join(dir, "..a"):             true,
+	}
+	for path, expected := range tests {
+		if got := withinDir(dir, path); got != expected {
+			t.Errorf("withinDir(%q, %q) = %v, want %v", dir, path, got, expected)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package converter
+
+import (
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+func TestConverter_ConvertImageVariants(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "photo.png")
+	saveTestImage(t, inputPath, createTestImage(200, 100))
+
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	config := types.Config{
+		InputDir:    tempDir,
+		OutputDir:   outputDir,
+		Format:      "jpeg",
+		JPEGQuality: 85,
+		Variants: []types.Variant{
+			{Suffix: "-sm", Resize: types.ResizeSpec{Width: 50}},
+			{Suffix: "-lg", Resize: types.ResizeSpec{Width: 100}, Format: "webp", Quality: 70},
+			{Suffix: "-half", Resize: types.ResizeSpec{Scale: 0.5}, Format: "png"},
+		},
+	}
+
+	results := NewConverter(config).ConvertImageVariants(inputPath, outputDir)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	expected := []struct {
+		path   string
+		width  int
+		height int
+	}{
+		{filepath.Join(outputDir, "photo-sm.jpg"), 50, 25},
+		{filepath.Join(outputDir, "photo-lg.webp"), 100, 50},
+		{filepath.Join(outputDir, "photo-half.png"), 100, 50},
+	}
+
+	for i, want := range expected {
+		result := results[i]
+		if !result.Success {
+			t.Fatalf("Variant %d failed: %v", i, result.Error)
+		}
+		if result.OutputPath != want.path {
+			t.Errorf("Variant %d: output path = %s, want %s", i, result.OutputPath, want.path)
+		}
+		if result.Variant != config.Variants[i].Suffix {
+			t.Errorf("Variant %d: variant = %q, want %q", i, result.Variant, config.Variants[i].Suffix)
+		}
+
+		file, err := os.Open(result.OutputPath)
+		if err != nil {
+			t.Fatalf("Failed to open output: %v", err)
+		}
+		cfg, _, err := image.DecodeConfig(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("Failed to decode output: %v", err)
+		}
+		if cfg.Width != want.width || cfg.Height != want.height {
+			t.Errorf("Variant %d: size = %dx%d, want %dx%d", i, cfg.Width, cfg.Height, want.width, want.height)
+		}
+	}
+}
+
+func TestConverter_ConvertImageVariants_NoVariants(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "photo.png")
+	saveTestImage(t, inputPath, createTestImage(20, 20))
+
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	config := types.Config{InputDir: tempDir, OutputDir: outputDir, Format: "png"}
+	results := NewConverter(config).ConvertImageVariants(inputPath, outputDir)
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Expected a single successful result, got %+v", results)
+	}
+	if results[0].OutputPath != filepath.Join(outputDir, "photo.png") {
+		t.Errorf("Unexpected output path: %s", results[0].OutputPath)
+	}
+}
+
+func TestConverter_ConvertImageVariants_LoadFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "broken.png")
+	if err := os.WriteFile(inputPath, []byte("not an image"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	config := types.Config{
+		Format:   "png",
+		Variants: []types.Variant{{Suffix: "-a"}, {Suffix: "-b"}},
+	}
+	results := NewConverter(config).ConvertImageVariants(inputPath, tempDir)
+	for i, result := range results {
+		if result.Success || result.Error == nil {
+			t.Errorf("Variant %d: expected failure", i)
+		}
+	}
+}
+
+func TestConverter_ConvertImageVariants_OutputCollision(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "photo.png")
+	saveTestImage(t, inputPath, createTestImage(20, 20))
+
+	// フォーマット未指定のバリエーションはPNGの入力ではPNGになり、format=pngのバリエーションと同じパスに書き込む
+	config := types.Config{
+		Variants: []types.Variant{{Suffix: "-a", Resize: types.ResizeSpec{Width: 10}}, {Suffix: "-a", Format: "png"}},
+	}
+	results := NewConverter(config).ConvertImageVariants(inputPath, tempDir)
+	if len(results) != 2 || !results[0].Success {
+		t.Fatalf("Expected the first variant to succeed, got %+v", results)
+	}
+	if results[1].Success || !errors.Is(results[1].Error, ErrOutputCollision) {
+		t.Errorf("Expected the duplicate variant to fail with ErrOutputCollision, got %+v", results[1])
+	}
+
+	// 先のバリエーションの出力が上書きされていない
+	img, err := NewImageLoader().Load(results[0].OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to load output: %v", err)
+	}
+	if img.Bounds().Dx() != 10 {
+		t.Errorf("Expected the first variant's 10px output, got width %d", img.Bounds().Dx())
+	}
+}
@@ -0,0 +1,125 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"image-converter/internal/types"
+)
+
+// ChangeWatcher は入力ディレクトリの変更を監視するインターフェースです
+type ChangeWatcher interface {
+	// Run はctxがキャンセルされるまで、書き込みが完了したファイルの変更をhandleに渡します
+	Run(ctx context.Context, handle func([]types.FileChange)) error
+}
+
+// WatchDirectory はctxがキャンセルされるまでwatcherが検出した画像を変換してoutputDirに出力します
+// deleteOutputsが有効な場合、削除（または名前を変更）された入力から生成した出力を削除します
+// 削除の対象は、この実行中に変換した出力のみです
+func (c *Converter) WatchDirectory(ctx context.Context, watcher ChangeWatcher, outputDir string, deleteOutputs bool) error {
+	fmt.Printf("Watching for changes (press Ctrl+C to stop)...\n")
+
+	return watcher.Run(ctx, func(changes []types.FileChange) {
+		for _, change := range changes {
+			switch change.Kind {
+			case types.FileWritten:
+				if _, err := c.formatDetector.DetectFormat(change.Path); err != nil {
+					continue
+				}
+				// 自身が書き込んだ出力は入力として扱わない
+				if c.isOutput(change.Path) {
+					continue
+				}
+				c.convertChanged(change.Path, outputDir)
+
+			case types.FileRemoved:
+				if deleteOutputs {
+					c.removeOutputs(change.Path)
+				}
+			}
+		}
+	})
+}
+
+// convertChanged は変更された画像を変換して結果を表示します
+func (c *Converter) convertChanged(sourcePath, outputDir string) {
+	fmt.Printf("Converting %s... ", sourcePath)
+
+	results := c.ConvertImageVariants(sourcePath, outputDir)
+	var firstErr error
+	for _, result := range results {
+		c.UpdateStats(result)
+		if !result.Success && firstErr == nil {
+			firstErr = result.Error
+		}
+	}
+	c.recordOutputs(results)
+
+	if firstErr != nil {
+		fmt.Printf("FAILED (%v)\n", firstErr)
+	} else if len(results) > 1 {
+		fmt.Printf("OK (%d variants)\n", len(results))
+	} else {
+		fmt.Printf("OK\n")
+	}
+}
+
+// removeOutputs はsourcePathから生成した出力を削除します
+func (c *Converter) removeOutputs(sourcePath string) {
+	c.statsMutex.Lock()
+	outputs := c.outputs[sourcePath]
+	delete(c.outputs, sourcePath)
+	c.statsMutex.Unlock()
+
+	for _, output := range outputs {
+		if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("Failed to remove %s (%v)\n", output, err)
+			continue
+		}
+		fmt.Printf("Removed %s\n", output)
+	}
+}
+
+// isOutput はpathがこの実行中に変換した出力かを返します
+func (c *Converter) isOutput(path string) bool {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	path = filepath.Clean(path)
+	for _, outputs := range c.outputs {
+		for _, output := range outputs {
+			if filepath.Clean(output) == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recordOutputs は変換に成功した出力を入力ごとに記録します（削除の反映に使用）
+func (c *Converter) recordOutputs(results []types.ConversionResult) {
+	c.statsMutex.Lock()
+	defer c.statsMutex.Unlock()
+
+	if c.outputs == nil {
+		c.outputs = map[string][]string{}
+	}
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		outputs := c.outputs[result.SourcePath]
+		known := false
+		for _, output := range outputs {
+			if output == result.OutputPath {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.outputs[result.SourcePath] = append(outputs, result.OutputPath)
+		}
+	}
+}
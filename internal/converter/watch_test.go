@@ -0,0 +1,144 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"image-converter/internal/types"
+)
+
+// scriptedWatcher は決められた変更を順に通知するテスト用のChangeWatcherです
+type scriptedWatcher struct {
+	batches [][]types.FileChange
+	after   func(i int) // 各通知の後に呼び出す（検証用）
+}
+
+func (w *scriptedWatcher) Run(ctx context.Context, handle func([]types.FileChange)) error {
+	for i, batch := range w.batches {
+		handle(batch)
+		if w.after != nil {
+			w.after(i)
+		}
+	}
+	return nil
+}
+
+func TestConverter_WatchDirectory(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	existing := filepath.Join(inputDir, "existing.png")
+	added := filepath.Join(inputDir, "added.png")
+	for _, path := range []string{existing, added} {
+		if err := saveImageWithFormat(createTestImage(40, 20), path, types.FormatPNG); err != nil {
+			t.Fatalf("Failed to save test image: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "notes.txt"), []byte("not an image"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	conv := NewConverter(types.Config{Format: "jpeg", JPEGQuality: 85})
+	if err := conv.ProcessFiles([]string{existing}, outputDir); err != nil {
+		t.Fatalf("ProcessFiles failed: %v", err)
+	}
+
+	existingOutput := filepath.Join(outputDir, "existing.jpg")
+	addedOutput := filepath.Join(outputDir, "added.jpg")
+
+	watcher := &scriptedWatcher{
+		batches: [][]types.FileChange{
+			{{Path: added, Kind: types.FileWritten}, {Path: filepath.Join(inputDir, "notes.txt"), Kind: types.FileWritten}},
+			{{Path: existing, Kind: types.FileRemoved}, {Path: added, Kind: types.FileRemoved}},
+		},
+		after: func(i int) {
+			switch i {
+			case 0:
+				if _, err := os.Stat(addedOutput); err != nil {
+					t.Errorf("Expected added image to be converted: %v", err)
+				}
+				if _, err := os.Stat(filepath.Join(outputDir, "notes.jpg")); !os.IsNotExist(err) {
+					t.Error("Expected non-image file to be ignored")
+				}
+			case 1:
+				for _, path := range []string{existingOutput, addedOutput} {
+					if _, err := os.Stat(path); !os.IsNotExist(err) {
+						t.Errorf("Expected %s to be removed", filepath.Base(path))
+					}
+				}
+			}
+		},
+	}
+
+	if err := conv.WatchDirectory(context.Background(), watcher, outputDir, true); err != nil {
+		t.Fatalf("WatchDirectory failed: %v", err)
+	}
+
+	stats := conv.GetStats()
+	if stats.Success != 2 {
+		t.Errorf("Success = %d, want 2", stats.Success)
+	}
+}
+
+func TestConverter_WatchDirectory_KeepOutputs(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	source := filepath.Join(inputDir, "photo.png")
+	if err := saveImageWithFormat(createTestImage(40, 20), source, types.FormatPNG); err != nil {
+		t.Fatalf("Failed to save test image: %v", err)
+	}
+
+	conv := NewConverter(types.Config{JPEGQuality: 85})
+	watcher := &scriptedWatcher{batches: [][]types.FileChange{
+		{{Path: source, Kind: types.FileWritten}},
+		{{Path: source, Kind: types.FileRemoved}},
+	}}
+	if err := conv.WatchDirectory(context.Background(), watcher, outputDir, false); err != nil {
+		t.Fatalf("WatchDirectory failed: %v", err)
+	}
+
+	// 削除の反映が無効な場合は出力を残す
+	if _, err := os.Stat(filepath.Join(outputDir, "photo.png")); err != nil {
+		t.Errorf("Expected output to be kept: %v", err)
+	}
+}
+
+func TestConverter_WatchDirectory_IgnoresOwnOutputs(t *testing.T) {
+	// 出力ディレクトリが監視対象の入力ディレクトリの中にある
+	inputDir := t.TempDir()
+	outputDir := filepath.Join(inputDir, "out")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	source := filepath.Join(inputDir, "photo.png")
+	if err := saveImageWithFormat(createTestImage(40, 20), source, types.FormatPNG); err != nil {
+		t.Fatalf("Failed to save test image: %v", err)
+	}
+	output := filepath.Join(outputDir, "photo.png")
+
+	conv := NewConverter(types.Config{Scale: 0.5})
+	watcher := &scriptedWatcher{batches: [][]types.FileChange{
+		{{Path: source, Kind: types.FileWritten}},
+		// 自身が書き込んだ出力の通知は変換しない
+		{{Path: output, Kind: types.FileWritten}},
+		{{Path: filepath.Join(inputDir, ".", "out", "photo.png"), Kind: types.FileWritten}},
+	}}
+	if err := conv.WatchDirectory(context.Background(), watcher, outputDir, false); err != nil {
+		t.Fatalf("WatchDirectory failed: %v", err)
+	}
+
+	if stats := conv.GetStats(); stats.Total != 1 || stats.Success != 1 {
+		t.Errorf("Expected only the source to be converted, got %+v", stats)
+	}
+	img, err := NewImageLoader().Load(output)
+	if err != nil {
+		t.Fatalf("Failed to load output: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 20 || size.Y != 10 {
+		t.Errorf("Expected the output to be scaled once, got %v", size)
+	}
+}
@@ -0,0 +1,240 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// WebPコンテナのチャンク識別子
+const (
+	chunkVP8X = "VP8X"
+	chunkANIM = "ANIM"
+	chunkANMF = "ANMF"
+	chunkALPH = "ALPH"
+	chunkVP8  = "VP8 "
+	chunkVP8L = "VP8L"
+)
+
+// VP8Xチャンクのフラグ
+const (
+	vp8xFlagAnimation = 0x02
+	vp8xFlagAlpha     = 0x10
+)
+
+// ANMFチャンクのフラグ（ブレンドしない）
+const anmfFlagNoBlend = 0x02
+
+// riffChunk はRIFFコンテナ内の1つのチャンクを表します
+type riffChunk struct {
+	ID   string
+	Data []byte
+}
+
+// parseWebPChunks はWebPファイルのRIFFコンテナを解析してチャンクの一覧を返します
+func parseWebPChunks(data []byte) ([]riffChunk, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("not a WebP file")
+	}
+
+	riffSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	end := 8 + riffSize
+	if end > len(data) {
+		return nil, fmt.Errorf("truncated WebP file")
+	}
+
+	return parseRIFFChunks(data[12:end])
+}
+
+// parseRIFFChunks はチャンクの並びを解析します（ANMFのペイロード解析にも使用）
+func parseRIFFChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated chunk header")
+		}
+		id := string(data[0:4])
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if size < 0 || 8+size > len(data) {
+			return nil, fmt.Errorf("truncated %q chunk", id)
+		}
+		chunks = append(chunks, riffChunk{ID: id, Data: data[8 : 8+size]})
+
+		// チャンクは偶数バイト境界に揃えられる
+		next := 8 + size + size&1
+		if next > len(data) {
+			next = len(data)
+		}
+		data = data[next:]
+	}
+	return chunks, nil
+}
+
+// writeRIFFChunk はチャンクを書き込みます（奇数長の場合はパディングを追加）
+func writeRIFFChunk(w io.Writer, id string, data []byte) error {
+	var header [8]byte
+	copy(header[0:4], id)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(data)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if len(data)&1 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putUint24 はリトルエンディアンの24ビット整数を書き込みます
+func putUint24(b []byte, v int) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
+
+// encodeWebPFrame は1フレームをエンコードし、ANMFに格納するビットストリームチャンクを返します
+// 戻り値にはALPH（ロッシーで透過がある場合）とVP8/VP8Lのチャンクが含まれます
+func encodeWebPFrame(img image.Image, quality int) ([]riffChunk, bool, error) {
+	data, err := webp.EncodeRGBA(img, float32(quality))
+	if err != nil {
+		return nil, false, err
+	}
+
+	chunks, err := parseWebPChunks(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var frameChunks []riffChunk
+	hasAlpha := false
+	for _, chunk := range chunks {
+		switch chunk.ID {
+		case chunkALPH:
+			hasAlpha = true
+			frameChunks = append(frameChunks, chunk)
+		case chunkVP8:
+			frameChunks = append(frameChunks, chunk)
+		case chunkVP8L:
+			// VP8Lはビットストリーム内にアルファを持つ
+			hasAlpha = true
+			frameChunks = append(frameChunks, chunk)
+		}
+	}
+
+	if len(frameChunks) == 0 {
+		return nil, false, fmt.Errorf("encoded frame has no bitstream")
+	}
+
+	return frameChunks, hasAlpha, nil
+}
+
+// webpLoopCount はGIFのループ回数をWebPのループ回数に変換します
+// GIF: 0=無限、-1=ループなし、n=n回繰り返し（計n+1回再生）
+// WebP: 0=無限、n=計n回再生
+func webpLoopCount(gifLoopCount int) int {
+	switch {
+	case gifLoopCount == 0:
+		return 0
+	case gifLoopCount < 0:
+		return 1
+	case gifLoopCount+1 > 0xffff:
+		return 0xffff
+	default:
+		return gifLoopCount + 1
+	}
+}
+
+// encodeAnimatedWebP はアニメーション画像をアニメーションWebP形式で書き込みます
+func encodeAnimatedWebP(w io.Writer, anim *AnimatedImage, quality int) error {
+	if anim.FrameCount() == 0 {
+		return fmt.Errorf("animation has no frames")
+	}
+
+	bounds := anim.Bounds()
+	canvasWidth, canvasHeight := bounds.Dx(), bounds.Dy()
+
+	var body bytes.Buffer
+	hasAlpha := false
+
+	for i, frame := range anim.Frames {
+		frameChunks, frameAlpha, err := encodeWebPFrame(frame, quality)
+		if err != nil {
+			return fmt.Errorf("failed to encode frame %d: %w", i, err)
+		}
+		hasAlpha = hasAlpha || frameAlpha
+
+		frameBounds := frame.Bounds()
+		var anmf bytes.Buffer
+		header := make([]byte, 16)
+		putUint24(header[0:3], 0) // X offset / 2
+		putUint24(header[3:6], 0) // Y offset / 2
+		putUint24(header[6:9], frameBounds.Dx()-1)
+		putUint24(header[9:12], frameBounds.Dy()-1)
+
+		// GIFの表示時間（1/100秒）をミリ秒に変換
+		duration := 0
+		if i < len(anim.Delays) {
+			duration = anim.Delays[i] * 10
+		}
+		if duration > 0xffffff {
+			duration = 0xffffff
+		}
+		putUint24(header[12:15], duration)
+		// 各フレームはキャンバス全体を含むためブレンドせずに上書きする
+		header[15] = anmfFlagNoBlend
+		anmf.Write(header)
+
+		for _, chunk := range frameChunks {
+			if err := writeRIFFChunk(&anmf, chunk.ID, chunk.Data); err != nil {
+				return err
+			}
+		}
+
+		if err := writeRIFFChunk(&body, chunkANMF, anmf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	// VP8Xチャンク
+	vp8x := make([]byte, 10)
+	vp8x[0] = vp8xFlagAnimation
+	if hasAlpha {
+		vp8x[0] |= vp8xFlagAlpha
+	}
+	putUint24(vp8x[4:7], canvasWidth-1)
+	putUint24(vp8x[7:10], canvasHeight-1)
+
+	// ANIMチャンク（背景色は透明、ループ回数）
+	animChunk := make([]byte, 6)
+	binary.LittleEndian.PutUint16(animChunk[4:6], uint16(webpLoopCount(anim.LoopCount)))
+
+	var header bytes.Buffer
+	if err := writeRIFFChunk(&header, chunkVP8X, vp8x); err != nil {
+		return err
+	}
+	if err := writeRIFFChunk(&header, chunkANIM, animChunk); err != nil {
+		return err
+	}
+
+	riffSize := 4 + header.Len() + body.Len()
+	var riffHeader [12]byte
+	copy(riffHeader[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(riffHeader[4:8], uint32(riffSize))
+	copy(riffHeader[8:12], "WEBP")
+
+	for _, b := range [][]byte{riffHeader[:], header.Bytes(), body.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+
+	"image-converter/internal/types"
+)
+
+// createTestAnimation はテスト用のアニメーション画像を生成します
+func createTestAnimation(width, height int, colors []color.RGBA) *AnimatedImage {
+	anim := &AnimatedImage{LoopCount: 0}
+	for i, c := range colors {
+		frame := image.NewRGBA(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				frame.SetRGBA(x, y, c)
+			}
+		}
+		anim.Frames = append(anim.Frames, frame)
+		anim.Delays = append(anim.Delays, (i+1)*5)
+	}
+	return anim
+}
+
+// stillWebPFromANMF はANMFチャンクのビットストリームから単一画像のWebPファイルを組み立てます
+func stillWebPFromANMF(t *testing.T, anmf []byte) []byte {
+	t.Helper()
+
+	width := int(anmf[6]) | int(anmf[7])<<8 | int(anmf[8])<<16 + 1
+	height := int(anmf[9]) | int(anmf[10])<<8 | int(anmf[11])<<16 + 1
+	chunks, err := parseRIFFChunks(anmf[16:])
+	if err != nil {
+		t.Fatalf("Failed to parse ANMF payload: %v", err)
+	}
+
+	var body bytes.Buffer
+	vp8x := make([]byte, 10)
+	if chunks[0].ID == chunkALPH {
+		vp8x[0] = vp8xFlagAlpha
+	}
+	putUint24(vp8x[4:7], width-1)
+	putUint24(vp8x[7:10], height-1)
+	writeRIFFChunk(&body, chunkVP8X, vp8x)
+	for _, chunk := range chunks {
+		writeRIFFChunk(&body, chunk.ID, chunk.Data)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(4+body.Len()))
+	out.WriteString("WEBP")
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func TestEncodeAnimatedWebP_Structure(t *testing.T) {
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 128}}
+	anim := createTestAnimation(32, 24, colors)
+	anim.LoopCount = 2
+
+	var buf bytes.Buffer
+	if err := encodeAnimatedWebP(&buf, anim, 80); err != nil {
+		t.Fatalf("Failed to encode animated WebP: %v", err)
+	}
+
+	chunks, err := parseWebPChunks(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	if chunks[0].ID != chunkVP8X || chunks[1].ID != chunkANIM {
+		t.Fatalf("Expected VP8X and ANIM chunks first, got %s, %s", chunks[0].ID, chunks[1].ID)
+	}
+
+	vp8x := chunks[0].Data
+	if vp8x[0]&vp8xFlagAnimation == 0 {
+		t.Error("Expected animation flag in VP8X")
+	}
+	if vp8x[0]&vp8xFlagAlpha == 0 {
+		t.Error("Expected alpha flag in VP8X")
+	}
+	canvasWidth := int(vp8x[4]) | int(vp8x[5])<<8 | int(vp8x[6])<<16 + 1
+	canvasHeight := int(vp8x[7]) | int(vp8x[8])<<8 | int(vp8x[9])<<16 + 1
+	if canvasWidth != 32 || canvasHeight != 24 {
+		t.Errorf("Expected canvas 32x24, got %dx%d", canvasWidth, canvasHeight)
+	}
+
+	// GIFのループ回数2（計3回再生）はWebPでは3
+	if loop := binary.LittleEndian.Uint16(chunks[1].Data[4:6]); loop != 3 {
+		t.Errorf("Expected loop count 3, got %d", loop)
+	}
+
+	var frames []riffChunk
+	for _, chunk := range chunks[2:] {
+		if chunk.ID == chunkANMF {
+			frames = append(frames, chunk)
+		}
+	}
+	if len(frames) != 3 {
+		t.Fatalf("Expected 3 ANMF chunks, got %d", len(frames))
+	}
+
+	for i, frame := range frames {
+		duration := int(frame.Data[12]) | int(frame.Data[13])<<8 | int(frame.Data[14])<<16
+		if expected := (i + 1) * 50; duration != expected {
+			t.Errorf("Frame %d: expected duration %dms, got %dms", i, expected, duration)
+		}
+
+		// フレームのビットストリームが単体でデコードできることを確認
+		img, err := xwebp.Decode(bytes.NewReader(stillWebPFromANMF(t, frame.Data)))
+		if err != nil {
+			t.Fatalf("Frame %d: failed to decode bitstream: %v", i, err)
+		}
+		if img.Bounds().Dx() != 32 || img.Bounds().Dy() != 24 {
+			t.Errorf("Frame %d: expected 32x24, got %v", i, img.Bounds())
+		}
+	}
+}
+
+func TestWebPLoopCount(t *testing.T) {
+	tests := []struct {
+		gifLoop  int
+		expected int
+	}{
+		{0, 0},
+		{-1, 1},
+		{1, 2},
+		{100000, 0xffff},
+	}
+
+	for _, tt := range tests {
+		if got := webpLoopCount(tt.gifLoop); got != tt.expected {
+			t.Errorf("webpLoopCount(%d) = %d, expected %d", tt.gifLoop, got, tt.expected)
+		}
+	}
+}
+
+func TestConverter_ConvertImage_AnimatedGIFToWebP(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "anim.gif")
+	outputDir := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatalf("Failed to create output directory: %v", err)
+	}
+
+	colors := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}}
+	saveTestGIF(t, inputPath, createTestGIF(40, 20, colors, gif.DisposalNone))
+
+	converter := NewConverter(types.Config{Format: "webp", Width: 20, JPEGQuality: 85})
+	result := converter.ConvertImage(inputPath, outputDir)
+	if !result.Success {
+		t.Fatalf("Expected conversion to succeed, got error: %v", result.Error)
+	}
+
+	data, err := os.ReadFile(result.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+
+	chunks, err := parseWebPChunks(data)
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	frameCount := 0
+	for _, chunk := range chunks {
+		if chunk.ID == chunkANMF {
+			frameCount++
+		}
+	}
+	if frameCount != 2 {
+		t.Errorf("Expected 2 frames, got %d", frameCount)
+	}
+}
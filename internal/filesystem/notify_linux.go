@@ -0,0 +1,61 @@
+//go:build linux
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// inotifyEvents は変更の通知を受け取るinotifyイベントです
+const inotifyEvents = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE
+
+// inotifyNotifier はinotifyでディレクトリの変更を通知するNotifierです
+type inotifyNotifier struct {
+	file *os.File
+	c    chan struct{}
+}
+
+// newInotifyNotifier はdirを監視するinotifyのNotifierを作成します
+func newInotifyNotifier(dir string) (Notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	if _, err := syscall.InotifyAddWatch(fd, dir, inotifyEvents); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch directory: %w", err)
+	}
+
+	// 非ブロッキングのfdはランタイムのポーラーで待機するため、Closeで読み込みが中断される
+	n := &inotifyNotifier{
+		file: os.NewFile(uintptr(fd), "inotify"),
+		c:    make(chan struct{}, 1),
+	}
+	go n.read()
+	return n, nil
+}
+
+// read はイベントを読み込むたびに通知します（イベントの内容はディレクトリの走査で確認する）
+func (n *inotifyNotifier) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			return
+		}
+		select {
+		case n.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (n *inotifyNotifier) C() <-chan struct{} {
+	return n.c
+}
+
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package filesystem
+
+import "errors"
+
+// newInotifyNotifier はinotifyのない環境ではエラーを返します（ポーリングにフォールバックする）
+func newInotifyNotifier(dir string) (Notifier, error) {
+	return nil, errors.New("inotify is not supported on this platform")
+}
@@ -0,0 +1,231 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+// FileState はファイルのサイズと更新時刻です
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Snapshot はディレクトリ内の各ファイル（パス）の状態です
+type Snapshot map[string]FileState
+
+// SnapshotFunc はディレクトリのスナップショットを取得する関数です
+type SnapshotFunc func(dir string) (Snapshot, error)
+
+// Notifier はディレクトリに変更があった可能性を通知します
+type Notifier interface {
+	// C は変更の可能性があるときに値を送るチャネルを返します
+	C() <-chan struct{}
+	Close() error
+}
+
+// Watcher はディレクトリを走査して、書き込みが完了したファイルの作成・変更と削除を検出します
+// 書き込み途中のファイルを変換しないよう、サイズと更新時刻が一定時間変わらなくなってから報告します
+// 名前の変更は元の名前の削除と新しい名前の作成として報告されます
+type Watcher struct {
+	dir      string
+	snapshot SnapshotFunc
+	notifier Notifier
+	settle   time.Duration    // 変更を報告するまでに状態が変わらない必要がある時間
+	now      func() time.Time // 現在時刻（テスト用に差し替え可能）
+
+	known   Snapshot               // 報告済み（または監視開始時点）の状態
+	pending map[string]pendingFile // 変化を検出したが、まだ安定していない状態
+}
+
+// pendingFile は安定待ちのファイルの状態と、その状態を最初に観測した時刻です
+type pendingFile struct {
+	state FileState
+	since time.Time
+}
+
+// NewWatcher はdirの監視を開始します（開始時点のファイルは報告しません）
+// 変更の検出にはinotify（Linux）を使用し、使用できない環境ではsettleごとのポーリングにフォールバックします
+func NewWatcher(dir string, settle time.Duration) (*Watcher, error) {
+	notifier, err := newInotifyNotifier(dir)
+	if err != nil {
+		notifier = NewPollingNotifier(settle)
+	}
+
+	watcher, err := newWatcher(dir, ReadSnapshot, notifier, settle, time.Now)
+	if err != nil {
+		notifier.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// newWatcher はスナップショットの取得方法・通知・時刻を指定してWatcherを作成します
+func newWatcher(dir string, snapshot SnapshotFunc, notifier Notifier, settle time.Duration, now func() time.Time) (*Watcher, error) {
+	initial, err := snapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		dir:      dir,
+		snapshot: snapshot,
+		notifier: notifier,
+		settle:   settle,
+		now:      now,
+		known:    initial,
+		pending:  map[string]pendingFile{},
+	}, nil
+}
+
+// Poll はディレクトリを走査し、前回の報告以降の変更のうち確定したものをパスの順に返します
+// 作成・変更されたファイルは、同じ状態がsettle以上続いた時点で報告します
+func (w *Watcher) Poll() ([]types.FileChange, error) {
+	current, err := w.snapshot(w.dir)
+	if err != nil {
+		return nil, err
+	}
+	now := w.now()
+
+	var changes []types.FileChange
+	for path, state := range current {
+		if known, ok := w.known[path]; ok && known == state {
+			// 変更されていない、または書き込み途中の変化が元に戻った
+			delete(w.pending, path)
+			continue
+		}
+
+		pending, ok := w.pending[path]
+		if !ok || pending.state != state {
+			// 新たな変化: 安定するまで待つ
+			w.pending[path] = pendingFile{state: state, since: now}
+			continue
+		}
+		if now.Sub(pending.since) >= w.settle {
+			changes = append(changes, types.FileChange{Path: path, Kind: types.FileWritten})
+			w.known[path] = state
+			delete(w.pending, path)
+		}
+	}
+
+	for path := range w.known {
+		if _, ok := current[path]; !ok {
+			changes = append(changes, types.FileChange{Path: path, Kind: types.FileRemoved})
+			delete(w.known, path)
+		}
+	}
+	for path := range w.pending {
+		if _, ok := current[path]; !ok {
+			delete(w.pending, path)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// Run はctxがキャンセルされるまで変更の通知を待って走査し、確定した変更をhandleに渡します
+// 安定待ちのファイルがある間はsettleごとに走査を繰り返します
+func (w *Watcher) Run(ctx context.Context, handle func([]types.FileChange)) error {
+	for {
+		// 安定待ちのファイルがなければ通知のみを待つ
+		var timer *time.Timer
+		var settled <-chan time.Time
+		if len(w.pending) > 0 {
+			timer = time.NewTimer(w.settle)
+			settled = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.notifier.C():
+		case <-settled:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		changes, err := w.Poll()
+		if err != nil {
+			return fmt.Errorf("failed to scan directory: %w", err)
+		}
+		if len(changes) > 0 {
+			handle(changes)
+		}
+	}
+}
+
+// Close は変更の通知を停止します
+func (w *Watcher) Close() error {
+	return w.notifier.Close()
+}
+
+// ReadSnapshot はディレクトリ直下のファイル（サブディレクトリを除く）の状態を取得します
+func ReadSnapshot(dir string) (Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	snapshot := Snapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// 走査中に削除されたファイル
+			continue
+		}
+		snapshot[filepath.Join(dir, entry.Name())] = FileState{Size: info.Size(), ModTime: info.ModTime()}
+	}
+	return snapshot, nil
+}
+
+// pollingNotifier は一定間隔で通知するNotifierです
+type pollingNotifier struct {
+	ticker *time.Ticker
+	c      chan struct{}
+	done   chan struct{}
+}
+
+// NewPollingNotifier はintervalごとに通知するNotifierを作成します
+func NewPollingNotifier(interval time.Duration) Notifier {
+	n := &pollingNotifier{
+		ticker: time.NewTicker(interval),
+		c:      make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-n.done:
+				return
+			case <-n.ticker.C:
+				select {
+				case n.c <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return n
+}
+
+func (n *pollingNotifier) C() <-chan struct{} {
+	return n.c
+}
+
+func (n *pollingNotifier) Close() error {
+	n.ticker.Stop()
+	close(n.done)
+	return nil
+}
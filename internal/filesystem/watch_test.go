@@ -0,0 +1,179 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+// fakeDirectory は実際のファイルシステムを使わずにスナップショットを返すテスト用のディレクトリです
+type fakeDirectory struct {
+	files Snapshot
+	now   time.Time
+}
+
+func (d *fakeDirectory) snapshot(string) (Snapshot, error) {
+	copied := Snapshot{}
+	for path, state := range d.files {
+		copied[path] = state
+	}
+	return copied, nil
+}
+
+func (d *fakeDirectory) write(path string, size int64) {
+	d.files[path] = FileState{Size: size, ModTime: d.now}
+}
+
+// poll は時刻をelapsedだけ進めてから走査します
+func (d *fakeDirectory) poll(t *testing.T, w *Watcher, elapsed time.Duration) []types.FileChange {
+	t.Helper()
+	d.now = d.now.Add(elapsed)
+	changes, err := w.Poll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return changes
+}
+
+// nopNotifier は通知しないNotifierです
+type nopNotifier struct{}
+
+func (nopNotifier) C() <-chan struct{} { return nil }
+func (nopNotifier) Close() error       { return nil }
+
+func newFakeWatcher(t *testing.T, dir *fakeDirectory) *Watcher {
+	t.Helper()
+	w, err := newWatcher("/in", dir.snapshot, nopNotifier{}, time.Second, func() time.Time { return dir.now })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return w
+}
+
+func TestWatcher_DebouncesPartialWrites(t *testing.T) {
+	dir := &fakeDirectory{files: Snapshot{}, now: time.Unix(1000, 0)}
+	dir.write("/in/existing.png", 10)
+	w := newFakeWatcher(t, dir)
+
+	// 監視開始時点のファイルは報告しない
+	if changes := dir.poll(t, w, 2*time.Second); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	// 書き込み中はサイズが変わり続けるため報告しない
+	for size := int64(100); size <= 300; size += 100 {
+		dir.write("/in/new.png", size)
+		if changes := dir.poll(t, w, 2*time.Second); len(changes) != 0 {
+			t.Errorf("expected no changes while writing, got %v", changes)
+		}
+	}
+
+	// 状態が変わらなくても、安定した時間が短ければ報告しない
+	if changes := dir.poll(t, w, 500*time.Millisecond); len(changes) != 0 {
+		t.Errorf("expected no changes before settling, got %v", changes)
+	}
+
+	expected := []types.FileChange{{Path: "/in/new.png", Kind: types.FileWritten}}
+	if changes := dir.poll(t, w, 500*time.Millisecond); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("changes = %v, want %v", changes, expected)
+	}
+
+	// 報告済みのファイルは変更されるまで報告しない
+	if changes := dir.poll(t, w, 2*time.Second); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestWatcher_ModifyRemoveRename(t *testing.T) {
+	dir := &fakeDirectory{files: Snapshot{}, now: time.Unix(1000, 0)}
+	dir.write("/in/a.png", 10)
+	dir.write("/in/b.png", 10)
+	w := newFakeWatcher(t, dir)
+
+	// 変更
+	dir.now = dir.now.Add(time.Second)
+	dir.write("/in/a.png", 20)
+	dir.poll(t, w, 0)
+	expected := []types.FileChange{{Path: "/in/a.png", Kind: types.FileWritten}}
+	if changes := dir.poll(t, w, time.Second); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("modify: changes = %v, want %v", changes, expected)
+	}
+
+	// 削除は直ちに報告する
+	delete(dir.files, "/in/a.png")
+	expected = []types.FileChange{{Path: "/in/a.png", Kind: types.FileRemoved}}
+	if changes := dir.poll(t, w, 0); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("remove: changes = %v, want %v", changes, expected)
+	}
+
+	// 名前の変更は削除と（安定後の）作成として報告する
+	dir.files["/in/c.png"] = dir.files["/in/b.png"]
+	delete(dir.files, "/in/b.png")
+	expected = []types.FileChange{{Path: "/in/b.png", Kind: types.FileRemoved}}
+	if changes := dir.poll(t, w, 0); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("rename: changes = %v, want %v", changes, expected)
+	}
+	expected = []types.FileChange{{Path: "/in/c.png", Kind: types.FileWritten}}
+	if changes := dir.poll(t, w, time.Second); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("rename: changes = %v, want %v", changes, expected)
+	}
+
+	// 書き込み途中で削除されたファイルは報告しない
+	dir.write("/in/d.png", 5)
+	dir.poll(t, w, 0)
+	delete(dir.files, "/in/d.png")
+	if changes := dir.poll(t, w, time.Second); len(changes) != 0 {
+		t.Errorf("expected no changes for a file removed while writing, got %v", changes)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "existing.png"), []byte("old"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	w, err := NewWatcher(tmpDir, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan types.FileChange, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(changes []types.FileChange) {
+			for _, change := range changes {
+				received <- change
+			}
+		})
+	}()
+
+	path := filepath.Join(tmpDir, "new.png")
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	select {
+	case change := <-received:
+		expected := types.FileChange{Path: path, Kind: types.FileWritten}
+		if change != expected {
+			t.Errorf("change = %v, want %v", change, expected)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for a change")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,357 @@
+// Package server はHTTP経由で画像を変換するサーバーを提供します
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"image-converter/internal/cli"
+	"image-converter/internal/converter"
+	"image-converter/internal/types"
+)
+
+// MaxDimension はクエリで指定できる幅・高さの上限（ピクセル）です
+const MaxDimension = 8192
+
+// ErrTooManyPixels は入力画像の画素数が上限（ServeConfig.MaxPixels）を超えることを表します
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// negotiableFormats は出力フォーマットを指定しない場合にAcceptヘッダーと照合するフォーマットの優先順です
+var negotiableFormats = []types.ImageFormat{
+	types.FormatJPEG,
+	types.FormatPNG,
+	types.FormatWebP,
+	types.FormatGIF,
+	types.FormatBMP,
+}
+
+// Server は画像変換のHTTPハンドラーを提供します
+type Server struct {
+	config types.ServeConfig
+	base   types.Config           // クエリで指定されなかった項目の既定値
+	now    func() time.Time       // 署名の有効期限の判定に使用する現在時刻
+	cache  *converter.ResultCache // 変換結果のキャッシュ（nilの場合はキャッシュしない）
+}
+
+// NewServer は新しいServerを作成します
+func NewServer(config types.ServeConfig) *Server {
+	return NewServerWithCache(config, nil)
+}
+
+// NewServerWithCache は変換結果をcacheに保存・再利用するServerを作成します
+// キャッシュはすべてのリクエストで共有され、レスポンスのX-Cacheヘッダーで利用状況（HIT, MISS）を返します
+func NewServerWithCache(config types.ServeConfig, cache *converter.ResultCache) *Server {
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = cli.DefaultServeConfig().MaxPixels
+	}
+	return &Server{
+		config: config,
+		base:   cli.DefaultConfig(),
+		now:    time.Now,
+		cache:  cache,
+	}
+}
+
+// Handler はGET /img/{path}とPOST /convertを処理するハンドラーを返します
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /img/{path...}", s.handleImage)
+	mux.HandleFunc("POST /convert", s.handleConvert)
+	return mux
+}
+
+// handleImage はルートディレクトリの画像を変換して返します
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+	if s.config.Root == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// http.Dirはルートディレクトリ外へのパス（..）を拒否する
+	file, err := http.Dir(s.config.Root).Open("/" + r.PathValue("path"))
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.convert(w, r, file)
+}
+
+// handleConvert はリクエスト本文の画像を変換して返します
+func (s *Server) handleConvert(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (!strings.HasPrefix(mediaType, "image/") && mediaType != "application/octet-stream") {
+			writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type: %s", contentType))
+			return
+		}
+	}
+
+	s.convert(w, r, http.MaxBytesReader(w, r.Body, s.config.MaxUploadBytes))
+}
+
+// authorize は秘密鍵が設定されている場合にリクエストの署名を検証します
+// 検証に失敗した場合は403を返し、falseを返します（ファイルや本文は読み込みません）
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if s.config.Secret == "" {
+		return true
+	}
+	if err := verifySignature(s.config.Secret, r.URL.Path, r.URL.Query(), s.now()); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+// convert はクエリに従ってbodyの画像を変換し、レスポンスとして書き込みます
+func (s *Server) convert(w http.ResponseWriter, r *http.Request, body io.Reader) {
+	config, err := s.requestConfig(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.checkPreset(config); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	// 入力フォーマットの判定
+	br := bufio.NewReader(body)
+	header, err := br.Peek(converter.SniffHeaderSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		writeError(w, readErrorStatus(err), err)
+		return
+	}
+	inputFormat, err := converter.SniffFormat(header)
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+
+	// 巨大なサイズのヘッダーを持つ画像は、デコードで大量のメモリを確保する前に拒否する
+	input, err := checkPixels(br, s.config.MaxPixels)
+	if err != nil {
+		status := readErrorStatus(err)
+		if errors.Is(err, ErrTooManyPixels) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	// 出力フォーマットの決定
+	outputFormat, negotiated, err := negotiateFormat(types.ImageFormat(config.Format), inputFormat, r.Header.Get("Accept"))
+	if negotiated {
+		w.Header().Add("Vary", "Accept")
+	}
+	if err != nil {
+		writeError(w, http.StatusNotAcceptable, err)
+		return
+	}
+
+	// エラー時にステータスコードを返せるよう、変換結果はバッファに書き込む
+	var buf bytes.Buffer
+	result := converter.NewConverterWithCache(*config, s.cache).ConvertStream(input, &buf, outputFormat)
+	if !result.Success {
+		status := http.StatusInternalServerError
+		if errors.Is(result.Error, converter.ErrDecode) {
+			status = readErrorStatus(result.Error)
+		}
+		writeError(w, status, result.Error)
+		return
+	}
+
+	w.Header().Set("Content-Type", converter.MIMEType(result.Format))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if result.Cache != "" {
+		w.Header().Set("X-Cache", strings.ToUpper(string(result.Cache)))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// checkPixels はrのヘッダーから画像のサイズを読み込み、画素数がmaxPixelsを超える場合はErrTooManyPixelsを返します
+// アニメーションとして出力する場合は全フレームをキャンバスの大きさで保持するため、GIFはフレーム数を掛けた画素数で判定します
+// 戻り値のReaderは、判定に使用したバイト列を含むr全体を返します
+func checkPixels(r io.Reader, maxPixels int64) (io.Reader, error) {
+	var header bytes.Buffer
+	config, format, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", converter.ErrDecode, err)
+	}
+	pixels := int64(config.Width) * int64(config.Height)
+	if pixels > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrTooManyPixels, config.Width, config.Height, maxPixels)
+	}
+
+	if format == string(types.FormatGIF) && pixels > 0 {
+		// フレーム数はブロックをたどって数える（本文の大きさはMaxUploadBytesで制限済み）
+		data, err := io.ReadAll(io.MultiReader(&header, r))
+		if err != nil {
+			return nil, err
+		}
+		// 壊れたデータの判定はデコーダーに任せ、それまでに数えたフレーム数で判定する
+		frames, _ := converter.CountGIFFrames(bytes.NewReader(data))
+		if frames > 1 && int64(frames) > maxPixels/pixels {
+			return nil, fmt.Errorf("%w: %dx%d with %d frames exceeds %d pixels", ErrTooManyPixels, config.Width, config.Height, frames, maxPixels)
+		}
+		return bytes.NewReader(data), nil
+	}
+	return io.MultiReader(&header, r), nil
+}
+
+// requestConfig はクエリパラメーターから変換設定を作成し、CLIと同じ規則で検証します
+func (s *Server) requestConfig(query url.Values) (*types.Config, error) {
+	config := s.base
+
+	var err error
+	if config.Width, err = queryInt(query, "w"); err != nil {
+		return nil, err
+	}
+	if config.Height, err = queryInt(query, "h"); err != nil {
+		return nil, err
+	}
+	if value := query.Get("scale"); value != "" {
+		if config.Scale, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid scale: %s", value)
+		}
+	}
+	if value := query.Get("q"); value != "" {
+		if config.JPEGQuality, err = strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("invalid q: %s", value)
+		}
+	}
+	config.Fit = query.Get("fit")
+	config.Format = query.Get("format")
+
+	if config.Width > MaxDimension || config.Height > MaxDimension {
+		return nil, fmt.Errorf("w and h must not exceed %d", MaxDimension)
+	}
+
+	if err := cli.ValidateOptions(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// queryInt はクエリパラメーターを整数として取得します（未指定の場合は0）
+func queryInt(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+	return n, nil
+}
+
+// negotiateFormat は出力フォーマットを決定します
+// requestedが指定されている場合はAcceptヘッダーで受け付けられるか確認し、
+// 指定されていない場合はAcceptヘッダーから選択します（negotiatedがtrue）
+// Acceptヘッダーがimage/webpを明示している場合はWebP、それ以外は入力と同じフォーマットを優先します
+func negotiateFormat(requested, input types.ImageFormat, accept string) (format types.ImageFormat, negotiated bool, err error) {
+	if requested != "" {
+		if q, _ := acceptQuality(accept, converter.MIMEType(requested)); q == 0 {
+			return "", false, fmt.Errorf("%s is not acceptable", converter.MIMEType(requested))
+		}
+		return requested, false, nil
+	}
+
+	if q, explicit := acceptQuality(accept, converter.MIMEType(types.FormatWebP)); q > 0 && explicit {
+		return types.FormatWebP, true, nil
+	}
+	if q, _ := acceptQuality(accept, converter.MIMEType(input)); q > 0 {
+		return input, true, nil
+	}
+	for _, candidate := range negotiableFormats {
+		if q, _ := acceptQuality(accept, converter.MIMEType(candidate)); q > 0 {
+			return candidate, true, nil
+		}
+	}
+	return "", true, fmt.Errorf("no acceptable image format")
+}
+
+// acceptQuality はAcceptヘッダーでmimeTypeに一致する最も具体的なメディア範囲の品質値を返します
+// explicitはmimeTypeがそのまま（ワイルドカードでなく）列挙されていたかを表します
+// Acceptヘッダーが空の場合はすべてを受け付けます
+func acceptQuality(accept, mimeType string) (q float64, explicit bool) {
+	if strings.TrimSpace(accept) == "" {
+		return 1, false
+	}
+
+	mainType, _, _ := strings.Cut(mimeType, "/")
+	best := 0
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(part, ";")
+		mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+
+		specificity := 0
+		switch mediaRange {
+		case mimeType:
+			specificity = 3
+		case mainType + "/*":
+			specificity = 2
+		case "*/*":
+			specificity = 1
+		}
+		if specificity <= best {
+			continue
+		}
+
+		best = specificity
+		q = 1
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+	}
+	return q, best == 3
+}
+
+// readErrorStatus は入力の読み込みエラーに対応するステータスコードを返します
+func readErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusUnprocessableEntity
+}
+
+// writeError はエラーメッセージをテキストで返します
+func writeError(w http.ResponseWriter, status int, err error) {
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,416 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"image-converter/internal/converter"
+	"image-converter/internal/types"
+)
+
+// encodeTestPNG はテスト用のPNG画像をエンコードします
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newTestServer はルートディレクトリにphoto.png（200x100）を配置したサーバーを作成します
+// configsを指定した場合は最初の設定の署名・サイズ制限・画素数の上限を使用します
+func newTestServer(t *testing.T, configs ...types.ServeConfig) *httptest.Server {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "photo.png"), encodeTestPNG(t, 200, 100), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	config := types.ServeConfig{Addr: ":0", Root: root, MaxUploadBytes: 16 << 10}
+	if len(configs) > 0 {
+		config.Secret = configs[0].Secret
+		config.Presets = configs[0].Presets
+		config.MaxPixels = configs[0].MaxPixels
+	}
+
+	srv := httptest.NewServer(NewServer(config).Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// get はAcceptヘッダーを指定してGETリクエストを送信します
+func get(t *testing.T, url, accept string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// decodeConfig はレスポンス本文の画像のサイズとフォーマットを返します
+func decodeConfig(t *testing.T, resp *http.Response) (image.Config, string) {
+	t.Helper()
+
+	cfg, format, err := image.DecodeConfig(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return cfg, format
+}
+
+func TestServer_GetImage(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := get(t, srv.URL+"/img/photo.png?w=100&h=100&fit=cover&format=jpeg&q=70", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Expected image/jpeg, got %s", ct)
+	}
+
+	cfg, format := decodeConfig(t, resp)
+	if format != "jpeg" || cfg.Width != 100 || cfg.Height != 100 {
+		t.Errorf("Expected 100x100 jpeg, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+}
+
+func TestServer_GetImageContain(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := get(t, srv.URL+"/img/photo.png?w=100&h=100", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+
+	cfg, format := decodeConfig(t, resp)
+	if format != "png" || cfg.Width != 100 || cfg.Height != 50 {
+		t.Errorf("Expected 100x50 png, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+}
+
+func TestServer_Negotiation(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		accept      string
+		contentType string
+	}{
+		{"image/avif,image/webp,*/*;q=0.8", "image/webp"},
+		{"image/*", "image/png"},
+		{"image/jpeg", "image/jpeg"},
+		{"", "image/png"},
+	}
+
+	for _, tt := range tests {
+		resp := get(t, srv.URL+"/img/photo.png?w=50", tt.accept)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Accept %q: expected 200, got %d", tt.accept, resp.StatusCode)
+			continue
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("Accept %q: expected %s, got %s", tt.accept, tt.contentType, ct)
+		}
+		if vary := resp.Header.Get("Vary"); vary != "Accept" {
+			t.Errorf("Accept %q: expected Vary: Accept, got %q", tt.accept, vary)
+		}
+	}
+}
+
+func TestServer_ErrorStatus(t *testing.T) {
+	srv := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		accept string
+		status int
+	}{
+		{"missing file", "/img/missing.png", "", http.StatusNotFound},
+		{"directory", "/img/sub", "", http.StatusNotFound},
+		{"path traversal", "/img/../server.go", "", http.StatusNotFound},
+		{"invalid width", "/img/photo.png?w=abc", "", http.StatusBadRequest},
+		{"negative width", "/img/photo.png?w=-1", "", http.StatusBadRequest},
+		{"too large", "/img/photo.png?w=100000", "", http.StatusBadRequest},
+		{"scale with width", "/img/photo.png?w=10&scale=0.5", "", http.StatusBadRequest},
+		{"unsupported format", "/img/photo.png?format=tiff", "", http.StatusBadRequest},
+		{"invalid fit", "/img/photo.png?fit=stretch", "", http.StatusBadRequest},
+		{"quality out of range", "/img/photo.png?q=0", "", http.StatusBadRequest},
+		{"not acceptable format", "/img/photo.png?format=webp", "image/png", http.StatusNotAcceptable},
+		{"nothing acceptable", "/img/photo.png", "text/html", http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		resp := get(t, srv.URL+tt.path, tt.accept)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_Convert(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/convert?w=40&format=gif", "image/png", bytes.NewReader(encodeTestPNG(t, 80, 80)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	cfg, format := decodeConfig(t, resp)
+	if format != "gif" || cfg.Width != 40 || cfg.Height != 40 {
+		t.Errorf("Expected 40x40 gif, got %dx%d %s", cfg.Width, cfg.Height, format)
+	}
+}
+
+func TestServer_ConvertErrors(t *testing.T) {
+	srv := newTestServer(t)
+	valid := encodeTestPNG(t, 10, 10)
+
+	// 圧縮の効かないノイズ画像でアップロードの上限を超える
+	noise := image.NewGray(image.Rect(0, 0, 256, 256))
+	seed := uint32(1)
+	for i := range noise.Pix {
+		seed ^= seed << 13
+		seed ^= seed >> 17
+		seed ^= seed << 5
+		noise.Pix[i] = uint8(seed)
+	}
+	var large bytes.Buffer
+	if err := png.Encode(&large, noise); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+	}{
+		{"not an image", "application/octet-stream", []byte("hello, world"), http.StatusUnsupportedMediaType},
+		{"wrong content type", "text/plain", valid, http.StatusUnsupportedMediaType},
+		{"truncated image", "image/png", valid[:len(valid)/2], http.StatusUnprocessableEntity},
+		{"too large", "image/png", large.Bytes(), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Post(srv.URL+"/convert", tt.contentType, bytes.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+	}
+}
+
+// hugePNGHeader は画素データを含まず、ヘッダー（IHDR）のみで巨大なサイズを宣言するPNGを作成します
+func hugePNGHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 4, 17)
+	copy(ihdr, "IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8ビットRGBA
+
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, ihdr...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestServer_MaxPixels(t *testing.T) {
+	srv := newTestServer(t)
+	huge := hugePNGHeader(100000, 100000)
+
+	// 既定の上限でも巨大なヘッダーの画像はデコードせずに拒否する
+	resp, err := http.Post(srv.URL+"/convert", "image/png", bytes.NewReader(huge))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("POST: expected 413, got %d", resp.StatusCode)
+	}
+
+	// 上限を下げると、ルートディレクトリの画像（200x100）も拒否する
+	limited := newTestServer(t, types.ServeConfig{MaxPixels: 10000})
+	if resp := get(t, limited.URL+"/img/photo.png", ""); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("GET: expected 413, got %d", resp.StatusCode)
+	}
+	resp, err = http.Post(limited.URL+"/convert?format=png", "image/png", bytes.NewReader(encodeTestPNG(t, 100, 100)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected an image at the limit to be converted, got %d", resp.StatusCode)
+	}
+	if cfg, _ := decodeConfig(t, resp); cfg.Width != 100 || cfg.Height != 100 {
+		t.Errorf("Expected 100x100, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+// tinyFrameGIF はwidth x heightのキャンバスに1x1のフレームをframes枚持つGIFを生成します
+func tinyFrameGIF(t *testing.T, width, height, frames int) []byte {
+	t.Helper()
+	pal := color.Palette{color.Black, color.White}
+	g := &gif.GIF{Config: image.Config{ColorModel: pal, Width: width, Height: height}}
+	for i := 0; i < frames; i++ {
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), pal))
+		g.Delay = append(g.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestServer_MaxPixels_AnimatedGIF(t *testing.T) {
+	// 数百バイトのGIFでも、アニメーションとして出力すると全フレームをキャンバスの大きさで保持する
+	bomb := tinyFrameGIF(t, 2000, 2000, 30)
+	srv := newTestServer(t)
+	resp, err := http.Post(srv.URL+"/convert?format=webp", "image/gif", bytes.NewReader(bomb))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for %d bytes with 30 frames, got %d", len(bomb), resp.StatusCode)
+	}
+
+	// キャンバス×フレーム数が上限以下であれば変換する
+	limited := newTestServer(t, types.ServeConfig{MaxPixels: 40000})
+	for _, tc := range []struct {
+		frames int
+		status int
+	}{
+		{4, http.StatusOK},
+		{5, http.StatusRequestEntityTooLarge},
+	} {
+		resp, err := http.Post(limited.URL+"/convert?format=gif", "image/gif", bytes.NewReader(tinyFrameGIF(t, 100, 100, tc.frames)))
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%d frames: expected %d, got %d", tc.frames, tc.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_Cache(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "photo.png"), encodeTestPNG(t, 200, 100), 0644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+	cache, err := converter.OpenResultCache(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("OpenResultCache failed: %v", err)
+	}
+
+	srv := httptest.NewServer(NewServerWithCache(types.ServeConfig{Root: root, MaxUploadBytes: 16 << 10}, cache).Handler())
+	defer srv.Close()
+
+	var bodies [2][]byte
+	for i, expected := range []string{"MISS", "HIT"} {
+		resp := get(t, srv.URL+"/img/photo.png?w=50&format=jpeg", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.StatusCode)
+		}
+		if xCache := resp.Header.Get("X-Cache"); xCache != expected {
+			t.Errorf("Request %d: expected X-Cache %s, got %q", i+1, expected, xCache)
+		}
+		if bodies[i], err = io.ReadAll(resp.Body); err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+	}
+	if !bytes.Equal(bodies[0], bodies[1]) {
+		t.Error("Expected the cached response to be identical")
+	}
+
+	// 同じ画像をアップロードした場合もキャッシュを使用する
+	resp, err := http.Post(srv.URL+"/convert?w=50&format=jpeg", "image/png", bytes.NewReader(encodeTestPNG(t, 200, 100)))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if xCache := resp.Header.Get("X-Cache"); xCache != "HIT" {
+		t.Errorf("Expected X-Cache HIT for an uploaded copy, got %q", xCache)
+	}
+}
+
+func TestServer_MethodNotAllowed(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/img/photo.png", "image/png", strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestAcceptQuality(t *testing.T) {
+	tests := []struct {
+		accept   string
+		mimeType string
+		q        float64
+		explicit bool
+	}{
+		{"", "image/webp", 1, false},
+		{"image/webp", "image/webp", 1, true},
+		{"image/*;q=0.5", "image/png", 0.5, false},
+		{"*/*;q=0.1, image/png;q=0", "image/png", 0, true},
+		{"text/html", "image/png", 0, false},
+	}
+
+	for _, tt := range tests {
+		q, explicit := acceptQuality(tt.accept, tt.mimeType)
+		if q != tt.q || explicit != tt.explicit {
+			t.Errorf("acceptQuality(%q, %q) = %v, %v; want %v, %v", tt.accept, tt.mimeType, q, explicit, tt.q, tt.explicit)
+		}
+	}
+}
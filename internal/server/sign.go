@@ -0,0 +1,125 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+// 署名に使用するクエリパラメーター名
+const (
+	signatureParam = "sig" // HMAC-SHA256の署名（URLセーフなBase64）
+	expiresParam   = "exp" // 有効期限（UNIX時間、省略時は無期限）
+)
+
+var (
+	// ErrMissingSignature は署名が必要なリクエストに署名がないことを表します
+	ErrMissingSignature = errors.New("missing signature")
+	// ErrInvalidSignature は署名が一致しないことを表します
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrSignatureExpired は署名の有効期限が過ぎていることを表します
+	ErrSignatureExpired = errors.New("signature expired")
+	// ErrSizeNotAllowed は要求されたサイズが許可されていないことを表します
+	ErrSizeNotAllowed = errors.New("size not allowed")
+)
+
+// SignURL はrawURLのパスとクエリ（sigを除く）に署名したURLを返します
+// パラメーターはサーバーと同じ規則で検証し、許可されていないサイズには署名しません
+// expiresがゼロ値でない場合は有効期限（exp）を付加して署名します
+func (s *Server) SignURL(rawURL string, expires time.Time) (string, error) {
+	if s.config.Secret == "" {
+		return "", fmt.Errorf("secret is required to sign URLs")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	query := u.Query()
+	query.Del(signatureParam)
+	query.Del(expiresParam)
+	if !expires.IsZero() {
+		query.Set(expiresParam, strconv.FormatInt(expires.Unix(), 10))
+	}
+
+	config, err := s.requestConfig(query)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkPreset(config); err != nil {
+		return "", err
+	}
+
+	query.Set(signatureParam, sign(s.config.Secret, u.Path, query))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// verifySignature はクエリの署名と有効期限を検証します
+func verifySignature(secret, path string, query url.Values, now time.Time) error {
+	signature := query.Get(signatureParam)
+	if signature == "" {
+		return ErrMissingSignature
+	}
+	expected := sign(secret, path, query)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	if value := query.Get(expiresParam); value != "" {
+		expires, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		if now.Unix() > expires {
+			return ErrSignatureExpired
+		}
+	}
+	return nil
+}
+
+// sign はパスとクエリ（sigを除き、キーの昇順）のHMAC-SHA256をURLセーフなBase64で返します
+func sign(secret, path string, query url.Values) string {
+	unsigned := url.Values{}
+	for key, values := range query {
+		if key != signatureParam {
+			unsigned[key] = values
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(path))
+	mac.Write([]byte{'?'})
+	mac.Write([]byte(unsigned.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkPreset は要求されたサイズが許可されたサイズのいずれかに一致するか確認します
+// 許可するサイズが設定されていない場合、またはサイズを指定しない（元のサイズの）場合は常に許可します
+func (s *Server) checkPreset(config *types.Config) error {
+	if len(s.config.Presets) == 0 {
+		return nil
+	}
+	if config.Scale > 0 {
+		return ErrSizeNotAllowed
+	}
+	if config.Width == 0 && config.Height == 0 {
+		return nil
+	}
+
+	requested := types.SizePreset{Width: config.Width, Height: config.Height}
+	for _, preset := range s.config.Presets {
+		if preset == requested {
+			return nil
+		}
+	}
+	return ErrSizeNotAllowed
+}
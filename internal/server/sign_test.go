@@ -0,0 +1,126 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"image-converter/internal/types"
+)
+
+func TestSignURL_RoundTrip(t *testing.T) {
+	s := NewServer(types.ServeConfig{Secret: "s3cr3t"})
+
+	signed, err := s.SignURL("/img/photo.png?w=100&format=webp", time.Time{})
+	if err != nil {
+		t.Fatalf("SignURL failed: %v", err)
+	}
+
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("Invalid signed URL %q: %v", signed, err)
+	}
+	if u.Query().Get("sig") == "" || u.Query().Get("exp") != "" {
+		t.Errorf("Unexpected query: %s", u.RawQuery)
+	}
+	if err := verifySignature("s3cr3t", u.Path, u.Query(), time.Now()); err != nil {
+		t.Errorf("verifySignature failed: %v", err)
+	}
+
+	// 別の秘密鍵やパラメーターの改ざんは拒否される
+	if err := verifySignature("other", u.Path, u.Query(), time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for wrong secret, got %v", err)
+	}
+	tampered := u.Query()
+	tampered.Set("w", "4000")
+	if err := verifySignature("s3cr3t", u.Path, tampered, time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for tampered query, got %v", err)
+	}
+	if err := verifySignature("s3cr3t", "/img/other.png", u.Query(), time.Now()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for different path, got %v", err)
+	}
+}
+
+func TestSignURL_Expires(t *testing.T) {
+	s := NewServer(types.ServeConfig{Secret: "s3cr3t"})
+	expires := time.Unix(1700000000, 0)
+
+	signed, err := s.SignURL("https://cdn.example.com/img/photo.png?w=100", expires)
+	if err != nil {
+		t.Fatalf("SignURL failed: %v", err)
+	}
+	if !strings.HasPrefix(signed, "https://cdn.example.com/img/photo.png?") {
+		t.Errorf("Expected scheme and host to be kept, got %s", signed)
+	}
+
+	u, _ := url.Parse(signed)
+	if err := verifySignature("s3cr3t", u.Path, u.Query(), expires.Add(-time.Minute)); err != nil {
+		t.Errorf("Expected valid signature before expiry, got %v", err)
+	}
+	if err := verifySignature("s3cr3t", u.Path, u.Query(), expires.Add(time.Minute)); !errors.Is(err, ErrSignatureExpired) {
+		t.Errorf("Expected ErrSignatureExpired, got %v", err)
+	}
+}
+
+func TestSignURL_Validation(t *testing.T) {
+	s := NewServer(types.ServeConfig{
+		Secret:  "s3cr3t",
+		Presets: []types.SizePreset{{Width: 400, Height: 300}, {Width: 800}},
+	})
+
+	for _, rawURL := range []string{"/img/a.png?w=400&h=300", "/img/a.png?w=800&format=webp", "/img/a.png"} {
+		if _, err := s.SignURL(rawURL, time.Time{}); err != nil {
+			t.Errorf("%s: unexpected error: %v", rawURL, err)
+		}
+	}
+
+	for _, rawURL := range []string{"/img/a.png?w=401", "/img/a.png?h=300", "/img/a.png?scale=0.5", "/img/a.png?w=800&format=tiff"} {
+		if _, err := s.SignURL(rawURL, time.Time{}); err == nil {
+			t.Errorf("%s: expected error", rawURL)
+		}
+	}
+
+	if _, err := NewServer(types.ServeConfig{}).SignURL("/img/a.png", time.Time{}); err == nil {
+		t.Error("Expected error without secret")
+	}
+}
+
+func TestServer_SignedRequests(t *testing.T) {
+	srv := newTestServer(t, types.ServeConfig{
+		Secret:  "s3cr3t",
+		Presets: []types.SizePreset{{Width: 100, Height: 100}},
+	})
+	signer := NewServer(types.ServeConfig{Secret: "s3cr3t"})
+
+	mustSign := func(rawURL string) string {
+		signed, err := signer.SignURL(rawURL, time.Time{})
+		if err != nil {
+			t.Fatalf("SignURL failed: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"signed", mustSign("/img/photo.png?w=100&h=100"), http.StatusOK},
+		{"unsigned", "/img/photo.png?w=100&h=100", http.StatusForbidden},
+		{"tampered", strings.Replace(mustSign("/img/photo.png?w=100&h=100"), "w=100", "w=99", 1), http.StatusForbidden},
+		{"signed but not a preset", mustSign("/img/photo.png?w=50"), http.StatusForbidden},
+		// 署名の検証はファイルの存在確認より先に行う
+		{"unsigned missing file", "/img/missing.png", http.StatusForbidden},
+		{"signed missing file", mustSign("/img/missing.png"), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		resp := get(t, srv.URL+tt.path, "")
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, resp.StatusCode)
+		}
+	}
+}
@@ -1,16 +1,86 @@
 package types
 
-import "image"
+import (
+	"image"
+	"io"
+	"time"
+)
 
 // Config はCLI設定を表します
 type Config struct {
-	InputDir    string
-	OutputDir   string
-	Scale       float64
-	Width       int
-	Height      int
-	Format      string
-	JPEGQuality int
+	InputDir         string
+	OutputDir        string
+	Scale            float64
+	Width            int
+	Height           int
+	Fit              string // 幅と高さの両方を指定した場合の合わせ方（contain, cover）
+	Format           string
+	JPEGQuality      int
+	FirstFrameOnly   bool          // アニメーション画像でも最初のフレームのみを出力
+	Colors           int           // パレット出力時の最大色数（2-256、0の場合は256）
+	Dither           string        // ディザリング方式（none, floyd-steinberg, ordered）
+	PNGPalette       bool          // PNGを8ビットパレット形式で出力
+	PNGMaxError      float64       // パレットPNGの許容誤差（RMSE、0の場合は無制限）
+	JPEGProgressive  bool          // プログレッシブJPEGで出力
+	JPEGSubsampling  string        // JPEGの色差サブサンプリング（444, 422, 420）
+	MaxBytes         int64         // 出力ファイルサイズの上限（バイト、0の場合は無制限）
+	AllowDownscale   bool          // 最低品質でも上限を超える場合にさらに縮小する
+	TargetSSIM       float64       // 目標とするSSIM（0-1、0の場合は無効）
+	Variants         []Variant     // 1つの入力から生成する出力バリエーション
+	ManifestPath     string        // 変換後に出力するJSONマニフェストのパス（空の場合は出力しない）
+	ManifestHTMLPath string        // 変換後に出力する<picture>タグのHTMLのパス（空の場合は出力しない）
+	Rules            []Rule        // ファイルごとに設定を切り替える規則（先頭から評価し、最初に一致した規則を適用）
+	Files            []string      // 変換する入力ファイル（位置引数と-files-fromで指定、-input-dirとは排他）
+	FilesFrom        string        // 入力ファイルの一覧を読み込むファイル（"-"の場合は標準入力）
+	OutputPath       string        // 単一ファイルモードの出力パス（-o）
+	CacheDir         string        // 変換結果のキャッシュディレクトリ（空の場合はキャッシュしない）
+	CacheMaxBytes    int64         // キャッシュの合計サイズの上限（バイト、0の場合は無制限）
+	Watch            bool          // 初回の変換後も入力ディレクトリを監視して変換を続ける
+	WatchDelete      bool          // 監視中に削除された入力の出力を削除する
+	WatchInterval    time.Duration // 書き込みの完了を判定する（ポーリング時は走査する）間隔
+	Sync             bool          // 変換後、入力が存在しなくなった出力を削除する
+	DryRun           bool          // 書き込みや削除を行わず、変換計画を表示する
+	PlanFormat       string        // 変換計画の表示形式（table, json）
+}
+
+// ServeConfig はHTTP変換サーバー（serveサブコマンド）の設定を表します
+type ServeConfig struct {
+	Addr           string       // 待ち受けアドレス（例: :8080）
+	Root           string       // GET /img/{path} で配信する画像のルートディレクトリ（空の場合は無効）
+	MaxUploadBytes int64        // POST /convert で受け付ける本文の最大サイズ（バイト）
+	MaxPixels      int64        // デコードする入力画像の最大画素数（幅×高さ、ヘッダーで判定）
+	Secret         string       // URL署名の秘密鍵（空の場合は署名を検証しない）
+	Presets        []SizePreset // 許可するサイズ（空の場合は制限しない）
+	CacheDir       string       // 変換結果のキャッシュディレクトリ（空の場合はキャッシュしない）
+	CacheMaxBytes  int64        // キャッシュの合計サイズの上限（バイト、0の場合は無制限）
+}
+
+// SizePreset はHTTP変換サーバーで許可する出力サイズ（w, hの組み合わせ）を表します
+// 0の項目はその寸法を指定しないことを表します（例: 400x は幅のみ400）
+type SizePreset struct {
+	Width  int
+	Height int
+}
+
+// SignConfig はsignサブコマンドの設定を表します
+type SignConfig struct {
+	Serve ServeConfig   // 秘密鍵と許可するサイズ（serveサブコマンドと共通）
+	URLs  []string      // 署名するURL（パスとクエリ、またはスキーム・ホストを含むURL）
+	TTL   time.Duration // 署名の有効期間（0の場合は無期限）
+}
+
+// InfoConfig はinfoサブコマンドの設定を表します
+type InfoConfig struct {
+	Files []string // 性質を表示する画像ファイル
+	JSON  bool     // JSON形式で出力する
+}
+
+// CompareConfig はcompareサブコマンドの設定を表します
+type CompareConfig struct {
+	A        string // 基準の画像
+	B        string // 比較する画像
+	Resize   bool   // サイズが異なる場合にBをAのサイズにリサイズする
+	DiffPath string // 差を可視化したヒートマップの出力パス（空の場合は出力しない）
 }
 
 // ResizeSpec は画像のリサイズ仕様を表します
@@ -18,6 +88,83 @@ type ResizeSpec struct {
 	Scale  float64 // 倍率指定（0より大きい、0の場合は未指定）
 	Width  int     // 幅のピクセル指定（0の場合は未指定）
 	Height int     // 高さのピクセル指定（0の場合は未指定）
+	Fit    FitMode // 幅と高さの両方を指定した場合の合わせ方（空の場合はcontain）
+}
+
+// FitMode は幅と高さの両方を指定した場合の合わせ方を表します
+type FitMode string
+
+const (
+	FitContain FitMode = "contain" // 縦横比を維持して範囲内に収める
+	FitCover   FitMode = "cover"   // 縦横比を維持して範囲を覆い、はみ出した部分の中央を切り取る
+)
+
+// Variant は1つの入力から生成する出力バリエーションを表します
+// 未指定の項目は基本設定（-scale/-width/-height, -format, -jpeg-quality）に従います
+type Variant struct {
+	Suffix  string     // 出力ファイル名のベース名に付加する接尾辞（例: -sm）
+	Resize  ResizeSpec // リサイズ仕様
+	Format  string     // 出力フォーマット
+	Quality int        // JPEG/WebP品質
+}
+
+// Rule は条件に一致したファイルに適用する設定を表します
+// 未指定（ゼロ値）の項目は基本設定に従います
+type Rule struct {
+	Name            string     // 規則の名前（変換結果に記録されます）
+	Match           RuleMatch  // 適用条件
+	Resize          ResizeSpec // リサイズ仕様
+	Format          string     // 出力フォーマット
+	Quality         int        // JPEG/WebP品質
+	Colors          int        // パレットの最大色数
+	Dither          string     // ディザリング方式
+	PNGPalette      *bool      // PNGをパレット形式で出力するか
+	JPEGProgressive *bool      // プログレッシブJPEGで出力するか
+	JPEGSubsampling string     // JPEGの色差サブサンプリング
+}
+
+// RuleMatch は規則の適用条件を表します
+// 指定されたすべての条件を満たす場合に一致し、何も指定しない場合はすべてのファイルに一致します
+type RuleMatch struct {
+	Glob      string   // 入力ディレクトリからの相対パス（/区切り）に対するパターン。/を含まない場合はファイル名と照合
+	Formats   []string // 入力フォーマット（jpeg, png, webp, gif, bmp）
+	MinWidth  int      // 幅の下限（ピクセル、0の場合は無制限）
+	MaxWidth  int      // 幅の上限（ピクセル、0の場合は無制限）
+	MinHeight int      // 高さの下限（ピクセル、0の場合は無制限）
+	MaxHeight int      // 高さの上限（ピクセル、0の場合は無制限）
+}
+
+// DitherMode はパレット化時のディザリング方式を表します
+type DitherMode string
+
+const (
+	DitherNone           DitherMode = "none"
+	DitherFloydSteinberg DitherMode = "floyd-steinberg"
+	DitherOrdered        DitherMode = "ordered"
+)
+
+// ChromaSubsampling はJPEGの色差サブサンプリング方式を表します
+type ChromaSubsampling string
+
+const (
+	Subsampling444 ChromaSubsampling = "444"
+	Subsampling422 ChromaSubsampling = "422"
+	Subsampling420 ChromaSubsampling = "420"
+)
+
+// EncodeOptions は品質以外のエンコーダー設定を表します
+type EncodeOptions struct {
+	Colors int        // パレットの最大色数（2-256、0の場合は256）
+	Dither DitherMode // ディザリング方式（空の場合はFloyd-Steinberg）
+	// PNGPalette が true の場合、PNGを半透明対応のパレット形式（tRNS付き）で出力します
+	PNGPalette bool
+	// PNGMaxError は減色誤差（RMSE、0-255）の上限で、超えた場合はフルカラーで出力します
+	// 0の場合は常にパレット形式を使用します
+	PNGMaxError float64
+	// JPEGProgressive が true の場合、プログレッシブJPEGで出力します
+	JPEGProgressive bool
+	// JPEGSubsampling はJPEGの色差サブサンプリングです（空の場合は4:2:0）
+	JPEGSubsampling ChromaSubsampling
 }
 
 // ImageFormat はサポートされる画像フォーマットを表します
@@ -37,21 +184,57 @@ type ConversionStats struct {
 	Success int
 	Failed  int
 	Skipped int
+	// CacheHits と CacheMisses はキャッシュ有効時に出力をキャッシュから復元した数と新たに変換した数です
+	CacheHits   int
+	CacheMisses int
 }
 
+// CacheStatus は出力がキャッシュから復元されたかどうかを表します
+type CacheStatus string
+
+const (
+	CacheHit  CacheStatus = "hit"  // キャッシュから復元した
+	CacheMiss CacheStatus = "miss" // 変換してキャッシュに保存した
+)
+
 // ConversionResult は個別の変換結果を表します
 type ConversionResult struct {
-	SourcePath string
-	OutputPath string
-	Success    bool
-	Error      error
+	SourcePath   string
+	OutputPath   string
+	Success      bool
+	Error        error
+	Quality      int         // 容量制限・知覚品質目標モードで採用した品質（JPEG/WebP）または色数（GIF/PNG）
+	SSIM         float64     // 知覚品質目標モードで計測した元画像とのSSIM
+	Variant      string      // バリエーションの接尾辞（バリエーション指定時のみ）
+	Format       ImageFormat // 出力フォーマット（成功時のみ）
+	Width        int         // 出力画像の幅（成功時のみ）
+	Height       int         // 出力画像の高さ（成功時のみ）
+	Bytes        int64       // 出力ファイルのサイズ（成功時のみ）
+	Rule         string      // 適用された規則の名前（規則に一致した場合のみ）
+	SourceFormat ImageFormat // 内容から判定した入力フォーマット（ストリーム変換時のみ）
+	Cache        CacheStatus // キャッシュの利用状況（キャッシュ有効時のみ）
+}
+
+// FileChangeKind は監視中のディレクトリで検出したファイルの変更の種類です
+type FileChangeKind string
+
+const (
+	FileWritten FileChangeKind = "written" // 作成・変更され、書き込みが完了した
+	FileRemoved FileChangeKind = "removed" // 削除された（名前の変更を含む）
+)
+
+// FileChange は監視中のディレクトリで検出したファイルの変更です
+type FileChange struct {
+	Path string
+	Kind FileChangeKind
 }
 
 // ImageProcessor は画像処理のインターフェースを定義します
+// 読み込みと保存はストリームに対して行い、ファイル以外（標準入出力など）にも対応します
 type ImageProcessor interface {
-	Load(path string) (image.Image, error)
+	Decode(r io.Reader) (image.Image, ImageFormat, error)
 	Resize(img image.Image, spec ResizeSpec) image.Image
-	Save(img image.Image, path string, format string, quality int) error
+	Encode(w io.Writer, img image.Image, format ImageFormat, quality int) error
 }
 
 // FileScanner はファイルシステム操作のインターフェースを定義します